@@ -0,0 +1,188 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/openshift/ocm-agent/pkg/metrics"
+)
+
+const (
+	// Reasons reported by the authentication failures metric
+	ReasonMissingCredentials       = "missing_credentials"
+	ReasonInvalidToken             = "invalid_token"
+	ReasonInvalidBasicAuth         = "invalid_basic_auth"
+	ReasonInvalidClientCertificate = "invalid_client_certificate"
+	ReasonMissingClientCertificate = "missing_client_certificate"
+)
+
+const (
+	authenticateHeader            = "WWW-Authenticate"
+	bearerPrefix                  = "Bearer "
+	basicAuthenticateHeaderValue  = `Basic realm="ocm-agent"`
+	bearerAuthenticateHeaderValue = `Bearer realm="ocm-agent"`
+)
+
+// Config defines the accepted credentials, a request is authenticated if it matches any of the configured methods.
+// No authentication is enforced when nothing is configured.
+type Config struct {
+	// TokenFile holds the bearer token expected in the Authorization header
+	TokenFile string
+	// Username is the basic auth user expected along the password in PasswordFile
+	Username string
+	// PasswordFile holds the basic auth password
+	PasswordFile string
+	// ClientCAFile holds the CA bundle verifying client certificates, it requires the server to serve TLS
+	ClientCAFile string
+}
+
+// Authenticator checks the credentials of the requests
+type Authenticator struct {
+	token     []byte
+	username  []byte
+	password  []byte
+	clientCAs *x509.CertPool
+}
+
+// NewAuthenticator reads the credentials referenced by the configuration
+func NewAuthenticator(c Config) (*Authenticator, error) {
+	a := &Authenticator{}
+
+	if c.TokenFile != "" {
+		token, err := readSecretFile(c.TokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read the bearer token: %w", err)
+		}
+		a.token = token
+	}
+
+	if c.Username != "" || c.PasswordFile != "" {
+		if c.Username == "" || c.PasswordFile == "" {
+			return nil, fmt.Errorf("basic auth requires both a username and a password file")
+		}
+		password, err := readSecretFile(c.PasswordFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read the basic auth password: %w", err)
+		}
+		a.username = []byte(c.Username)
+		a.password = password
+	}
+
+	if c.ClientCAFile != "" {
+		pem, err := os.ReadFile(c.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read the client CA bundle: %w", err)
+		}
+		a.clientCAs = x509.NewCertPool()
+		if !a.clientCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in the client CA bundle %s", c.ClientCAFile)
+		}
+	}
+
+	return a, nil
+}
+
+// Enabled indicates whether any authentication method is configured
+func (a *Authenticator) Enabled() bool {
+	return a.token != nil || a.username != nil || a.clientCAs != nil
+}
+
+// ClientCAs returns the pool verifying client certificates, nil if client certificates are not accepted
+func (a *Authenticator) ClientCAs() *x509.CertPool {
+	return a.clientCAs
+}
+
+// Middleware rejects the requests which don't match any of the configured credentials
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	if !a.Enabled() {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reason, ok := a.authenticate(r)
+		if !ok {
+			log.WithFields(log.Fields{"path": r.URL.Path, "remote_addr": r.RemoteAddr, "reason": reason}).Warning("rejecting unauthenticated request")
+			metrics.CountAuthenticationFailure(r.URL.Path, reason)
+			if a.username != nil {
+				w.Header().Add(authenticateHeader, basicAuthenticateHeaderValue)
+			}
+			if a.token != nil {
+				w.Header().Add(authenticateHeader, bearerAuthenticateHeaderValue)
+			}
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// authenticate returns whether the request is authenticated, or the reason it is not
+func (a *Authenticator) authenticate(r *http.Request) (string, bool) {
+	reason := ReasonMissingCredentials
+
+	if a.clientCAs != nil {
+		if r.TLS != nil && len(r.TLS.PeerCertificates) > 0 {
+			if a.verifyClientCertificate(r) {
+				return "", true
+			}
+			reason = ReasonInvalidClientCertificate
+		} else if a.token == nil && a.username == nil {
+			return ReasonMissingClientCertificate, false
+		}
+	}
+
+	header := r.Header.Get("Authorization")
+	if a.token != nil && strings.HasPrefix(header, bearerPrefix) {
+		if subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(header, bearerPrefix)), a.token) == 1 {
+			return "", true
+		}
+		return ReasonInvalidToken, false
+	}
+
+	if a.username != nil {
+		if username, password, ok := r.BasicAuth(); ok {
+			// Both values are always compared so the response time doesn't reveal which one is wrong
+			usernameMatch := subtle.ConstantTimeCompare([]byte(username), a.username)
+			passwordMatch := subtle.ConstantTimeCompare([]byte(password), a.password)
+			if usernameMatch&passwordMatch == 1 {
+				return "", true
+			}
+			return ReasonInvalidBasicAuth, false
+		}
+	}
+
+	return reason, false
+}
+
+// verifyClientCertificate checks the certificate presented by the client was issued by the client CA
+func (a *Authenticator) verifyClientCertificate(r *http.Request) bool {
+	certs := r.TLS.PeerCertificates
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         a.clientCAs,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	return err == nil
+}
+
+// readSecretFile reads a credential from a file, ignoring the surrounding white spaces
+func readSecretFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	secret := strings.TrimSpace(string(data))
+	if secret == "" {
+		return nil, fmt.Errorf("file %s is empty", path)
+	}
+	return []byte(secret), nil
+}
@@ -0,0 +1,165 @@
+package auth_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/openshift/ocm-agent/pkg/auth"
+)
+
+// newCertificate creates a certificate signed by the parent, or a self signed CA if parent is nil
+func newCertificate(parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).ToNot(HaveOccurred())
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "alertmanager"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	Expect(err).ToNot(HaveOccurred())
+	cert, err := x509.ParseCertificate(der)
+	Expect(err).ToNot(HaveOccurred())
+	return cert, key
+}
+
+var _ = Describe("Authenticator", func() {
+	var (
+		dir     string
+		handler http.Handler
+		config  auth.Config
+	)
+
+	writeFile := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		Expect(os.WriteFile(path, []byte(content), 0600)).To(Succeed())
+		return path
+	}
+
+	serve := func(r *http.Request) *httptest.ResponseRecorder {
+		a, err := auth.NewAuthenticator(config)
+		Expect(err).ToNot(HaveOccurred())
+		rr := httptest.NewRecorder()
+		a.Middleware(handler).ServeHTTP(rr, r)
+		return rr
+	}
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+		config = auth.Config{}
+		handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		})
+	})
+
+	It("doesn't enforce anything when no method is configured", func() {
+		rr := serve(httptest.NewRequest(http.MethodPost, "/alertmanager-receiver", nil))
+		Expect(rr.Code).To(Equal(http.StatusOK))
+	})
+
+	Context("with a bearer token", func() {
+		BeforeEach(func() {
+			config.TokenFile = writeFile("token", "s3cr3t\n")
+		})
+		It("accepts the token", func() {
+			r := httptest.NewRequest(http.MethodPost, "/alertmanager-receiver", nil)
+			r.Header.Set("Authorization", "Bearer s3cr3t")
+			Expect(serve(r).Code).To(Equal(http.StatusOK))
+		})
+		It("rejects a wrong token", func() {
+			r := httptest.NewRequest(http.MethodPost, "/alertmanager-receiver", nil)
+			r.Header.Set("Authorization", "Bearer nope")
+			rr := serve(r)
+			Expect(rr.Code).To(Equal(http.StatusUnauthorized))
+			Expect(rr.Header().Get("WWW-Authenticate")).To(ContainSubstring("Bearer"))
+		})
+		It("rejects a request without credentials", func() {
+			rr := serve(httptest.NewRequest(http.MethodPost, "/alertmanager-receiver", nil))
+			Expect(rr.Code).To(Equal(http.StatusUnauthorized))
+		})
+		It("fails on an empty token file", func() {
+			config.TokenFile = writeFile("empty", " \n")
+			_, err := auth.NewAuthenticator(config)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("with basic auth", func() {
+		BeforeEach(func() {
+			config.Username = "alertmanager"
+			config.PasswordFile = writeFile("password", "p4ss")
+		})
+		It("accepts the credentials", func() {
+			r := httptest.NewRequest(http.MethodPost, "/alertmanager-receiver", nil)
+			r.SetBasicAuth("alertmanager", "p4ss")
+			Expect(serve(r).Code).To(Equal(http.StatusOK))
+		})
+		It("rejects a wrong password", func() {
+			r := httptest.NewRequest(http.MethodPost, "/alertmanager-receiver", nil)
+			r.SetBasicAuth("alertmanager", "wrong")
+			rr := serve(r)
+			Expect(rr.Code).To(Equal(http.StatusUnauthorized))
+			Expect(rr.Header().Get("WWW-Authenticate")).To(ContainSubstring("Basic"))
+		})
+		It("requires both the username and the password", func() {
+			config.PasswordFile = ""
+			_, err := auth.NewAuthenticator(config)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("with client certificates", func() {
+		var (
+			ca    *x509.Certificate
+			caKey *ecdsa.PrivateKey
+		)
+		BeforeEach(func() {
+			ca, caKey = newCertificate(nil, nil)
+			config.ClientCAFile = writeFile("ca.crt", string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Raw})))
+		})
+		It("accepts a certificate issued by the client CA", func() {
+			cert, _ := newCertificate(ca, caKey)
+			r := httptest.NewRequest(http.MethodPost, "/alertmanager-receiver", nil)
+			r.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}
+			Expect(serve(r).Code).To(Equal(http.StatusOK))
+		})
+		It("rejects a certificate issued by another CA", func() {
+			otherCA, otherKey := newCertificate(nil, nil)
+			cert, _ := newCertificate(otherCA, otherKey)
+			r := httptest.NewRequest(http.MethodPost, "/alertmanager-receiver", nil)
+			r.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}
+			Expect(serve(r).Code).To(Equal(http.StatusUnauthorized))
+		})
+		It("rejects a request without certificate", func() {
+			Expect(serve(httptest.NewRequest(http.MethodPost, "/alertmanager-receiver", nil)).Code).To(Equal(http.StatusUnauthorized))
+		})
+		It("falls back to the other methods when no certificate is presented", func() {
+			config.TokenFile = writeFile("token", "s3cr3t")
+			r := httptest.NewRequest(http.MethodPost, "/alertmanager-receiver", nil)
+			r.Header.Set("Authorization", "Bearer s3cr3t")
+			Expect(serve(r).Code).To(Equal(http.StatusOK))
+		})
+	})
+})
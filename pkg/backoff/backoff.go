@@ -0,0 +1,125 @@
+package backoff
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+)
+
+const (
+	DefaultInitialInterval = 500 * time.Millisecond
+	DefaultMultiplier      = 2
+	DefaultMaxInterval     = 30 * time.Second
+)
+
+// Config defines how an operation is retried. The delay before a retry is drawn uniformly between 0 and
+// InitialInterval*Multiplier^n capped to MaxInterval, n being the number of failed attempts so far (full jitter).
+type Config struct {
+	InitialInterval time.Duration
+	Multiplier      float64
+	MaxInterval     time.Duration
+	// MaxAttempts is the number of attempts after which the last error is returned, unlimited if 0
+	MaxAttempts int
+	// MaxElapsedTime is the time after which no retry is started, unlimited if 0
+	MaxElapsedTime time.Duration
+	// Retriable tells which errors are retried, all of them if nil
+	Retriable func(error) bool
+	// RetryAfter returns the delay requested by the error before the next attempt, e.g. by a Retry-After header,
+	// which is used instead of the backoff delay when positive
+	RetryAfter func(error) time.Duration
+	// OnRetry is called after a failed attempt with the delay before the next one
+	OnRetry func(attempt int, err error, delay time.Duration)
+}
+
+// permanentError stops the retries
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent wraps err so that the operation returning it is not retried
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// Retry calls fn until it succeeds, returns an error which is not retriable, or the attempts or elapsed time are
+// exhausted, waiting between the attempts according to the configuration. It stops waiting when ctx is done.
+func Retry(ctx context.Context, c Config, fn func() error) error {
+	c = withDefaults(c)
+	start := time.Now()
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil {
+			return nil
+		}
+		var permanent *permanentError
+		if errors.As(err, &permanent) {
+			return permanent.err
+		}
+		if c.Retriable != nil && !c.Retriable(err) {
+			return err
+		}
+		if c.MaxAttempts > 0 && attempt >= c.MaxAttempts {
+			return err
+		}
+
+		delay := c.delay(attempt)
+		if c.RetryAfter != nil {
+			if requested := c.RetryAfter(err); requested > 0 {
+				delay = requested
+			}
+		}
+		if c.MaxElapsedTime > 0 && time.Since(start)+delay > c.MaxElapsedTime {
+			return err
+		}
+		if c.OnRetry != nil {
+			c.OnRetry(attempt, err, delay)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%w, last error: %w", ctx.Err(), err)
+		case <-timer.C:
+		}
+	}
+}
+
+// delay returns the jittered delay following the attempt
+func (c Config) delay(attempt int) time.Duration {
+	ceiling := float64(c.InitialInterval) * math.Pow(c.Multiplier, float64(attempt-1))
+	if ceiling > float64(c.MaxInterval) {
+		ceiling = float64(c.MaxInterval)
+	}
+	if ceiling < 1 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(ceiling) + 1)) //#nosec G404 -- The jitter doesn't need a secure source
+}
+
+func withDefaults(c Config) Config {
+	if c.InitialInterval <= 0 {
+		c.InitialInterval = DefaultInitialInterval
+	}
+	if c.Multiplier < 1 {
+		c.Multiplier = DefaultMultiplier
+	}
+	if c.MaxInterval <= 0 {
+		c.MaxInterval = DefaultMaxInterval
+	}
+	return c
+}
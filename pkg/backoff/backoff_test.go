@@ -0,0 +1,115 @@
+package backoff_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/openshift/ocm-agent/pkg/backoff"
+)
+
+var _ = Describe("Backoff", func() {
+
+	var (
+		ctx     context.Context
+		config  backoff.Config
+		calls   int
+		errTest = errors.New("a generic test error")
+		failing = func(times int) func() error {
+			return func() error {
+				calls++
+				if calls <= times {
+					return errTest
+				}
+				return nil
+			}
+		}
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		calls = 0
+		config = backoff.Config{
+			InitialInterval: time.Millisecond,
+			MaxInterval:     5 * time.Millisecond,
+			MaxAttempts:     3,
+		}
+	})
+
+	It("calls the function once when it succeeds", func() {
+		Expect(backoff.Retry(ctx, config, failing(0))).To(Succeed())
+		Expect(calls).To(Equal(1))
+	})
+
+	It("retries the function until it succeeds", func() {
+		Expect(backoff.Retry(ctx, config, failing(2))).To(Succeed())
+		Expect(calls).To(Equal(3))
+	})
+
+	It("returns the last error once the attempts are exhausted", func() {
+		Expect(backoff.Retry(ctx, config, failing(5))).To(MatchError(errTest))
+		Expect(calls).To(Equal(3))
+	})
+
+	It("doesn't retry the errors which are not retriable", func() {
+		config.Retriable = func(err error) bool { return !errors.Is(err, errTest) }
+		Expect(backoff.Retry(ctx, config, failing(5))).To(MatchError(errTest))
+		Expect(calls).To(Equal(1))
+	})
+
+	It("doesn't retry the permanent errors", func() {
+		err := backoff.Retry(ctx, config, func() error {
+			calls++
+			return backoff.Permanent(errTest)
+		})
+		Expect(err).To(Equal(errTest))
+		Expect(calls).To(Equal(1))
+	})
+
+	It("stops retrying after the maximum elapsed time", func() {
+		config.MaxAttempts = 0
+		config.InitialInterval = 20 * time.Millisecond
+		config.MaxInterval = 20 * time.Millisecond
+		config.MaxElapsedTime = 50 * time.Millisecond
+		Expect(backoff.Retry(ctx, config, failing(100))).To(MatchError(errTest))
+		Expect(calls).To(BeNumerically("<", 100))
+	})
+
+	It("stops waiting when the context is done", func() {
+		config.MaxAttempts = 0
+		config.InitialInterval = time.Hour
+		config.MaxInterval = time.Hour
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+		err := backoff.Retry(ctx, config, failing(100))
+		Expect(err).To(MatchError(context.DeadlineExceeded))
+		Expect(err).To(MatchError(errTest))
+		Expect(calls).To(Equal(1))
+	})
+
+	It("reports each retry with its delay", func() {
+		var attempts []int
+		config.OnRetry = func(attempt int, err error, delay time.Duration) {
+			Expect(err).To(MatchError(errTest))
+			Expect(delay).To(BeNumerically("<=", config.MaxInterval))
+			attempts = append(attempts, attempt)
+		}
+		Expect(backoff.Retry(ctx, config, failing(2))).To(Succeed())
+		Expect(attempts).To(Equal([]int{1, 2}))
+	})
+
+	It("waits the delay requested by the error", func() {
+		var delays []time.Duration
+		config.RetryAfter = func(err error) time.Duration { return 30 * time.Millisecond }
+		config.OnRetry = func(attempt int, err error, delay time.Duration) {
+			delays = append(delays, delay)
+		}
+		start := time.Now()
+		Expect(backoff.Retry(ctx, config, failing(1))).To(Succeed())
+		Expect(time.Since(start)).To(BeNumerically(">=", 30*time.Millisecond))
+		Expect(delays).To(Equal([]time.Duration{30 * time.Millisecond}))
+	})
+})
@@ -0,0 +1,13 @@
+package certreloader_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestCertReloaderSuite(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "CertReloader Suite")
+}
@@ -0,0 +1,96 @@
+package certreloader_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/openshift/ocm-agent/pkg/certreloader"
+)
+
+// writeCertificate writes a self signed certificate pair with the given common name
+func writeCertificate(certFile, keyFile, commonName string, modTime time.Time) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).ToNot(HaveOccurred())
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	Expect(err).ToNot(HaveOccurred())
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	Expect(err).ToNot(HaveOccurred())
+
+	Expect(os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)).To(Succeed())
+	Expect(os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600)).To(Succeed())
+	Expect(os.Chtimes(certFile, modTime, modTime)).To(Succeed())
+	Expect(os.Chtimes(keyFile, modTime, modTime)).To(Succeed())
+}
+
+func commonName(r *certreloader.CertReloader) string {
+	cert, err := r.GetCertificate(nil)
+	Expect(err).ToNot(HaveOccurred())
+	parsed, err := x509.ParseCertificate(cert.Certificate[0])
+	Expect(err).ToNot(HaveOccurred())
+	return parsed.Subject.CommonName
+}
+
+var _ = Describe("CertReloader", func() {
+	var (
+		certFile string
+		keyFile  string
+	)
+
+	BeforeEach(func() {
+		dir := GinkgoT().TempDir()
+		certFile = filepath.Join(dir, "tls.crt")
+		keyFile = filepath.Join(dir, "tls.key")
+		writeCertificate(certFile, keyFile, "first", time.Now().Add(-time.Minute))
+	})
+
+	It("serves the certificate from the files", func() {
+		r, err := certreloader.NewCertReloader(certFile, keyFile)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(commonName(r)).To(Equal("first"))
+	})
+
+	It("fails when the files can't be loaded", func() {
+		_, err := certreloader.NewCertReloader(certFile, filepath.Join(GinkgoT().TempDir(), "missing.key"))
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("reloads the certificate when the files change", func() {
+		r, err := certreloader.NewCertReloader(certFile, keyFile)
+		Expect(err).ToNot(HaveOccurred())
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go r.Watch(ctx, 10*time.Millisecond)
+
+		writeCertificate(certFile, keyFile, "second", time.Now())
+		Eventually(func() string { return commonName(r) }).Should(Equal("second"))
+	})
+
+	It("keeps the previous certificate when the new files are invalid", func() {
+		r, err := certreloader.NewCertReloader(certFile, keyFile)
+		Expect(err).ToNot(HaveOccurred())
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go r.Watch(ctx, 10*time.Millisecond)
+
+		Expect(os.WriteFile(keyFile, []byte("not a key"), 0600)).To(Succeed())
+		Consistently(func() string { return commonName(r) }, 100*time.Millisecond).Should(Equal("first"))
+	})
+})
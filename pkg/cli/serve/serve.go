@@ -1,14 +1,29 @@
 package serve
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
+	"github.com/openshift/ocm-agent/pkg/auth"
+	"github.com/openshift/ocm-agent/pkg/certreloader"
 	"github.com/openshift/ocm-agent/pkg/consts"
+	"github.com/openshift/ocm-agent/pkg/httpchecker"
 	"github.com/openshift/ocm-agent/pkg/ocm"
+	"github.com/openshift/ocm-agent/pkg/outbox"
+	"github.com/openshift/ocm-agent/pkg/queue"
+	"github.com/openshift/ocm-agent/pkg/ratelimit"
 	"github.com/sirupsen/logrus"
 
 	"github.com/gorilla/mux"
@@ -17,30 +32,64 @@ import (
 
 	kcmdutil "k8s.io/kubectl/pkg/cmd/util"
 	"k8s.io/kubectl/pkg/util/templates"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/openshift/ocm-agent/pkg/config"
+	"github.com/openshift/ocm-agent/pkg/events"
 	"github.com/openshift/ocm-agent/pkg/handlers"
 	"github.com/openshift/ocm-agent/pkg/k8s"
+	"github.com/openshift/ocm-agent/pkg/leader"
 	"github.com/openshift/ocm-agent/pkg/logging"
 	"github.com/openshift/ocm-agent/pkg/metrics"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 
 	sdk "github.com/openshift-online/ocm-sdk-go"
+	oav1alpha1 "github.com/openshift/ocm-agent-operator/api/v1alpha1"
 )
 
 // serveOptions define the configuration options required by OCM agent to serve.
 type serveOptions struct {
-	accessToken       string
-	services          []string
-	ocmURL            string
-	externalClusterID string
-	ocmClientID       string
-	ocmClientSecret   string
-	debug             bool
-	fleetMode         bool
-	logger            logrus.Logger
+	accessToken          string
+	services             []string
+	ocmURL               string
+	externalClusterID    string
+	ocmClientID          string
+	ocmClientSecret      string
+	debug                bool
+	fleetMode            bool
+	queueWorkers         int
+	queueSize            int
+	queueBacklogFile     string
+	dryRun               bool
+	authTokenFile        string
+	authUsername         string
+	authPasswordFile     string
+	authClientCAFile     string
+	tlsCertFile          string
+	tlsKeyFile           string
+	tlsServingCertDir    string
+	leaderElection       bool
+	leaderElectionNS     string
+	rateLimit            ratelimit.Config
+	circuitBreaker       ocm.CircuitBreakerConfig
+	ocmRequestTimeout    time.Duration
+	ocmRetryAttempts     int
+	ocmOperationTimeouts map[string]string
+	outboxConfigMap      string
+	outboxReplayInterval time.Duration
+	lsReconcileInterval  time.Duration
+	informerCache        bool
+	events               bool
+	recordPruneInterval  time.Duration
+	recordRetention      time.Duration
+	recordLayout         string
+	healthInterval       time.Duration
+	logger               logrus.Logger
 }
 
+// shutdownTimeout is the time the server waits for the in-flight requests on termination
+const shutdownTimeout = 10 * time.Second
+
 var (
 	serviceLong = templates.LongDesc(`
 	Start the OCM Agent server
@@ -68,6 +117,51 @@ var (
 
 	# Start OCM agent server in fleet mode on staging clusters (in development/testing mode)
 	ocm-agent serve --services $SERVICE --ocm-url $URL --fleet-mode --ocm-client-id $CLIENT_ID --ocm-client-secret $CLIENT_SECRET
+
+	# Start OCM agent server in fleet mode processing alerts asynchronously with a persisted backlog
+	ocm-agent serve --fleet-mode --services "$SERVICE" --ocm-url @urlfile --queue-workers 4 --queue-backlog-file /var/lib/ocm-agent/backlog.json
+
+	# Start OCM agent server recording the notifications instead of sending them
+	ocm-agent serve --fleet-mode --services "$SERVICE" --ocm-url @urlfile --dry-run
+
+	# Start OCM agent server only accepting alerts from Alertmanager authenticated with a bearer token
+	ocm-agent serve --fleet-mode --services "$SERVICE" --ocm-url @urlfile --auth-token-file /etc/ocm-agent/token
+
+	# Start OCM agent server serving TLS with a service serving certificate, reloaded when it is rotated
+	ocm-agent serve --fleet-mode --services "$SERVICE" --ocm-url @urlfile --tls-serving-cert-dir /etc/tls/private
+
+	# Start OCM agent server as one of several replicas, only the elected leader sends the notifications
+	ocm-agent serve --fleet-mode --services "$SERVICE" --ocm-url @urlfile --leader-election
+
+	# Start OCM agent server sending at most 2 notifications per minute for a cluster, dropping the others
+	ocm-agent serve --fleet-mode --services "$SERVICE" --ocm-url @urlfile --rate-limit-per-cluster 2 --rate-limit-policy drop
+
+	# Start OCM agent server failing fast on OCM requests for a minute once half of at least 10 requests failed
+	ocm-agent serve --fleet-mode --services "$SERVICE" --ocm-url @urlfile --ocm-circuit-breaker-min-requests 10 --ocm-circuit-breaker-open-timeout 1m
+
+	# Start OCM agent server giving up on OCM requests after 10 seconds, or 5 seconds for the cluster lookups
+	ocm-agent serve --fleet-mode --services "$SERVICE" --ocm-url @urlfile --ocm-request-timeout 10s --ocm-operation-timeouts get_cluster_by_external_id=5s
+
+	# Start OCM agent server sending the OCM requests failing transiently up to 5 times
+	ocm-agent serve --fleet-mode --services "$SERVICE" --ocm-url @urlfile --ocm-retry-attempts 5
+
+	# Start OCM agent server persisting the OCM writes which couldn't be delivered, replayed every minute
+	ocm-agent serve --fleet-mode --services "$SERVICE" --ocm-url @urlfile --outbox-configmap ocm-agent-outbox --outbox-replay-interval 1m
+
+	# Start OCM agent server reconciling the limited support reasons with the notification records every 30 minutes
+	ocm-agent serve --fleet-mode --services "$SERVICE" --ocm-url @urlfile --limited-support-reconcile-interval 30m
+
+	# Start OCM agent server reading the notifications from the API server on every alert
+	ocm-agent serve --fleet-mode --services "$SERVICE" --ocm-url @urlfile --informer-cache=false
+
+	# Start OCM agent server pruning the notification records of the hosted clusters without a notification for a week
+	ocm-agent serve --fleet-mode --services "$SERVICE" --ocm-url @urlfile --record-retention 168h
+
+	# Start OCM agent server keeping a notification record per hosted cluster
+	ocm-agent serve --fleet-mode --services "$SERVICE" --ocm-url @urlfile --record-layout hosted-cluster
+
+	# Start OCM agent server without emitting Kubernetes Events on the notifications
+	ocm-agent serve --fleet-mode --services "$SERVICE" --ocm-url @urlfile --events=false
 	`)
 
 	sdkclient *sdk.Connection
@@ -117,6 +211,39 @@ func NewServeCmd() *cobra.Command {
 	cmd.Flags().StringVarP(&o.ocmClientSecret, config.OCMClientSecret, "", "", "OCM Client Secret for testing fleet mode (string)")
 	cmd.Flags().StringSliceVarP(&o.services, config.Services, "", []string{}, "OCM service name (string)")
 	cmd.Flags().BoolVar(&o.fleetMode, config.FleetMode, false, "Fleet Mode (bool)")
+	cmd.Flags().IntVar(&o.queueWorkers, config.QueueWorkers, 0, "Number of workers processing alerts asynchronously, alerts are processed synchronously if 0 (int)")
+	cmd.Flags().IntVar(&o.queueSize, config.QueueSize, 1000, "Maximum number of alerts waiting to be processed asynchronously (int)")
+	cmd.Flags().StringVar(&o.queueBacklogFile, config.QueueBacklogFile, "", "File persisting the alerts waiting to be processed asynchronously (string)")
+	cmd.Flags().BoolVar(&o.dryRun, config.DryRun, false, "Record the notifications instead of sending them to OCM (bool)")
+	cmd.Flags().StringVar(&o.authTokenFile, config.AuthTokenFile, "", "File holding the bearer token required on the webhook (string)")
+	cmd.Flags().StringVar(&o.authUsername, config.AuthUsername, "", "Basic auth username required on the webhook (string)")
+	cmd.Flags().StringVar(&o.authPasswordFile, config.AuthPasswordFile, "", "File holding the basic auth password required on the webhook (string)")
+	cmd.Flags().StringVar(&o.authClientCAFile, config.AuthClientCAFile, "", "CA bundle verifying the client certificates presented to the webhook (string)")
+	cmd.Flags().StringVar(&o.tlsCertFile, config.TLSCertFile, "", "Certificate served on the service and metrics ports (string)")
+	cmd.Flags().StringVar(&o.tlsKeyFile, config.TLSKeyFile, "", "Private key of the certificate served on the service and metrics ports (string)")
+	cmd.Flags().StringVar(&o.tlsServingCertDir, config.TLSServingCertDir, "", "Directory holding the served certificate as tls.crt and tls.key (string)")
+	cmd.Flags().BoolVar(&o.leaderElection, config.LeaderElection, false, "Elect a leader among the replicas, the only one processing alerts (bool)")
+	cmd.Flags().StringVar(&o.leaderElectionNS, config.LeaderElectionNamespace, handlers.OCMAgentNamespaceName, "Namespace holding the leader election Lease (string)")
+	cmd.Flags().Float64Var(&o.rateLimit.Global, config.RateLimitGlobal, 0, "OCM writes allowed per minute, unlimited if 0 (float)")
+	cmd.Flags().Float64Var(&o.rateLimit.PerCluster, config.RateLimitPerCluster, 0, "OCM writes allowed per minute for a cluster, unlimited if 0 (float)")
+	cmd.Flags().Float64Var(&o.rateLimit.PerTemplate, config.RateLimitPerTemplate, 0, "OCM writes allowed per minute for a notification template, unlimited if 0 (float)")
+	cmd.Flags().StringVar(&o.rateLimit.Policy, config.RateLimitPolicy, ratelimit.PolicyWait, "Policy for the throttled OCM writes, wait or drop (string)")
+	cmd.Flags().DurationVar(&o.rateLimit.MaxDelay, config.RateLimitMaxDelay, ratelimit.DefaultMaxDelay, "Longest a throttled OCM write waits before being deferred (duration)")
+	cmd.Flags().Float64Var(&o.circuitBreaker.FailureRatio, config.OCMCircuitBreakerFailureRatio, ocm.DefaultCircuitBreakerFailureRatio, "Ratio of failed OCM requests opening the circuit breaker, disabled if 0 (float)")
+	cmd.Flags().IntVar(&o.circuitBreaker.MinRequests, config.OCMCircuitBreakerMinRequests, ocm.DefaultCircuitBreakerMinRequests, "Number of OCM requests in a minute needed to open the circuit breaker (int)")
+	cmd.Flags().DurationVar(&o.circuitBreaker.OpenTimeout, config.OCMCircuitBreakerOpenTimeout, ocm.DefaultCircuitBreakerOpenTimeout, "Time the circuit breaker stays open before probing OCM again (duration)")
+	cmd.Flags().DurationVar(&o.healthInterval, config.OCMHealthCheckInterval, httpchecker.DefaultHealthTTL, "Time the availability of OCM is trusted before it is checked again (duration)")
+	cmd.Flags().DurationVar(&o.ocmRequestTimeout, config.OCMRequestTimeout, ocm.DefaultRequestTimeout, "Time an OCM request can take unless its operation has a specific timeout, unlimited if 0 (duration)")
+	cmd.Flags().IntVar(&o.ocmRetryAttempts, config.OCMRetryAttempts, ocm.DefaultRetryAttempts, "Number of attempts of the OCM requests failing transiently, not retried if 1 (int)")
+	cmd.Flags().StringToStringVar(&o.ocmOperationTimeouts, config.OCMOperationTimeouts, nil, "Time the OCM requests can take by operation, e.g. send_service_log=10s (map)")
+	cmd.Flags().StringVar(&o.outboxConfigMap, config.OutboxConfigMap, "", "ConfigMap persisting the OCM writes which couldn't be delivered, disabled if empty (string)")
+	cmd.Flags().DurationVar(&o.outboxReplayInterval, config.OutboxReplayInterval, outbox.DefaultReplayInterval, "Time between two replays of the OCM writes waiting in the outbox (duration)")
+	cmd.Flags().DurationVar(&o.lsReconcileInterval, config.LimitedSupportReconcileInterval, handlers.DefaultLimitedSupportReconcileInterval, "Time between two reconciliations of the limited support reasons with OCM in fleet mode, disabled if 0 (duration)")
+	cmd.Flags().DurationVar(&o.recordPruneInterval, config.RecordPruneInterval, handlers.DefaultRecordPruneInterval, "Time between two prunings of the notification records in fleet mode, disabled if 0 (duration)")
+	cmd.Flags().DurationVar(&o.recordRetention, config.RecordRetention, handlers.DefaultRecordRetention, "Time the notification record of a hosted cluster is kept without a notification, kept until the cluster is gone if 0 (duration)")
+	cmd.Flags().StringVar(&o.recordLayout, config.RecordLayout, handlers.RecordLayoutManagementCluster, "Keep the notification records by management-cluster or by hosted-cluster, migrating the records of the management clusters (string)")
+	cmd.Flags().BoolVar(&o.informerCache, config.InformerCache, true, "Read the notifications from an informer cache rather than from the API server on every alert (bool)")
+	cmd.Flags().BoolVar(&o.events, config.Events, true, "Emit Kubernetes Events on the notifications for their OCM writes (bool)")
 	cmd.PersistentFlags().BoolVarP(&o.debug, config.Debug, "d", false, "Debug mode enable")
 	kcmdutil.CheckErr(viper.BindPFlags(cmd.Flags()))
 
@@ -127,6 +254,12 @@ func NewServeCmd() *cobra.Command {
 	cmd.MarkFlagsRequiredTogether(config.AccessToken, config.ExternalClusterID)
 	// OCM Client ID and Secret required together in fleet mode
 	cmd.MarkFlagsRequiredTogether(config.OCMClientID, config.OCMClientSecret)
+	// Basic auth needs both the username and the password
+	cmd.MarkFlagsRequiredTogether(config.AuthUsername, config.AuthPasswordFile)
+	// The served certificate comes either from the certificate and key files or from a directory
+	cmd.MarkFlagsRequiredTogether(config.TLSCertFile, config.TLSKeyFile)
+	cmd.MarkFlagsMutuallyExclusive(config.TLSServingCertDir, config.TLSCertFile)
+	cmd.MarkFlagsMutuallyExclusive(config.TLSServingCertDir, config.TLSKeyFile)
 	// Can't pass combination of fleet mode and default mode flags together
 	cmd.MarkFlagsMutuallyExclusive(config.FleetMode, config.AccessToken)
 	cmd.MarkFlagsMutuallyExclusive(config.FleetMode, config.ExternalClusterID)
@@ -167,6 +300,10 @@ func (o *serveOptions) Run() error {
 	var ocmAgentURL string
 
 	o.logger.Info("Starting ocm-agent server")
+
+	// The context is cancelled on termination, aborting the in-flight work
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 	o.logger.WithField("URL", o.ocmURL).Debug("OCM URL configured")
 	o.logger.WithField("Service", o.services).Debug("OCM Service configured")
 
@@ -176,6 +313,13 @@ func (o *serveOptions) Run() error {
 		o.logger.WithField("FleetMode", o.fleetMode).Info("Fleet mode not configured")
 	}
 
+	// Serve TLS on both ports if a certificate is configured
+	tlsConfig, err := o.tlsConfig()
+	if err != nil {
+		o.logger.WithError(err).Fatal("Can't load the TLS certificate")
+		return err
+	}
+
 	// create new router for metrics
 	rMetrics := mux.NewRouter()
 	rMetrics.Path(consts.MetricsPath).Handler(promhttp.Handler())
@@ -189,8 +333,9 @@ func (o *serveOptions) Run() error {
 			Addr:              ":" + strconv.Itoa(consts.OCMAgentMetricsPort),
 			ReadHeaderTimeout: 3 * time.Second,
 			Handler:           rMetrics,
+			TLSConfig:         tlsConfig,
 		}
-		err := server.ListenAndServe()
+		err := listenAndServe(server)
 		if err != nil {
 			o.logger.WithError(err).Fatal("Failed to start listening on metrics port")
 			os.Exit(1)
@@ -204,6 +349,13 @@ func (o *serveOptions) Run() error {
 		return err
 	}
 
+	// Only the leader processes alerts when several replicas are deployed
+	elector, err := o.newElector()
+	if err != nil {
+		o.logger.WithError(err).Fatal("Can't initialise leader election")
+		return err
+	}
+
 	// Depending on whether the FleetMode is enabled or not, we need to initiate the OCM SDK connection accordingly
 	// If fleet mode is not enabled, we will fetch the cluster ID and access token to initiate connection with OCM
 	if !o.fleetMode {
@@ -262,7 +414,8 @@ func (o *serveOptions) Run() error {
 			}
 		}
 
-		sdkclient, err = sdk.NewConnectionBuilder().URL(ocmAgentURL).Client(ocmAgentClientID, ocmAgentClientSecret).Insecure(false).Build()
+		// The requests are retried by the OCM client, see ocm.WithRetries
+		sdkclient, err = sdk.NewConnectionBuilder().URL(ocmAgentURL).Client(ocmAgentClientID, ocmAgentClientSecret).Insecure(false).RetryLimit(0).Build()
 		if err != nil {
 			o.logger.WithError(err).Fatal("Can't initialise OCM sdk.connection client in fleet mode")
 			return err
@@ -270,23 +423,127 @@ func (o *serveOptions) Run() error {
 		o.logger.Info("Connection with OCM initialised successfully in fleet mode")
 	}
 
-	// Initialize OCMClient
-	ocmclient := ocm.NewOcmClient(sdkclient)
+	// The availability of OCM is tracked from the outcome of the requests and probed in the background when idle
+	health := httpchecker.NewHealthMonitor(httpchecker.NewHTTPChecker(nil), sdkclient.URL(), o.healthInterval)
+	go health.Run(ctx)
+
+	// Initialize OCMClient, failing fast while OCM is unavailable
+	timeouts, err := ocm.NewTimeouts(o.ocmRequestTimeout, o.ocmOperationTimeouts)
+	if err != nil {
+		o.logger.WithError(err).Fatal("Can't initialise the OCM request timeouts")
+		return err
+	}
+	clientOptions := []ocm.ClientOption{ocm.WithHealthMonitor(health), ocm.WithTimeouts(timeouts), ocm.WithRetries(ocm.RetryConfig(o.ocmRetryAttempts))}
+	// OCM being unavailable is handled by failing the alerts as retriable, the agent stays ready so that Alertmanager
+	// keeps reaching it, the checks only report it
+	readyzChecks := []handlers.ReadyzCheck{{Name: "ocm", Check: health.Available, Informational: true}}
+	if o.circuitBreaker.FailureRatio > 0 {
+		breaker := ocm.NewCircuitBreaker(o.circuitBreaker)
+		clientOptions = append(clientOptions, ocm.WithCircuitBreaker(breaker))
+		readyzChecks = append(readyzChecks, handlers.ReadyzCheck{Name: "ocm-circuit-breaker", Check: breaker.Ready, Informational: true})
+	}
+	ocmclient := ocm.NewOcmClient(sdkclient, clientOptions...)
+
+	// Authenticate the requests posting alerts
+	authenticator, err := auth.NewAuthenticator(auth.Config{
+		TokenFile:    o.authTokenFile,
+		Username:     o.authUsername,
+		PasswordFile: o.authPasswordFile,
+		ClientCAFile: o.authClientCAFile,
+	})
+	if err != nil {
+		o.logger.WithError(err).Fatal("Can't initialise the webhook authentication")
+		return err
+	}
+	if !authenticator.Enabled() {
+		o.logger.Warning("No authentication configured for the webhook")
+	}
+	serviceTLSConfig := tlsConfig
+	if authenticator.ClientCAs() != nil {
+		if tlsConfig == nil {
+			o.logger.Warning("The service is not served over TLS, clients can't present certificates")
+		} else {
+			// The certificate is verified against the CA by the authentication, the handshake only asks for it
+			serviceTLSConfig = tlsConfig.Clone()
+			serviceTLSConfig.ClientAuth = tls.VerifyClientCertIfGiven
+			serviceTLSConfig.ClientCAs = authenticator.ClientCAs()
+		}
+	}
 
 	// create a new router
 	r := mux.NewRouter()
 
 	livezHandler := handlers.NewLivezHandler()
-	readyzHandler := handlers.NewReadyzHandler()
+	readyzHandler := handlers.NewReadyzHandler(readyzChecks...)
 	r.Path(consts.LivezPath).Handler(livezHandler)
 	r.Path(consts.ReadyzPath).Handler(readyzHandler)
 
+	// The notifications sent to OCM are throttled by the rate limits
+	limiter, err := ratelimit.NewLimiter(o.rateLimit)
+	if err != nil {
+		o.logger.WithError(err).Fatal("Can't initialise the rate limits")
+		return err
+	}
+
+	// Notifications are recorded instead of being sent in dry-run mode, or when their template is annotated for it
+	dryRunRecorder := ocm.NewDryRunRecorder(ocm.DefaultDryRunRecords)
+	r.Path(consts.DryRunPath).Handler(authenticator.Middleware(handlers.NewDryRunHandler(dryRunRecorder)))
+	if o.dryRun {
+		o.logger.Info("Dry-run mode configured, notifications won't be sent to OCM")
+	}
+
+	// The webhook handlers report the notifications sent to OCM with Kubernetes Events
+	eventRecorder, err := o.startEventRecorder(ctx)
+	if err != nil {
+		o.logger.WithError(err).Fatal("Can't start the event recorder")
+		return err
+	}
+
 	if o.fleetMode {
 		// The webhook receiver is independent of the enabled services in the configmap
 		// as it's not a direct reverse proxy and doesn't directly reflect a single service
 		o.logger.Info("Initialising alertmanager webhook handler in fleet mode")
-		webhookReceiverHandler := handlers.NewWebhookRHOBSReceiverHandler(client, ocmclient)
-		r.Path(consts.WebhookReceiverPath).Handler(webhookReceiverHandler)
+		if !slices.Contains(handlers.RecordLayouts, o.recordLayout) {
+			err := fmt.Errorf("unknown notification record layout %q, expected one of %v", o.recordLayout, handlers.RecordLayouts)
+			o.logger.WithError(err).Fatal("Can't initialise the notification records")
+			return err
+		}
+		cache, err := o.startCache(ctx, &oav1alpha1.ManagedFleetNotification{})
+		if err != nil {
+			o.logger.WithError(err).Fatal("Can't start the informer cache")
+			return err
+		}
+		webhookReceiverHandler := handlers.NewWebhookRHOBSReceiverHandler(client, ocmclient).
+			WithDryRun(dryRunRecorder, o.dryRun).
+			WithRateLimiter(limiter).
+			WithHealthMonitor(health).
+			WithCache(cache).
+			WithRecordLayout(o.recordLayout).
+			WithEvents(eventRecorder)
+		if o.queueWorkers > 0 {
+			q, err := o.startQueue(webhookReceiverHandler.ProcessQueuedItem, elector)
+			if err != nil {
+				o.logger.WithError(err).Fatal("Can't start the alert processing queue")
+				return err
+			}
+			webhookReceiverHandler.WithQueue(q)
+		}
+		if ob := o.startOutbox(client, webhookReceiverHandler.ReplayOutboxEntry, elector); ob != nil {
+			webhookReceiverHandler.WithOutbox(ob)
+		}
+		if o.lsReconcileInterval > 0 {
+			o.logger.WithField("Interval", o.lsReconcileInterval).Info("Reconciling the limited support reasons with OCM")
+			elector.OnStartedLeading(func(ctx context.Context) {
+				webhookReceiverHandler.RunLimitedSupportReconciler(ctx, o.lsReconcileInterval)
+			})
+		}
+		if o.recordPruneInterval > 0 {
+			o.logger.WithFields(logrus.Fields{"Interval": o.recordPruneInterval, "Retention": o.recordRetention}).Info("Pruning the notification records")
+			elector.OnStartedLeading(func(ctx context.Context) {
+				webhookReceiverHandler.RunRecordPruner(ctx, o.recordPruneInterval, o.recordRetention)
+			})
+		}
+		r.Path(consts.WebhookReceiverPath).Handler(authenticator.Middleware(elector.Middleware(webhookReceiverHandler)))
 		r.Use(metrics.PrometheusMiddleware)
 	} else {
 		internalID, err := ocm.GetInternalIDByExternalID(o.externalClusterID, sdkclient)
@@ -301,8 +558,29 @@ func (o *serveOptions) Run() error {
 				// TODO: we might want to split this out of the service switch,
 				// see comment for fleet mode.
 				o.logger.Info("Initialising alertmanager webhook handler in NON-fleet mode")
-				webhookReceiverHandler := handlers.NewWebhookReceiverHandler(client, ocmclient)
-				r.Path(consts.WebhookReceiverPath).Handler(webhookReceiverHandler)
+				cache, err := o.startCache(ctx, &oav1alpha1.ManagedNotification{})
+				if err != nil {
+					o.logger.WithError(err).Fatal("Can't start the informer cache")
+					return err
+				}
+				webhookReceiverHandler := handlers.NewWebhookReceiverHandler(client, ocmclient).
+					WithDryRun(dryRunRecorder, o.dryRun).
+					WithRateLimiter(limiter).
+					WithHealthMonitor(health).
+					WithCache(cache).
+					WithEvents(eventRecorder)
+				if o.queueWorkers > 0 {
+					q, err := o.startQueue(webhookReceiverHandler.ProcessQueuedItem, elector)
+					if err != nil {
+						o.logger.WithError(err).Fatal("Can't start the alert processing queue")
+						return err
+					}
+					webhookReceiverHandler.WithQueue(q)
+				}
+				if ob := o.startOutbox(client, webhookReceiverHandler.ReplayOutboxEntry, elector); ob != nil {
+					webhookReceiverHandler.WithOutbox(ob)
+				}
+				r.Path(consts.WebhookReceiverPath).Handler(authenticator.Middleware(elector.Middleware(webhookReceiverHandler)))
 				r.Use(metrics.PrometheusMiddleware)
 			case config.ClustersService:
 				o.logger.Info("Initialising UpgradePolicy handlers")
@@ -318,6 +596,8 @@ func (o *serveOptions) Run() error {
 		}
 	}
 
+	go elector.Run(ctx)
+
 	// serve
 	o.logger.WithField("Port", consts.OCMAgentServicePort).Info("Start listening on service port")
 	// Adding ReadHeaderTimeout to fix below gosec error
@@ -326,10 +606,22 @@ func (o *serveOptions) Run() error {
 		Addr:              ":" + strconv.Itoa(consts.OCMAgentServicePort),
 		ReadHeaderTimeout: 3 * time.Second,
 		Handler:           r,
+		TLSConfig:         serviceTLSConfig,
+		// The requests are cancelled on termination
+		BaseContext: func(net.Listener) context.Context { return ctx },
 	}
-	err = server.ListenAndServe()
+	go func() {
+		<-ctx.Done()
+		o.logger.Info("Shutting down the OCM Agent server")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			o.logger.WithError(err).Warning("OCM Agent server didn't shut down gracefully")
+		}
+	}()
+	err = listenAndServe(server)
 	// err = http.ListenAndServe(":"+strconv.Itoa(consts.OCMAgentServicePort), r)
-	if err != nil {
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
 		o.logger.WithError(err).Fatal("OCM Agent failed to serve")
 		os.Exit(1)
 	}
@@ -337,6 +629,126 @@ func (o *serveOptions) Run() error {
 	return nil
 }
 
+// startQueue creates the alert processing queue, its workers process the alerts with the given function
+// once the replica leads
+func (o *serveOptions) startQueue(fn queue.ProcessFunc, elector *leader.Elector) (*queue.Queue, error) {
+	var store queue.Store
+	if o.queueBacklogFile != "" {
+		store = queue.NewFileStore(o.queueBacklogFile)
+	}
+
+	q, err := queue.NewQueue(o.queueWorkers, o.queueSize, store)
+	if err != nil {
+		return nil, err
+	}
+
+	o.logger.WithField("Workers", o.queueWorkers).Info("Processing alerts asynchronously")
+	elector.OnStartedLeading(func(ctx context.Context) {
+		if err := q.Start(ctx, fn); err != nil {
+			o.logger.WithError(err).Fatal("Can't start the alert processing queue")
+		}
+	})
+	return q, nil
+}
+
+// startOutbox creates the outbox holding the OCM writes which couldn't be delivered, it is replayed with the
+// given function once the replica leads. No outbox is used unless its ConfigMap is configured.
+func (o *serveOptions) startOutbox(c client.Client, deliver outbox.DeliverFunc, elector *leader.Elector) *outbox.Outbox {
+	if o.outboxConfigMap == "" {
+		return nil
+	}
+
+	ob := outbox.New(outbox.NewConfigMapStore(c, handlers.OCMAgentNamespaceName, o.outboxConfigMap))
+	o.logger.WithField("ConfigMap", o.outboxConfigMap).Info("Deferring the undelivered OCM writes to the outbox")
+	elector.OnStartedLeading(func(ctx context.Context) {
+		ob.Run(ctx, o.outboxReplayInterval, deliver)
+	})
+	return ob
+}
+
+// startCache starts the informer cache of the notifications read by the webhook handler, no cache is used if it
+// is disabled
+func (o *serveOptions) startCache(ctx context.Context, objects ...client.Object) (client.Reader, error) {
+	if !o.informerCache {
+		return nil, nil
+	}
+
+	o.logger.Info("Reading the notifications from an informer cache")
+	return k8s.StartCache(ctx, handlers.OCMAgentNamespaceName, objects...)
+}
+
+// startEventRecorder starts the recorder of the Kubernetes Events emitted on the notifications, no event is emitted
+// if it is disabled
+func (o *serveOptions) startEventRecorder(ctx context.Context) (*events.Recorder, error) {
+	if !o.events {
+		return nil, nil
+	}
+
+	recorder, err := k8s.StartEventRecorder(ctx, events.Component, events.CorrelatorOptions())
+	if err != nil {
+		return nil, err
+	}
+	o.logger.Info("Emitting Kubernetes Events on the notifications")
+	return events.NewRecorder(recorder), nil
+}
+
+// newElector returns the elector deciding whether this replica processes the alerts
+func (o *serveOptions) newElector() (*leader.Elector, error) {
+	if !o.leaderElection {
+		return leader.NewSingleReplicaElector(), nil
+	}
+
+	leases, err := k8s.NewCoordinationClient()
+	if err != nil {
+		return nil, err
+	}
+	// The pod name identifies the replica, the hostname matches it unless the downward API provides it
+	identity := os.Getenv("POD_NAME")
+	if identity == "" {
+		identity, err = os.Hostname()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	o.logger.WithFields(logrus.Fields{"Identity": identity, "Namespace": o.leaderElectionNS}).Info("Leader election configured")
+	return leader.NewElector(leases, o.leaderElectionNS, identity), nil
+}
+
+// tlsConfig returns the TLS configuration serving the configured certificate, which is reloaded when its files
+// change, or nil if no certificate is configured
+func (o *serveOptions) tlsConfig() (*tls.Config, error) {
+	certFile, keyFile := o.tlsCertFile, o.tlsKeyFile
+	if o.tlsServingCertDir != "" {
+		certFile = filepath.Join(o.tlsServingCertDir, consts.TLSServingCertFile)
+		keyFile = filepath.Join(o.tlsServingCertDir, consts.TLSServingKeyFile)
+	}
+	if certFile == "" {
+		return nil, nil
+	}
+
+	reloader, err := certreloader.NewCertReloader(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	go reloader.Watch(context.Background(), certreloader.DefaultPollInterval)
+
+	o.logger.WithField("CertFile", certFile).Info("Serving TLS")
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: reloader.GetCertificate,
+	}, nil
+}
+
+// listenAndServe serves TLS when the server has a TLS configuration, plaintext otherwise
+func listenAndServe(server *http.Server) error {
+	if server.TLSConfig != nil {
+		// The certificate is provided by the TLS configuration
+		return server.ListenAndServeTLS("", "")
+	}
+	return server.ListenAndServe()
+}
+
 func deleteFirstElementIfFileName(slice []string) []string {
 	if len(slice) > 0 && strings.HasPrefix(slice[0], "@") {
 		slice = slice[1:]
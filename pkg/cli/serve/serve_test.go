@@ -52,6 +52,39 @@ func TestServeCommandFlags(t *testing.T) {
 		{config.OCMClientSecret, "", "OCM Client Secret for testing fleet mode (string)"},
 		{config.Services, "", "OCM service name (string)"},
 		{config.FleetMode, "", "Fleet Mode (bool)"},
+		{config.QueueWorkers, "", "Number of workers processing alerts asynchronously, alerts are processed synchronously if 0 (int)"},
+		{config.QueueSize, "", "Maximum number of alerts waiting to be processed asynchronously (int)"},
+		{config.QueueBacklogFile, "", "File persisting the alerts waiting to be processed asynchronously (string)"},
+		{config.DryRun, "", "Record the notifications instead of sending them to OCM (bool)"},
+		{config.AuthTokenFile, "", "File holding the bearer token required on the webhook (string)"},
+		{config.AuthUsername, "", "Basic auth username required on the webhook (string)"},
+		{config.AuthPasswordFile, "", "File holding the basic auth password required on the webhook (string)"},
+		{config.AuthClientCAFile, "", "CA bundle verifying the client certificates presented to the webhook (string)"},
+		{config.TLSCertFile, "", "Certificate served on the service and metrics ports (string)"},
+		{config.TLSKeyFile, "", "Private key of the certificate served on the service and metrics ports (string)"},
+		{config.TLSServingCertDir, "", "Directory holding the served certificate as tls.crt and tls.key (string)"},
+		{config.LeaderElection, "", "Elect a leader among the replicas, the only one processing alerts (bool)"},
+		{config.LeaderElectionNamespace, "", "Namespace holding the leader election Lease (string)"},
+		{config.RateLimitGlobal, "", "OCM writes allowed per minute, unlimited if 0 (float)"},
+		{config.RateLimitPerCluster, "", "OCM writes allowed per minute for a cluster, unlimited if 0 (float)"},
+		{config.RateLimitPerTemplate, "", "OCM writes allowed per minute for a notification template, unlimited if 0 (float)"},
+		{config.RateLimitPolicy, "", "Policy for the throttled OCM writes, wait or drop (string)"},
+		{config.RateLimitMaxDelay, "", "Longest a throttled OCM write waits before being deferred (duration)"},
+		{config.OCMCircuitBreakerFailureRatio, "", "Ratio of failed OCM requests opening the circuit breaker, disabled if 0 (float)"},
+		{config.OCMCircuitBreakerMinRequests, "", "Number of OCM requests in a minute needed to open the circuit breaker (int)"},
+		{config.OCMCircuitBreakerOpenTimeout, "", "Time the circuit breaker stays open before probing OCM again (duration)"},
+		{config.OCMHealthCheckInterval, "", "Time the availability of OCM is trusted before it is checked again (duration)"},
+		{config.OCMRequestTimeout, "", "Time an OCM request can take unless its operation has a specific timeout, unlimited if 0 (duration)"},
+		{config.OCMOperationTimeouts, "", "Time the OCM requests can take by operation, e.g. send_service_log=10s (map)"},
+		{config.OCMRetryAttempts, "", "Number of attempts of the OCM requests failing transiently, not retried if 1 (int)"},
+		{config.OutboxConfigMap, "", "ConfigMap persisting the OCM writes which couldn't be delivered, disabled if empty (string)"},
+		{config.OutboxReplayInterval, "", "Time between two replays of the OCM writes waiting in the outbox (duration)"},
+		{config.RecordPruneInterval, "", "Time between two prunings of the notification records in fleet mode, disabled if 0 (duration)"},
+		{config.RecordRetention, "", "Time the notification record of a hosted cluster is kept without a notification, kept until the cluster is gone if 0 (duration)"},
+		{config.RecordLayout, "", "Keep the notification records by management-cluster or by hosted-cluster, migrating the records of the management clusters (string)"},
+		{config.InformerCache, "", "Read the notifications from an informer cache rather than from the API server on every alert (bool)"},
+		{config.Events, "", "Emit Kubernetes Events on the notifications for their OCM writes (bool)"},
+		{config.LimitedSupportReconcileInterval, "", "Time between two reconciliations of the limited support reasons with OCM in fleet mode, disabled if 0 (duration)"},
 		{config.Debug, "d", "Debug mode enable"},
 	}
 
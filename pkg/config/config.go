@@ -17,6 +17,72 @@ const (
 	OCMClientID string = "ocm-client-id"
 	// OCMClientSecret represents the OCM Client ID that will be used for testing fleet-mode run
 	OCMClientSecret string = "ocm-client-secret" //#nosec G101 -- This is a false positive
+	// QueueWorkers represents the number of workers processing alerts asynchronously, 0 processes them synchronously
+	QueueWorkers string = "queue-workers"
+	// QueueSize represents the maximum number of alerts waiting to be processed
+	QueueSize string = "queue-size"
+	// QueueBacklogFile represents the file the pending alerts are persisted to
+	QueueBacklogFile string = "queue-backlog-file"
+	// DryRun represents whether the notifications are recorded instead of being sent to OCM
+	DryRun string = "dry-run"
+	// AuthTokenFile represents the file holding the bearer token expected from Alertmanager
+	AuthTokenFile string = "auth-token-file"
+	// AuthUsername represents the basic auth username expected from Alertmanager
+	AuthUsername string = "auth-username"
+	// AuthPasswordFile represents the file holding the basic auth password expected from Alertmanager
+	AuthPasswordFile string = "auth-password-file" //#nosec G101 -- This is a false positive
+	// AuthClientCAFile represents the CA bundle verifying the client certificates presented to the webhook
+	AuthClientCAFile string = "auth-client-ca-file"
+	// TLSCertFile represents the certificate served on the service and metrics ports
+	TLSCertFile string = "tls-cert-file"
+	// TLSKeyFile represents the private key of the certificate served on the service and metrics ports
+	TLSKeyFile string = "tls-key-file"
+	// TLSServingCertDir represents a directory holding the served certificate as tls.crt and tls.key, e.g. a service serving certificate secret
+	TLSServingCertDir string = "tls-serving-cert-dir"
+	// LeaderElection represents whether the replicas elect a leader, the only one sending notifications
+	LeaderElection string = "leader-election"
+	// LeaderElectionNamespace represents the namespace holding the leader election Lease
+	LeaderElectionNamespace string = "leader-election-namespace"
+	// RateLimitGlobal represents the number of OCM writes allowed per minute
+	RateLimitGlobal string = "rate-limit-global"
+	// RateLimitPerCluster represents the number of OCM writes allowed per minute for a cluster
+	RateLimitPerCluster string = "rate-limit-per-cluster"
+	// RateLimitPerTemplate represents the number of OCM writes allowed per minute for a notification template
+	RateLimitPerTemplate string = "rate-limit-per-template"
+	// RateLimitPolicy represents whether the throttled OCM writes are delayed or dropped
+	RateLimitPolicy string = "rate-limit-policy"
+	// RateLimitMaxDelay represents the longest a throttled OCM write is delayed
+	RateLimitMaxDelay string = "rate-limit-max-delay"
+	// OCMCircuitBreakerFailureRatio represents the ratio of failed OCM requests opening the circuit breaker
+	OCMCircuitBreakerFailureRatio string = "ocm-circuit-breaker-failure-ratio"
+	// OCMCircuitBreakerMinRequests represents the number of OCM requests needed to open the circuit breaker
+	OCMCircuitBreakerMinRequests string = "ocm-circuit-breaker-min-requests"
+	// OCMCircuitBreakerOpenTimeout represents how long the circuit breaker stays open before probing OCM again
+	OCMCircuitBreakerOpenTimeout string = "ocm-circuit-breaker-open-timeout"
+	// OCMHealthCheckInterval represents how long the availability of OCM is trusted before it is checked again
+	OCMHealthCheckInterval string = "ocm-health-check-interval"
+	// OCMRequestTimeout represents the time an OCM request can take
+	OCMRequestTimeout string = "ocm-request-timeout"
+	// OCMOperationTimeouts represents the time the OCM requests can take by operation
+	OCMOperationTimeouts string = "ocm-operation-timeouts"
+	// OCMRetryAttempts represents the number of attempts of the OCM requests failing transiently
+	OCMRetryAttempts string = "ocm-retry-attempts"
+	// OutboxConfigMap represents the ConfigMap persisting the OCM writes which couldn't be delivered
+	OutboxConfigMap string = "outbox-configmap"
+	// OutboxReplayInterval represents the time between two replays of the outbox
+	OutboxReplayInterval string = "outbox-replay-interval"
+	// RecordPruneInterval represents the time between two prunings of the ManagedFleetNotificationRecords
+	RecordPruneInterval string = "record-prune-interval"
+	// RecordRetention represents how long the item of a hosted cluster is kept in a ManagedFleetNotificationRecord without a notification
+	RecordRetention string = "record-retention"
+	// RecordLayout represents whether the ManagedFleetNotificationRecords are kept by management or hosted cluster
+	RecordLayout string = "record-layout"
+	// InformerCache represents whether the notifications are read from an informer cache rather than from the API server
+	InformerCache string = "informer-cache"
+	// Events represents whether Kubernetes Events are emitted on the notifications for their OCM writes
+	Events string = "events"
+	// LimitedSupportReconcileInterval represents the time between two reconciliations of the limited support reasons with OCM
+	LimitedSupportReconcileInterval string = "limited-support-reconcile-interval"
 
 	ServiceLogService string = "service_logs" //#nosec G101 -- This is a false positive
 
@@ -14,6 +14,13 @@ const (
 	LivezPath = "/livez"
 	// Alertmanger webhook receiver path
 	WebhookReceiverPath = "/alertmanager-receiver"
+	// Path listing the notifications recorded instead of being sent in dry-run mode
+	DryRunPath = "/dry-run"
+
+	// Certificate file name in a service serving certificate directory
+	TLSServingCertFile = "tls.crt"
+	// Private key file name in a service serving certificate directory
+	TLSServingKeyFile = "tls.key"
 
 	// OCMAgentAccessFleetSecretPathBase is the base path where to find the secret
 	OCMAgentAccessFleetSecretPathBase = "/secrets/"
@@ -35,4 +42,21 @@ const (
 
 	// The max amount of pages allowed for a single OCM request.
 	OCMListRequestMaxPerPage = 100
+
+	// Annotation on ManagedNotification and ManagedFleetNotification selecting the engine rendering the notification texts
+	TemplateEngineAnnotation = "ocmagent.managed.openshift.io/template-engine"
+	// Annotation on ManagedNotification and ManagedFleetNotification recording its notifications instead of sending them
+	DryRunAnnotation = "ocmagent.managed.openshift.io/dry-run"
+	// Annotation on ManagedNotification listing the notifications placing the cluster into limited support
+	LimitedSupportAnnotation = "ocmagent.managed.openshift.io/limited-support"
+	// Annotation on ManagedFleetNotification holding the body of the service log sent when the alert resolves
+	ResolvedMessageAnnotation = "ocmagent.managed.openshift.io/resolved-message"
+	// Annotation on ManagedNotification recording the alert instances a notification was delivered for
+	DeliveredAlertsAnnotation = "ocmagent.managed.openshift.io/delivered-alerts"
+	// Annotation on ManagedFleetNotificationRecord and ManagedNotification recording the IDs of the limited support reasons posted by the agent
+	LimitedSupportReasonsAnnotation = "ocmagent.managed.openshift.io/limited-support-reasons"
+	// Label on the ManagedFleetNotificationRecord of a hosted cluster naming its management cluster
+	ManagementClusterLabel = "ocmagent.managed.openshift.io/management-cluster"
+	// Label on the ManagedFleetNotificationRecord of a hosted cluster naming the hosted cluster
+	HostedClusterLabel = "ocmagent.managed.openshift.io/hosted-cluster"
 )
@@ -0,0 +1,146 @@
+package events
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/openshift/ocm-agent/pkg/ocm"
+)
+
+// Component is the source of the events emitted by the agent
+const Component = "ocm-agent"
+
+const (
+	// ReasonServiceLogSent is a service log sent to OCM
+	ReasonServiceLogSent = "ServiceLogSent"
+	// ReasonLimitedSupportSet is a cluster placed into limited support
+	ReasonLimitedSupportSet = "LimitedSupportSet"
+	// ReasonLimitedSupportRemoved is a limited support reason removed from a cluster
+	ReasonLimitedSupportRemoved = "LimitedSupportRemoved"
+	// ReasonSendFailed is an OCM write which failed
+	ReasonSendFailed = "SendFailed"
+	// ReasonSuppressed is a notification not sent again within its resend wait
+	ReasonSuppressed = "NotificationSuppressed"
+)
+
+const (
+	// TemplateAnnotation is the annotation of the events holding the name of the notification template
+	TemplateAnnotation = "ocmagent.managed.openshift.io/template"
+	// ClusterAnnotation is the annotation of the events holding the ID of the cluster
+	ClusterAnnotation = "ocmagent.managed.openshift.io/cluster-id"
+	// OperationIDAnnotation is the annotation of the events holding the ID of the OCM operation, if any
+	OperationIDAnnotation = "ocmagent.managed.openshift.io/operation-id"
+)
+
+// Notification identifies the notification template and the cluster an event is about
+type Notification struct {
+	Template string
+	Cluster  string
+}
+
+// Recorder emits the events of the notifications on their ManagedNotification or ManagedFleetNotification. A nil
+// Recorder emits none.
+type Recorder struct {
+	recorder record.EventRecorder
+}
+
+// NewRecorder returns a Recorder emitting the events through the event recorder
+func NewRecorder(recorder record.EventRecorder) *Recorder {
+	return &Recorder{recorder: recorder}
+}
+
+// ServiceLogSent reports the firing or resolved service log sent for the notification
+func (r *Recorder) ServiceLogSent(obj runtime.Object, n Notification, operationID string, firing bool) {
+	state := "resolved"
+	if firing {
+		state = "firing"
+	}
+	r.emit(obj, n, operationID, corev1.EventTypeNormal, ReasonServiceLogSent, "Sent the %s service log of %s to cluster %s%s",
+		state, n.Template, n.Cluster, operationSuffix(operationID))
+}
+
+// LimitedSupportSet reports the cluster placed into limited support with the reason for the notification
+func (r *Recorder) LimitedSupportSet(obj runtime.Object, n Notification, operationID, reasonID string) {
+	r.emit(obj, n, operationID, corev1.EventTypeNormal, ReasonLimitedSupportSet, "Placed cluster %s into limited support for %s with reason '%s'%s",
+		n.Cluster, n.Template, reasonID, operationSuffix(operationID))
+}
+
+// LimitedSupportRemoved reports the limited support reason of the notification removed from the cluster
+func (r *Recorder) LimitedSupportRemoved(obj runtime.Object, n Notification, operationID, reasonID string) {
+	r.emit(obj, n, operationID, corev1.EventTypeNormal, ReasonLimitedSupportRemoved, "Removed the limited support reason '%s' of %s from cluster %s%s",
+		reasonID, n.Template, n.Cluster, operationSuffix(operationID))
+}
+
+// SendFailed reports the OCM operation which failed for the notification, e.g. ocm.OperationSendServiceLog
+func (r *Recorder) SendFailed(obj runtime.Object, n Notification, operation string, err error) {
+	operationID := ocm.OperationID(err)
+	r.emit(obj, n, operationID, corev1.EventTypeWarning, ReasonSendFailed, "Failed to %s for %s on cluster %s%s: %v",
+		operation, n.Template, n.Cluster, operationSuffix(operationID), err)
+}
+
+// Suppressed reports the notification not sent again to the cluster within its resend wait, in hours
+func (r *Recorder) Suppressed(obj runtime.Object, n Notification, resendWait int32) {
+	r.emit(obj, n, "", corev1.EventTypeNormal, ReasonSuppressed, "Not sending %s to cluster %s again within its resend wait of %dh",
+		n.Template, n.Cluster, resendWait)
+}
+
+// CorrelatorOptions returns the options of the correlator of the events emitted by the agent. The events of a
+// ManagedFleetNotification are about many clusters: they are rate limited and aggregated by notification template
+// and cluster rather than by object, so that an alert storm on a cluster neither drops nor combines the events of
+// the others.
+func CorrelatorOptions() record.CorrelatorOptions {
+	return record.CorrelatorOptions{
+		SpamKeyFunc: spamKey,
+		KeyFunc:     aggregatorKey,
+	}
+}
+
+// spamKey returns the key of the events sharing a rate limit: the ones from the same source about the same object,
+// notification template and cluster
+func spamKey(event *corev1.Event) string {
+	return strings.Join([]string{
+		event.Source.Component,
+		event.Source.Host,
+		event.InvolvedObject.Kind,
+		event.InvolvedObject.Namespace,
+		event.InvolvedObject.Name,
+		string(event.InvolvedObject.UID),
+		event.InvolvedObject.APIVersion,
+		event.Annotations[TemplateAnnotation],
+		event.Annotations[ClusterAnnotation],
+	}, "")
+}
+
+// aggregatorKey returns the key of the similar events combined into a single one, those of the same reason about
+// the same notification template and cluster, and the key of the identical events among them
+func aggregatorKey(event *corev1.Event) (string, string) {
+	aggregateKey, localKey := record.EventAggregatorByReasonFunc(event)
+	return aggregateKey + event.Annotations[TemplateAnnotation] + event.Annotations[ClusterAnnotation], localKey
+}
+
+// emit records the event with the annotations identifying the notification and the OCM operation
+func (r *Recorder) emit(obj runtime.Object, n Notification, operationID, eventType, reason, messageFmt string, args ...interface{}) {
+	if r == nil {
+		return
+	}
+	annotations := map[string]string{
+		TemplateAnnotation: n.Template,
+		ClusterAnnotation:  n.Cluster,
+	}
+	if operationID != "" {
+		annotations[OperationIDAnnotation] = operationID
+	}
+	r.recorder.AnnotatedEventf(obj, annotations, eventType, reason, messageFmt, args...)
+}
+
+// operationSuffix returns the mention of the OCM operation ID appended to the messages, if any
+func operationSuffix(operationID string) string {
+	if operationID == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (OCM operation ID %s)", operationID)
+}
@@ -0,0 +1,134 @@
+package events_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	oav1alpha1 "github.com/openshift/ocm-agent-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/openshift/ocm-agent/pkg/events"
+	"github.com/openshift/ocm-agent/pkg/ocm"
+)
+
+// eventSink records the events sent to the API server
+type eventSink struct {
+	mu     sync.Mutex
+	events []*corev1.Event
+}
+
+func (s *eventSink) record(event *corev1.Event) (*corev1.Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, event.DeepCopy())
+	return event, nil
+}
+
+func (s *eventSink) Create(event *corev1.Event) (*corev1.Event, error) { return s.record(event) }
+func (s *eventSink) Update(event *corev1.Event) (*corev1.Event, error) { return s.record(event) }
+func (s *eventSink) Patch(event *corev1.Event, _ []byte) (*corev1.Event, error) {
+	return s.record(event)
+}
+
+// messages returns the messages of the events sent for the cluster
+func (s *eventSink) messages(cluster string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var messages []string
+	for _, event := range s.events {
+		if event.Annotations[events.ClusterAnnotation] == cluster {
+			messages = append(messages, event.Message)
+		}
+	}
+	return messages
+}
+
+var _ = Describe("Event recorder", func() {
+
+	var (
+		fakeRecorder *record.FakeRecorder
+		recorder     *events.Recorder
+		mn           *oav1alpha1.ManagedNotification
+		notification = events.Notification{Template: "test-template", Cluster: "cluster-1"}
+	)
+
+	BeforeEach(func() {
+		fakeRecorder = record.NewFakeRecorder(10)
+		recorder = events.NewRecorder(fakeRecorder)
+		mn = &oav1alpha1.ManagedNotification{}
+	})
+
+	It("reports a service log sent with its OCM operation ID", func() {
+		recorder.ServiceLogSent(mn, notification, "operation-1", true)
+		Expect(fakeRecorder.Events).To(Receive(And(
+			HavePrefix("Normal ServiceLogSent Sent the firing service log of test-template to cluster cluster-1 (OCM operation ID operation-1)"),
+			ContainSubstring(events.OperationIDAnnotation+":operation-1"),
+			ContainSubstring(events.TemplateAnnotation+":test-template"),
+			ContainSubstring(events.ClusterAnnotation+":cluster-1"),
+		)))
+	})
+
+	It("reports the limited support reasons set and removed", func() {
+		recorder.LimitedSupportSet(mn, notification, "", "reason-1")
+		recorder.LimitedSupportRemoved(mn, notification, "", "reason-1")
+		Expect(fakeRecorder.Events).To(Receive(And(
+			HavePrefix("Normal LimitedSupportSet Placed cluster cluster-1 into limited support for test-template with reason 'reason-1' map["),
+			Not(ContainSubstring(events.OperationIDAnnotation)),
+		)))
+		Expect(fakeRecorder.Events).To(Receive(HavePrefix("Normal LimitedSupportRemoved Removed the limited support reason 'reason-1' of test-template from cluster cluster-1")))
+	})
+
+	It("reports a failed OCM write as a warning with the operation ID of the error", func() {
+		err := &ocm.ResponseError{Operation: ocm.OperationSendServiceLog, Status: http.StatusInternalServerError, OperationID: "operation-2"}
+		recorder.SendFailed(mn, notification, ocm.OperationSendServiceLog, err)
+		Expect(fakeRecorder.Events).To(Receive(And(
+			HavePrefix("Warning SendFailed Failed to send_service_log for test-template on cluster cluster-1 (OCM operation ID operation-2): "),
+			ContainSubstring(events.OperationIDAnnotation+":operation-2"),
+		)))
+	})
+
+	It("reports a failed OCM write without a response", func() {
+		recorder.SendFailed(mn, notification, ocm.OperationRemoveLimitedSupport, errors.New("OCM unavailable"))
+		Expect(fakeRecorder.Events).To(Receive(HavePrefix("Warning SendFailed Failed to remove_limited_support for test-template on cluster cluster-1: OCM unavailable")))
+	})
+
+	It("reports a notification suppressed within its resend wait", func() {
+		recorder.Suppressed(mn, notification, 24)
+		Expect(fakeRecorder.Events).To(Receive(HavePrefix("Normal NotificationSuppressed Not sending test-template to cluster cluster-1 again within its resend wait of 24h")))
+	})
+
+	It("neither drops nor combines the events of a cluster during an alert storm on another one", func() {
+		scheme := runtime.NewScheme()
+		Expect(oav1alpha1.AddToScheme(scheme)).To(Succeed())
+		sink := &eventSink{}
+		broadcaster := record.NewBroadcaster(record.WithCorrelatorOptions(events.CorrelatorOptions()))
+		broadcaster.StartRecordingToSink(sink)
+		defer broadcaster.Shutdown()
+		recorder := events.NewRecorder(broadcaster.NewRecorder(scheme, corev1.EventSource{Component: events.Component}))
+		mfn := &oav1alpha1.ManagedFleetNotification{ObjectMeta: metav1.ObjectMeta{Name: "test-mfn", Namespace: "openshift-ocm-agent-operator"}}
+
+		storm := events.Notification{Template: "test-template", Cluster: "cluster-1"}
+		for i := 0; i < 40; i++ {
+			recorder.SendFailed(mfn, storm, ocm.OperationSendServiceLog, fmt.Errorf("attempt %d failed", i))
+		}
+		recorder.ServiceLogSent(mfn, events.Notification{Template: "test-template", Cluster: "cluster-2"}, "operation-1", true)
+
+		Eventually(func() []string { return sink.messages("cluster-2") }).Should(ConsistOf(
+			"Sent the firing service log of test-template to cluster cluster-2 (OCM operation ID operation-1)",
+		))
+		// The events of the storm are still rate limited
+		Expect(len(sink.messages("cluster-1"))).To(BeNumerically("<", 40))
+	})
+
+	It("emits no event without a recorder", func() {
+		var recorder *events.Recorder
+		Expect(func() { recorder.ServiceLogSent(mn, notification, "operation-1", false) }).NotTo(Panic())
+	})
+})
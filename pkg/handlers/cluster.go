@@ -27,11 +27,11 @@ func NewClusterHandler(o ocm.OCMClient, clusterId string) *ClusterHandler {
 func (g *ClusterHandler) ServeClusterGet(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case "GET":
-		cluster, operationIdHeader, err := g.ocm.GetCluster(g.clusterId)
+		cluster, operationIdHeader, err := g.ocm.GetCluster(r.Context(), g.clusterId)
 
 		w.Header().Set(OCM_OPERATION_ID_HEADER, operationIdHeader)
 		if err != nil {
-			errorMessageResponse(err, w)
+			ocmErrorResponse(err, w)
 			return
 		}
 
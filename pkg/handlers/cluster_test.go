@@ -302,7 +302,7 @@ var _ = Describe("ClusterHandler", func() {
 			clusterHandler.ServeClusterGet(responseRecorder, req)
 
 			Expect(reflect.DeepEqual(ocmOperationId, responseRecorder.Header().Get(handlers.OCM_OPERATION_ID_HEADER))).To(BeTrue())
-			Expect(responseRecorder.Result().StatusCode).To(Equal(http.StatusBadRequest))
+			Expect(responseRecorder.Result().StatusCode).To(Equal(http.StatusBadGateway))
 		})
 
 		It("should return an error for POST method", func() {
@@ -328,7 +328,41 @@ var _ = Describe("ClusterHandler", func() {
 			clusterHandler.ServeClusterGet(responseRecorder, req)
 
 			Expect(reflect.DeepEqual(ocmOperationId, responseRecorder.Header().Get(handlers.OCM_OPERATION_ID_HEADER))).To(BeTrue())
-			Expect(responseRecorder.Result().StatusCode).To(Equal(http.StatusBadRequest))
+			Expect(responseRecorder.Result().StatusCode).To(Equal(http.StatusInternalServerError))
+		})
+
+		It("should return the error status answered by ocm", func() {
+			makeOCMRequest(
+				"GET",
+				http.StatusNotFound,
+				fmt.Sprintf("/api/clusters_mgmt/v1/clusters/%s", internalId),
+				`{"kind": "Error", "reason": "Cluster not found"}`,
+			)
+
+			req := httptest.NewRequest("GET", "/cluster", nil)
+
+			clusterHandler.ServeClusterGet(responseRecorder, req)
+
+			Expect(responseRecorder.Result().StatusCode).To(Equal(http.StatusNotFound))
+			Expect(responseRecorder.Body.String()).To(ContainSubstring("Cluster not found"))
+		})
+
+		It("should return a service unavailable error when the ocm circuit is open", func() {
+			accessToken := MakeTokenString("Bearer", 15*time.Minute)
+			sdkclient, _ := sdk.NewConnectionBuilder().
+				Logger(nil).
+				Tokens(accessToken).
+				URL(apiServer.URL()).
+				Build()
+			cb := ocm.NewCircuitBreaker(ocm.CircuitBreakerConfig{FailureRatio: 1, MinRequests: 1, Window: time.Minute, OpenTimeout: time.Hour})
+			_ = cb.Execute(func() (int, error) { return 0, fmt.Errorf("connection refused") })
+			clusterHandler = handlers.NewClusterHandler(ocm.NewOcmClient(sdkclient, ocm.WithCircuitBreaker(cb)), internalId)
+
+			req := httptest.NewRequest("GET", "/cluster", nil)
+
+			clusterHandler.ServeClusterGet(responseRecorder, req)
+
+			Expect(responseRecorder.Result().StatusCode).To(Equal(http.StatusServiceUnavailable))
 		})
 
 		It("should set correct content type for successful GET request", func() {
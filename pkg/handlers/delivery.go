@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/prometheus/alertmanager/template"
+	log "github.com/sirupsen/logrus"
+
+	oav1alpha1 "github.com/openshift/ocm-agent-operator/api/v1alpha1"
+
+	"github.com/openshift/ocm-agent/pkg/consts"
+)
+
+// deliveredAlert identifies the alert instance a notification was delivered for, and when
+type deliveredAlert struct {
+	Fingerprint string    `json:"fingerprint"`
+	StartsAt    time.Time `json:"startsAt"`
+	SentAt      time.Time `json:"sentAt"`
+}
+
+// matches checks if the delivery was made for the same alert instance
+func (d deliveredAlert) matches(alert template.Alert) bool {
+	return d.Fingerprint == alert.Fingerprint && d.StartsAt.Equal(alert.StartsAt)
+}
+
+// notificationDeliveries are the last firing and resolved deliveries of a notification
+type notificationDeliveries struct {
+	Firing   *deliveredAlert `json:"firing,omitempty"`
+	Resolved *deliveredAlert `json:"resolved,omitempty"`
+}
+
+// last returns the last firing or resolved delivery, if any
+func (d notificationDeliveries) last(firing bool) *deliveredAlert {
+	if firing {
+		return d.Firing
+	}
+	return d.Resolved
+}
+
+// deliveries returns the last deliveries of the notifications, recorded on the ManagedNotification by notification
+func deliveries(mn *oav1alpha1.ManagedNotification) map[string]notificationDeliveries {
+	recorded := map[string]notificationDeliveries{}
+	value, ok := mn.Annotations[consts.DeliveredAlertsAnnotation]
+	if !ok {
+		return recorded
+	}
+	if err := json.Unmarshal([]byte(value), &recorded); err != nil {
+		// The notifications are then sent as if they were never delivered
+		log.WithError(err).WithField(LogFieldManagedNotification, mn.Name).Warning("ignoring the malformed delivered alerts annotation")
+		return map[string]notificationDeliveries{}
+	}
+	return recorded
+}
+
+// recordDelivery records on the ManagedNotification the delivery of the firing or resolved notification, replacing
+// the previous one
+func recordDelivery(mn *oav1alpha1.ManagedNotification, name string, firing bool, delivery deliveredAlert) error {
+	recorded := deliveries(mn)
+	d := recorded[name]
+	if firing {
+		d.Firing = &delivery
+	} else {
+		d.Resolved = &delivery
+	}
+	recorded[name] = d
+
+	value, err := json.Marshal(recorded)
+	if err != nil {
+		return err
+	}
+	if mn.Annotations == nil {
+		mn.Annotations = map[string]string{}
+	}
+	mn.Annotations[consts.DeliveredAlertsAnnotation] = string(value)
+	return nil
+}
+
+// isDelivered checks if the notification was already delivered for this firing or resolved transition of the
+// alert instance, which happens when Alertmanager delivers the same alert again. The last firing and resolved
+// deliveries of each notification are recorded in an annotation of the ManagedNotification. A firing
+// notification for the same instance is still delivered again once the resend window has passed.
+func isDelivered(mn *oav1alpha1.ManagedNotification, n *oav1alpha1.Notification, alert template.Alert, firing bool) bool {
+	// Without a fingerprint the instances of the alert can't be told apart
+	if alert.Fingerprint == "" {
+		return false
+	}
+	d := deliveries(mn)[n.Name].last(firing)
+	if d == nil || !d.matches(alert) {
+		return false
+	}
+	if !firing {
+		return true
+	}
+	return time.Since(d.SentAt) < time.Duration(n.ResendWait)*time.Hour
+}
@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/alertmanager/template"
+	corev1 "k8s.io/api/core/v1"
+	k8serrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	ocmagentv1alpha1 "github.com/openshift/ocm-agent-operator/api/v1alpha1"
+
+	"github.com/openshift/ocm-agent/pkg/consts"
+	testconst "github.com/openshift/ocm-agent/pkg/consts/test"
+	clientmocks "github.com/openshift/ocm-agent/pkg/util/test/generated/mocks/client"
+)
+
+var _ = Describe("Notification deliveries", func() {
+
+	var (
+		mockCtrl         *gomock.Controller
+		mockClient       *clientmocks.MockClient
+		mockStatusWriter *clientmocks.MockStatusWriter
+		handler          *WebhookReceiverHandler
+		notification     ocmagentv1alpha1.Notification
+		mn               *ocmagentv1alpha1.ManagedNotification
+		alert            template.Alert
+	)
+
+	// record sets the notification status with the given deliveries of firing and resolved notifications
+	record := func(firing, resolved *deliveredAlert) {
+		status := ocmagentv1alpha1.NotificationRecord{Name: notification.Name}
+		now := &metav1.Time{Time: time.Now()}
+		_ = status.SetStatus(ocmagentv1alpha1.ConditionAlertFiring, "Alert starts firing", corev1.ConditionTrue, now)
+		_ = status.SetStatus(ocmagentv1alpha1.ConditionAlertResolved, "Alert has not resolved", corev1.ConditionFalse, now)
+		mn.Status.NotificationRecords = ocmagentv1alpha1.NotificationRecords{status}
+		if firing != nil {
+			Expect(recordDelivery(mn, notification.Name, true, *firing)).To(Succeed())
+		}
+		if resolved != nil {
+			Expect(recordDelivery(mn, notification.Name, false, *resolved)).To(Succeed())
+		}
+	}
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		mockClient = clientmocks.NewMockClient(mockCtrl)
+		mockStatusWriter = clientmocks.NewMockStatusWriter(mockCtrl)
+		handler = &WebhookReceiverHandler{c: mockClient}
+		notification = testconst.TestNotification
+		mn = &ocmagentv1alpha1.ManagedNotification{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-mn", Namespace: "openshift-ocm-agent-operator", ResourceVersion: "1"},
+		}
+		alert = testconst.NewTestAlert(false, false)
+		alert.Fingerprint = "abc123"
+		alert.StartsAt = time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+	})
+
+	Context("When checking if a notification was already delivered", func() {
+		It("should not report a delivery without any recorded", func() {
+			Expect(isDelivered(mn, &notification, alert, true)).To(BeFalse())
+			record(nil, nil)
+			Expect(isDelivered(mn, &notification, alert, true)).To(BeFalse())
+		})
+		It("should report a resolved notification delivered for the same alert instance", func() {
+			record(nil, &deliveredAlert{Fingerprint: alert.Fingerprint, StartsAt: alert.StartsAt, SentAt: time.Now().Add(-48 * time.Hour)})
+			Expect(isDelivered(mn, &notification, alert, false)).To(BeTrue())
+			Expect(isDelivered(mn, &notification, alert, true)).To(BeFalse())
+		})
+		It("should report a firing notification delivered within the resend window", func() {
+			record(&deliveredAlert{Fingerprint: alert.Fingerprint, StartsAt: alert.StartsAt, SentAt: time.Now()}, nil)
+			Expect(isDelivered(mn, &notification, alert, true)).To(BeTrue())
+		})
+		It("should not report a firing notification delivered before the resend window", func() {
+			record(&deliveredAlert{Fingerprint: alert.Fingerprint, StartsAt: alert.StartsAt, SentAt: time.Now().Add(-2 * time.Hour)}, nil)
+			Expect(isDelivered(mn, &notification, alert, true)).To(BeFalse())
+		})
+		It("should not report a delivery for another instance of the alert", func() {
+			record(&deliveredAlert{Fingerprint: alert.Fingerprint, StartsAt: alert.StartsAt.Add(-time.Hour), SentAt: time.Now()}, nil)
+			Expect(isDelivered(mn, &notification, alert, true)).To(BeFalse())
+		})
+		It("should not report a delivery recorded for another notification", func() {
+			Expect(recordDelivery(mn, "another-notification", true, deliveredAlert{Fingerprint: alert.Fingerprint, StartsAt: alert.StartsAt, SentAt: time.Now()})).To(Succeed())
+			Expect(isDelivered(mn, &notification, alert, true)).To(BeFalse())
+		})
+		It("should ignore a malformed record", func() {
+			mn.Annotations = map[string]string{consts.DeliveredAlertsAnnotation: "{"}
+			Expect(isDelivered(mn, &notification, alert, true)).To(BeFalse())
+		})
+		It("should not report a delivery for an alert without fingerprint", func() {
+			record(&deliveredAlert{StartsAt: alert.StartsAt, SentAt: time.Now()}, nil)
+			alert.Fingerprint = ""
+			Expect(isDelivered(mn, &notification, alert, true)).To(BeFalse())
+		})
+	})
+
+	Context("When updating the notification status", func() {
+		var updated, patched *ocmagentv1alpha1.ManagedNotification
+
+		// expectPatch expects the delivery to be recorded on the ManagedNotification with an optimistic lock
+		expectPatch := func() {
+			mockClient.EXPECT().Patch(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+				func(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+					patched = obj.(*ocmagentv1alpha1.ManagedNotification).DeepCopy()
+					data, err := patch.Data(obj)
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(string(data)).To(ContainSubstring(`"resourceVersion"`))
+					Expect(string(data)).To(ContainSubstring(consts.DeliveredAlertsAnnotation))
+					return nil
+				})
+		}
+
+		BeforeEach(func() {
+			updated, patched = nil, nil
+			mockClient.EXPECT().Get(gomock.Any(), client.ObjectKey{Namespace: mn.Namespace, Name: mn.Name}, gomock.Any()).DoAndReturn(
+				func(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+					mn.DeepCopyInto(obj.(*ocmagentv1alpha1.ManagedNotification))
+					return nil
+				}).AnyTimes()
+			mockClient.EXPECT().Status().Return(mockStatusWriter).AnyTimes()
+		})
+
+		It("should record the alert instance the notification was sent for", func() {
+			expectPatch()
+			mockStatusWriter.EXPECT().Update(gomock.Any(), gomock.Any()).DoAndReturn(
+				func(ctx context.Context, obj client.Object, opts ...client.SubResourceUpdateOption) error {
+					updated = obj.(*ocmagentv1alpha1.ManagedNotification)
+					return nil
+				})
+			_, err := handler.updateNotificationStatus(context.TODO(), &notification, mn, true, alert, corev1.ConditionTrue)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(isDelivered(patched, &notification, alert, true)).To(BeTrue())
+			Expect(isDelivered(patched, &notification, alert, false)).To(BeFalse())
+			Expect(updated.Annotations).To(Equal(patched.Annotations))
+		})
+		It("should keep the firing delivery once the alert resolved", func() {
+			record(&deliveredAlert{Fingerprint: alert.Fingerprint, StartsAt: alert.StartsAt, SentAt: time.Now()}, nil)
+			expectPatch()
+			mockStatusWriter.EXPECT().Update(gomock.Any(), gomock.Any()).DoAndReturn(
+				func(ctx context.Context, obj client.Object, opts ...client.SubResourceUpdateOption) error {
+					updated = obj.(*ocmagentv1alpha1.ManagedNotification)
+					return nil
+				})
+			_, err := handler.updateNotificationStatus(context.TODO(), &notification, mn, false, alert, corev1.ConditionTrue)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(isDelivered(patched, &notification, alert, true)).To(BeTrue())
+			Expect(isDelivered(patched, &notification, alert, false)).To(BeTrue())
+			status, err := updated.Status.GetNotificationRecord(notification.Name)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(status.Conditions.GetCondition(ocmagentv1alpha1.ConditionAlertFiring).Reason).To(Equal("Alert is not firing"))
+		})
+		It("should not record a notification which failed to be sent", func() {
+			mockStatusWriter.EXPECT().Update(gomock.Any(), gomock.Any()).DoAndReturn(
+				func(ctx context.Context, obj client.Object, opts ...client.SubResourceUpdateOption) error {
+					updated = obj.(*ocmagentv1alpha1.ManagedNotification)
+					return nil
+				})
+			_, err := handler.updateNotificationStatus(context.TODO(), &notification, mn, true, alert, corev1.ConditionFalse)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(isDelivered(updated, &notification, alert, true)).To(BeFalse())
+		})
+		It("should report an error if the delivery can't be recorded", func() {
+			mockClient.EXPECT().Patch(gomock.Any(), gomock.Any(), gomock.Any()).Return(k8serrs.NewInternalError(fmt.Errorf("a fake error")))
+			_, err := handler.updateNotificationStatus(context.TODO(), &notification, mn, true, alert, corev1.ConditionTrue)
+			Expect(err).Should(HaveOccurred())
+		})
+		It("should report an error if the status can't be updated", func() {
+			expectPatch()
+			mockStatusWriter.EXPECT().Update(gomock.Any(), gomock.Any()).Return(k8serrs.NewInternalError(fmt.Errorf("a fake error")))
+			_, err := handler.updateNotificationStatus(context.TODO(), &notification, mn, true, alert, corev1.ConditionTrue)
+			Expect(err).Should(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/openshift/ocm-agent/pkg/ocm"
+)
+
+type DryRunHandler struct {
+	recorder *ocm.DryRunRecorder
+}
+
+// dry-run endpoint response
+type DryRunResponse struct {
+	Records []ocm.DryRunRecord
+}
+
+func NewDryRunHandler(recorder *ocm.DryRunRecorder) *DryRunHandler {
+	return &DryRunHandler{recorder: recorder}
+}
+
+func (h *DryRunHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	log.Debug("Handling dry-run request")
+	// validate request
+	if r != nil && r.Method != http.MethodGet {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	// write response
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	response := DryRunResponse{
+		Records: h.recorder.Records(),
+	}
+	err := json.NewEncoder(w).Encode(response)
+	if err != nil {
+		log.Errorf("Failed to write to response: %s\n", err)
+		http.Error(w, "Failed to write to response", http.StatusInternalServerError)
+		return
+	}
+}
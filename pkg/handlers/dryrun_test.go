@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/onsi/gomega/ghttp"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/openshift/ocm-agent/pkg/ocm"
+)
+
+var _ = Describe("Dry-run tests", func() {
+
+	var (
+		recorder      *ocm.DryRunRecorder
+		dryRunHandler *DryRunHandler
+		server        *ghttp.Server
+	)
+
+	BeforeEach(func() {
+		recorder = ocm.NewDryRunRecorder(2)
+		dryRunHandler = NewDryRunHandler(recorder)
+		server = ghttp.NewServer()
+		server.AppendHandlers(dryRunHandler.ServeHTTP)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Context("Dry-run handler post", func() {
+		It("Returns the correct http status code", func() {
+			resp, err := http.Post(server.URL(), "application/json", nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(resp.StatusCode).Should(Equal(http.StatusMethodNotAllowed))
+		})
+	})
+	Context("Dry-run handler get", func() {
+		It("Returns the recorded notifications", func() {
+			recorder.Record(ocm.OperationSendServiceLog, "cluster-1", json.RawMessage(`{"summary":"first"}`))
+			recorder.Record(ocm.OperationSendServiceLog, "cluster-2", json.RawMessage(`{"summary":"second"}`))
+			recorder.Record(ocm.OperationSendLimitedSupport, "cluster-3", json.RawMessage(`{"summary":"third"}`))
+
+			resp, err := http.Get(server.URL())
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(resp.StatusCode).Should(Equal(http.StatusOK))
+			Expect(resp.Header.Get("Content-Type")).Should(Equal("application/json"))
+
+			var response DryRunResponse
+			Expect(json.NewDecoder(resp.Body).Decode(&response)).To(Succeed())
+			// The oldest record was dropped from the ring buffer
+			Expect(response.Records).To(HaveLen(2))
+			Expect(response.Records[0].ClusterID).To(Equal("cluster-2"))
+			Expect(response.Records[1].ClusterID).To(Equal("cluster-3"))
+			Expect(response.Records[1].Operation).To(Equal(ocm.OperationSendLimitedSupport))
+		})
+	})
+})
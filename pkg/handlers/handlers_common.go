@@ -1,10 +1,14 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
 
 	log "github.com/sirupsen/logrus"
+
+	"github.com/openshift/ocm-agent/pkg/ocm"
 )
 
 const OCM_OPERATION_ID_HEADER = "X-Operation-Id"
@@ -15,6 +19,24 @@ func errorMessageResponse(err error, w http.ResponseWriter) {
 	http.Error(w, fmt.Sprintf("%v", err), http.StatusBadRequest)
 }
 
+// ocmErrorResponse answers a proxied request with the status matching the error of the OCM client: the error
+// status returned by OCM if it answered one, otherwise the reason why the request couldn't be proxied
+func ocmErrorResponse(err error, w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/plain")
+	log.Error(err)
+	status := http.StatusBadGateway
+	var responseErr *ocm.ResponseError
+	switch {
+	case errors.As(err, &responseErr) && responseErr.Status >= http.StatusBadRequest:
+		status = responseErr.Status
+	case errors.Is(err, ocm.ErrCircuitOpen):
+		status = http.StatusServiceUnavailable
+	case errors.Is(err, context.DeadlineExceeded):
+		status = http.StatusGatewayTimeout
+	}
+	http.Error(w, fmt.Sprintf("%v", err), status)
+}
+
 func invalidRequestVerbResponse(method string, w http.ResponseWriter) {
 	w.Header().Set("Content-Type", "text/plain")
 	log.Errorf("Invalid request verb: %s", method)
@@ -1,15 +1,27 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/prometheus/alertmanager/template"
 	log "github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	"github.com/openshift/ocm-agent/pkg/consts"
+	"github.com/openshift/ocm-agent/pkg/events"
+	"github.com/openshift/ocm-agent/pkg/httpchecker"
+	"github.com/openshift/ocm-agent/pkg/metrics"
 	"github.com/openshift/ocm-agent/pkg/ocm"
+	"github.com/openshift/ocm-agent/pkg/outbox"
+	"github.com/openshift/ocm-agent/pkg/queue"
+	"github.com/openshift/ocm-agent/pkg/ratelimit"
 
 	_ "github.com/golang/mock/mockgen/model"
 )
@@ -21,25 +33,158 @@ const (
 	AMLabelAlertMCID           = "_mc_id"
 	AMLabelAlertHCID           = "_id"
 
-	LogFieldNotificationName           = "notification"
-	LogFieldNotificationRecordName     = "notification_record"
-	LogFieldResendInterval             = "resend_interval"
-	LogFieldAlertname                  = "alertname"
-	LogFieldAlert                      = "alert"
-	LogFieldIsFiring                   = "is_firing"
-	LogFieldManagedNotification        = "managed_notification_cr"
-	LogFieldPostServiceLogOpId         = "post_servicelog_operation_id"
-	LogFieldPostServiceLogFailedReason = "post_servicelog_failed_reason"
+	LogFieldNotificationName       = "notification"
+	LogFieldNotificationRecordName = "notification_record"
+	LogFieldResendInterval         = "resend_interval"
+	LogFieldAlertname              = "alertname"
+	LogFieldAlert                  = "alert"
+	LogFieldIsFiring               = "is_firing"
+	LogFieldFingerprint            = "fingerprint"
+	LogFieldManagedNotification    = "managed_notification_cr"
 
 	// Header returned in OCM responses
 	HeaderOperationId = "X-Operation-Id"
 )
 
+const (
+	// AlertOutcomeProcessed is reported for an alert which has been fully handled
+	AlertOutcomeProcessed = "processed"
+	// AlertOutcomeSkipped is reported for an alert which is not meant to be handled by ocm-agent
+	AlertOutcomeSkipped = "skipped"
+	// AlertOutcomeFailed is reported for an alert which could not be handled
+	AlertOutcomeFailed = "failed"
+	// AlertOutcomeDeferred is reported for an alert whose notification was added to the outbox to be sent later
+	AlertOutcomeDeferred = "deferred"
+	// AlertOutcomeDropped is reported for an alert whose notification was dropped by the rate limits, it is not
+	// delivered again
+	AlertOutcomeDropped = "dropped"
+)
+
+// errInvalidAlert is returned when an alert does not carry the labels required to be processed
+var errInvalidAlert = errors.New("alert does not meet valid criteria")
+
 // Alert Manager receiver response
 type AMReceiverResponse struct {
 	Error  error
 	Code   int
 	Status string
+	// Alerts holds the outcome of each alert of the request, it is empty when the alerts were not processed
+	Alerts []AMReceiverAlertResult `json:",omitempty"`
+}
+
+// AMReceiverAlertResult is the outcome of the processing of a single alert
+type AMReceiverAlertResult struct {
+	Fingerprint string
+	Template    string
+	Outcome     string
+	Error       string `json:",omitempty"`
+	// Retriable indicates the alert failed for a transient reason and should be delivered again
+	Retriable bool `json:",omitempty"`
+}
+
+// retriableError marks an error as transient, e.g. OCM being unavailable, so that Alertmanager delivers the alert again
+type retriableError struct {
+	err error
+}
+
+func (e *retriableError) Error() string {
+	return e.err.Error()
+}
+
+func (e *retriableError) Unwrap() error {
+	return e.err
+}
+
+// retriable wraps err so that isRetriable reports it as transient
+func retriable(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &retriableError{err: err}
+}
+
+// retriableOCMError marks the error of a failed OCM request as retriable, unless OCM rejected the request for good,
+// e.g. with a 4xx status, so that Alertmanager doesn't deliver again an alert which can't succeed
+func retriableOCMError(err error) error {
+	if !ocm.IsTransient(err) {
+		return err
+	}
+	return retriable(err)
+}
+
+// isRetriable indicates whether the failure could go away by processing the alert again later. The typed OCM
+// errors are classified by the OCM client whether or not they were marked as retriable, so that an alert OCM
+// rejected for good, or whose cluster OCM doesn't know, isn't delivered again.
+func isRetriable(err error) bool {
+	var responseErr *ocm.ResponseError
+	if errors.As(err, &responseErr) || errors.Is(err, ocm.ErrClusterNotFound) || errors.Is(err, ocm.ErrInvalidExternalID) {
+		return ocm.IsTransient(err)
+	}
+	var re *retriableError
+	if errors.As(err, &re) {
+		return true
+	}
+	return apierrors.IsConflict(err) ||
+		apierrors.IsServerTimeout(err) ||
+		apierrors.IsTimeout(err) ||
+		apierrors.IsTooManyRequests(err) ||
+		apierrors.IsServiceUnavailable(err) ||
+		apierrors.IsInternalError(err)
+}
+
+// alertResult reports the outcome of the processing of an alert from the error it returned
+func alertResult(alert template.Alert, err error) AMReceiverAlertResult {
+	result := AMReceiverAlertResult{
+		Fingerprint: alert.Fingerprint,
+		Template:    alert.Labels[AMLabelTemplateName],
+		Outcome:     AlertOutcomeProcessed,
+	}
+	switch {
+	case err == nil:
+	case errors.Is(err, errInvalidAlert):
+		result.Outcome = AlertOutcomeSkipped
+	case errors.Is(err, errDeferred):
+		result.Outcome = AlertOutcomeDeferred
+		result.Error = err.Error()
+	case errors.Is(err, ratelimit.ErrDropped):
+		result.Outcome = AlertOutcomeDropped
+		result.Error = err.Error()
+		metrics.CountDroppedAlert(result.Template)
+	default:
+		result.Outcome = AlertOutcomeFailed
+		result.Error = err.Error()
+		result.Retriable = isRetriable(err)
+	}
+	return result
+}
+
+// newAMReceiverResponse builds the response for a processed request out of the outcome of each of its alerts.
+// The response code is 503 when at least one alert failed for a transient reason so that Alertmanager
+// delivers the alerts again, alerts which can't succeed on a new attempt and the dropped alerts don't trigger
+// a redelivery.
+func newAMReceiverResponse(results []AMReceiverAlertResult) *AMReceiverResponse {
+	var errs []error
+	retry, failed := false, false
+	for _, result := range results {
+		if result.Outcome != AlertOutcomeFailed && result.Outcome != AlertOutcomeDropped {
+			continue
+		}
+		errs = append(errs, fmt.Errorf("alert %s for template %s: %s", result.Fingerprint, result.Template, result.Error))
+		failed = failed || result.Outcome == AlertOutcomeFailed
+		retry = retry || result.Retriable
+	}
+
+	response := &AMReceiverResponse{Error: errors.Join(errs...), Code: http.StatusOK, Status: "ok", Alerts: results}
+	switch {
+	case retry:
+		response.Code = http.StatusServiceUnavailable
+		response.Status = "some alerts could not be processed and should be retried"
+	case failed:
+		response.Status = "some alerts could not be processed"
+	case len(errs) > 0:
+		response.Status = "some alerts were dropped by the rate limits"
+	}
+	return response
 }
 
 // Use prometheus alertmanager template type for post data
@@ -48,12 +193,35 @@ type AMReceiverData template.Data
 type AMReceiverAlert template.Alert
 
 type WebhookReceiverHandler struct {
-	c   client.Client
+	c        client.Client
+	ocm      ocm.OCMClient
+	clusters *ocm.ClusterCache
+	queue    *queue.Queue
+	dryRun   dryRunConfig
+	limiter  *ratelimit.Limiter
+	health   *httpchecker.HealthMonitor
+	outbox   *outbox.Outbox
+	cache    client.Reader
+	events   *events.Recorder
+}
+
+// dryRunConfig selects the notifications which are recorded instead of being sent to OCM
+type dryRunConfig struct {
 	ocm ocm.OCMClient
+	all bool
 }
 
-type OCMResponseBody struct {
-	Reason string `json:"reason"`
+// clientFor returns the client delivering the notifications of the given ManagedNotification or
+// ManagedFleetNotification, and whether the notifications are only recorded
+func (d dryRunConfig) clientFor(obj metav1.Object, c ocm.OCMClient) (ocm.OCMClient, bool) {
+	if d.ocm == nil {
+		return c, false
+	}
+	annotated, _ := strconv.ParseBool(obj.GetAnnotations()[consts.DryRunAnnotation])
+	if d.all || annotated {
+		return d.ocm, true
+	}
+	return c, false
 }
 
 // isValidAlert indicates whether the supplied alert is one that warrants being processed for a notification.
@@ -103,31 +271,75 @@ func alertName(a template.Alert) (*string, error) {
 	return nil, fmt.Errorf("no alertname defined in alert")
 }
 
-// responseChecker checks the ocm response returns error or not
-func responseChecker(opId string, statusCode int, asBytes []byte) error {
-	if statusCode == http.StatusCreated {
-		log.WithField(LogFieldPostServiceLogOpId, opId).Info("service log sent succeeded")
-		return nil
+// throttle waits for the rate limits to allow an OCM write, a write deferred by the limiter is retriable
+func throttle(ctx context.Context, limiter *ratelimit.Limiter, operation, clusterID, template string) error {
+	err := limiter.Wait(ctx, operation, clusterID, template)
+	if errors.Is(err, ratelimit.ErrDeferred) {
+		return retriable(err)
 	}
+	return err
+}
 
-	var ocmRes OCMResponseBody
-	err := json.Unmarshal(asBytes, &ocmRes)
+// withClusterData adds the metadata of the cluster to the service log builder when its templates reference it
+func withClusterData(ctx context.Context, b *ocm.ServiceLogBuilder, clusters *ocm.ClusterCache, externalID string) error {
+	if !b.NeedsClusterData() {
+		return nil
+	}
+	if clusters == nil {
+		return fmt.Errorf("cluster metadata is not available to render the notification")
+	}
+	cluster, err := clusters.Get(ctx, externalID)
 	if err != nil {
-		return err
+		// A cluster OCM doesn't know, e.g. once deleted, won't be found on a new attempt either
+		return retriableOCMError(fmt.Errorf("unable to get the metadata of cluster %s: %w", externalID, err))
 	}
+	b.WithClusterData(ocm.ClusterData(cluster))
+	return nil
+}
 
-	log.WithFields(log.Fields{LogFieldPostServiceLogOpId: opId, LogFieldPostServiceLogFailedReason: ocmRes.Reason}).Error("service log sent failed")
+// enqueueAMReceiver splits the alert data into one queue item per alert, keyed by keyFn,
+// so alerts for the same key are processed in order while unrelated alerts don't wait on each other
+func enqueueAMReceiver(q *queue.Queue, d AMReceiverData, keyFn func(template.Alert) string) *AMReceiverResponse {
+	entries := make([]queue.Entry, 0, len(d.Alerts))
+	for _, alert := range d.Alerts {
+		item := d
+		item.Alerts = template.Alerts{alert}
+		entries = append(entries, queue.Entry{Key: keyFn(alert), Payload: item})
+	}
+	// The alerts are queued all together or not at all, so that the redelivery asked for doesn't duplicate them
+	if err := q.EnqueueAll(entries...); err != nil {
+		log.WithError(err).WithField("alerts", len(d.Alerts)).Error("unable to queue alerts")
+		code := http.StatusInternalServerError
+		if errors.Is(err, queue.ErrQueueFull) {
+			code = http.StatusServiceUnavailable
+		}
+		return &AMReceiverResponse{Error: err, Status: "unable to queue alerts", Code: code}
+	}
+	return &AMReceiverResponse{Error: nil, Status: "queued", Code: http.StatusOK}
+}
 
-	switch statusCode {
-	case http.StatusBadRequest:
-		return fmt.Errorf("validation errors occurred")
-	case http.StatusUnauthorized:
-		return fmt.Errorf("invalid auth token")
-	case http.StatusForbidden:
-		return fmt.Errorf("unauthorized to perform operation")
-	case http.StatusInternalServerError:
-		return fmt.Errorf("internal server error")
-	default:
-		return fmt.Errorf("unknown Service Log return code")
+// decodeQueuedItem returns the alert data held by a queue item
+func decodeQueuedItem(item queue.Item) (AMReceiverData, error) {
+	var d AMReceiverData
+	err := json.Unmarshal(item.Payload, &d)
+	if err != nil {
+		return d, fmt.Errorf("unable to decode queued alert data: %w", err)
+	}
+	return d, nil
+}
+
+// queuedResponseError turns an unsuccessful AMReceiverResponse of a queued item into an error, which is retriable
+// when the response asks for a redelivery
+func queuedResponseError(response *AMReceiverResponse) error {
+	if response.Code == http.StatusOK {
+		return nil
+	}
+	err := errors.New(response.Status)
+	if response.Error != nil {
+		err = fmt.Errorf("%s: %w", response.Status, response.Error)
+	}
+	if response.Code == http.StatusServiceUnavailable {
+		return queue.Retriable(err)
 	}
+	return err
 }
@@ -1,11 +1,23 @@
 package handlers
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/golang/mock/gomock"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"github.com/prometheus/alertmanager/template"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 
 	testconst "github.com/openshift/ocm-agent/pkg/consts/test"
+	"github.com/openshift/ocm-agent/pkg/ocm"
+	webhookreceivermock "github.com/openshift/ocm-agent/pkg/ocm/mocks"
+	"github.com/openshift/ocm-agent/pkg/queue"
+	"github.com/openshift/ocm-agent/pkg/ratelimit"
 )
 
 var _ = Describe("Webhook Handler Helpers", func() {
@@ -59,4 +71,96 @@ var _ = Describe("Webhook Handler Helpers", func() {
 			})
 		})
 	})
+
+	Context("When reporting the outcome of the alerts", func() {
+		It("should report processed alerts", func() {
+			r := alertResult(testAlert, nil)
+			Expect(r.Outcome).To(Equal(AlertOutcomeProcessed))
+			Expect(r.Template).To(Equal(testAlert.Labels[AMLabelTemplateName]))
+			Expect(r.Error).To(BeEmpty())
+		})
+		It("should report invalid alerts as skipped", func() {
+			r := alertResult(testAlert, errInvalidAlert)
+			Expect(r.Outcome).To(Equal(AlertOutcomeSkipped))
+		})
+		It("should classify transient failures as retriable", func() {
+			Expect(alertResult(testAlert, retriable(errors.New("OCM down"))).Retriable).To(BeTrue())
+			Expect(alertResult(testAlert, kerrors.NewConflict(schema.GroupResource{}, "mn", errors.New("conflict"))).Retriable).To(BeTrue())
+			Expect(alertResult(testAlert, kerrors.NewNotFound(schema.GroupResource{}, "mn")).Retriable).To(BeFalse())
+			Expect(alertResult(testAlert, errors.New("invalid template")).Retriable).To(BeFalse())
+		})
+		It("should classify the OCM failures by their type", func() {
+			rejected := &ocm.ForbiddenError{ResponseError: &ocm.ResponseError{Status: http.StatusForbidden}}
+			Expect(isRetriable(rejected)).To(BeFalse())
+			Expect(isRetriable(retriable(rejected))).To(BeFalse())
+			Expect(isRetriable(&ocm.RateLimitedError{ResponseError: &ocm.ResponseError{Status: http.StatusTooManyRequests}})).To(BeTrue())
+			Expect(isRetriable(&ocm.ServerError{ResponseError: &ocm.ResponseError{Status: http.StatusBadGateway}})).To(BeTrue())
+			Expect(isRetriable(retriable(fmt.Errorf("cluster %w", ocm.ErrClusterNotFound)))).To(BeFalse())
+			Expect(isRetriable(retriable(ocm.ErrInvalidExternalID))).To(BeFalse())
+		})
+		It("should answer ok when all the alerts were processed", func() {
+			response := newAMReceiverResponse([]AMReceiverAlertResult{alertResult(testAlert, nil), alertResult(testAlert, errInvalidAlert)})
+			Expect(response.Code).To(Equal(http.StatusOK))
+			Expect(response.Status).To(Equal("ok"))
+			Expect(response.Error).To(BeNil())
+			Expect(response.Alerts).To(HaveLen(2))
+		})
+		It("should not ask for a redelivery when the failures are permanent", func() {
+			response := newAMReceiverResponse([]AMReceiverAlertResult{alertResult(testAlert, nil), alertResult(testAlert, errors.New("invalid template"))})
+			Expect(response.Code).To(Equal(http.StatusOK))
+			Expect(response.Status).To(Equal("some alerts could not be processed"))
+			Expect(response.Error).To(HaveOccurred())
+		})
+		It("should ask for a redelivery when any failure is retriable", func() {
+			response := newAMReceiverResponse([]AMReceiverAlertResult{alertResult(testAlert, errors.New("invalid template")), alertResult(testAlert, retriable(errors.New("OCM down")))})
+			Expect(response.Code).To(Equal(http.StatusServiceUnavailable))
+			Expect(response.Error).To(HaveOccurred())
+		})
+		It("should report the alerts dropped by the rate limits without asking for a redelivery", func() {
+			result := alertResult(testAlert, fmt.Errorf("unable to send: %w", ratelimit.ErrDropped))
+			Expect(result.Outcome).To(Equal(AlertOutcomeDropped))
+			Expect(result.Retriable).To(BeFalse())
+			response := newAMReceiverResponse([]AMReceiverAlertResult{alertResult(testAlert, nil), result})
+			Expect(response.Code).To(Equal(http.StatusOK))
+			Expect(response.Status).To(Equal("some alerts were dropped by the rate limits"))
+			Expect(response.Error).To(HaveOccurred())
+		})
+		It("should process a queued item again only when a redelivery is asked for", func() {
+			Expect(queuedResponseError(&AMReceiverResponse{Status: "ok", Code: http.StatusOK})).To(Succeed())
+			permanent := queuedResponseError(&AMReceiverResponse{Status: "failed", Code: http.StatusInternalServerError})
+			Expect(permanent).To(HaveOccurred())
+			Expect(queue.IsRetriable(permanent)).To(BeFalse())
+			transient := queuedResponseError(newAMReceiverResponse([]AMReceiverAlertResult{alertResult(testAlert, retriable(errors.New("OCM down")))}))
+			Expect(queue.IsRetriable(transient)).To(BeTrue())
+		})
+	})
+
+	Context("When adding the cluster data to a notification", func() {
+		var (
+			mockOCMClient *webhookreceivermock.MockOCMClient
+			clusters      *ocm.ClusterCache
+			builder       *ocm.ServiceLogBuilder
+		)
+
+		BeforeEach(func() {
+			mockOCMClient = webhookreceivermock.NewMockOCMClient(gomock.NewController(GinkgoT()))
+			clusters = ocm.NewClusterCache(mockOCMClient, ocm.DefaultClusterCacheTTL)
+			builder = ocm.NewServiceLogBuilder("Cluster ${cluster.name} is degraded", "desc", "", "cluster-id", testconst.TestNotification.Severity, "", nil)
+		})
+
+		It("should ask for a redelivery when OCM fails transiently", func() {
+			mockOCMClient.EXPECT().GetClusterByExternalID(gomock.Any(), "cluster-id").Return(nil, &ocm.ServerError{ResponseError: &ocm.ResponseError{Status: http.StatusBadGateway}})
+			Expect(isRetriable(withClusterData(context.TODO(), builder, clusters, "cluster-id"))).To(BeTrue())
+		})
+		It("should not ask for a redelivery for a cluster OCM doesn't know", func() {
+			mockOCMClient.EXPECT().GetClusterByExternalID(gomock.Any(), "cluster-id").Return(nil, fmt.Errorf("cluster with external id cluster-id %w", ocm.ErrClusterNotFound))
+			err := withClusterData(context.TODO(), builder, clusters, "cluster-id")
+			Expect(err).To(MatchError(ocm.ErrClusterNotFound))
+			Expect(isRetriable(err)).To(BeFalse())
+		})
+		It("should not ask for a redelivery for an invalid external ID", func() {
+			mockOCMClient.EXPECT().GetClusterByExternalID(gomock.Any(), "cluster-id").Return(nil, ocm.ErrInvalidExternalID)
+			Expect(isRetriable(withClusterData(context.TODO(), builder, clusters, "cluster-id"))).To(BeFalse())
+		})
+	})
 })
@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+	"github.com/prometheus/alertmanager/template"
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	oav1alpha1 "github.com/openshift/ocm-agent-operator/api/v1alpha1"
+
+	"github.com/openshift/ocm-agent/pkg/consts"
+)
+
+// limitedSupportReasonsKey identifies the limited support reasons of a notification for a hosted cluster
+func limitedSupportReasonsKey(notification, hcID string) string {
+	return notification + "/" + hcID
+}
+
+// limitedSupportReasons returns the IDs of the limited support reasons posted by the agent, recorded on the
+// ManagedFleetNotificationRecord by notification and hosted cluster, or on the ManagedNotification by notification
+func limitedSupportReasons(obj client.Object) map[string][]string {
+	reasons := map[string][]string{}
+	value, ok := obj.GetAnnotations()[consts.LimitedSupportReasonsAnnotation]
+	if !ok {
+		return reasons
+	}
+	if err := json.Unmarshal([]byte(value), &reasons); err != nil {
+		// The reasons are then matched as for the records created before their IDs were recorded
+		log.WithError(err).WithField(LogFieldNotificationRecordName, obj.GetName()).Warning("ignoring the malformed limited support reasons annotation")
+		return map[string][]string{}
+	}
+	return reasons
+}
+
+// ownedLimitedSupportReasons returns the IDs of the limited support reasons the agent posted for the alert, and
+// whether they are known. They aren't for the records created before the IDs were recorded which show an
+// outstanding limited support, the reasons are then matched on their content.
+func (h *WebhookRHOBSReceiverHandler) ownedLimitedSupportReasons(ctx context.Context, alert template.Alert, mfn *oav1alpha1.ManagedFleetNotification) (ids []string, known bool, err error) {
+	mcID := alert.Labels[AMLabelAlertMCID]
+	hcID := alert.Labels[AMLabelAlertHCID]
+
+	mfnr, err := h.notificationRecord(ctx, mcID, hcID)
+	if err != nil {
+		if client.IgnoreNotFound(err) == nil {
+			return nil, true, nil
+		}
+		return nil, false, err
+	}
+
+	ids, known = recordedLimitedSupportReasons(mfnr, mcID, hcID, mfn.Spec.FleetNotification.Name)
+	return ids, known, nil
+}
+
+// recordedLimitedSupportReasons returns the IDs of the limited support reasons recorded on the
+// ManagedFleetNotificationRecord for the notification and hosted cluster, see ownedLimitedSupportReasons
+func recordedLimitedSupportReasons(mfnr *oav1alpha1.ManagedFleetNotificationRecord, mcID, hcID, notification string) (ids []string, known bool) {
+	ids, known = limitedSupportReasons(mfnr)[limitedSupportReasonsKey(notification, hcID)]
+	if known {
+		return ids, true
+	}
+
+	// Records created before the reason IDs were recorded only tell whether a limited support is outstanding
+	item, err := mfnr.GetNotificationRecordItem(mcID, notification, hcID)
+	if err != nil || item.FiringNotificationSentCount <= item.ResolvedNotificationSentCount {
+		return nil, true
+	}
+	return nil, false
+}
+
+// ownsLimitedSupportReason indicates whether the limited support reason was posted by the agent for the
+// notification. Without known IDs, only a reason identical to the one the agent posts is considered its own.
+func ownsLimitedSupportReason(reason *cmv1.LimitedSupportReason, summary, details string, ids []string, known bool) bool {
+	if known {
+		for _, id := range ids {
+			if id == reason.ID() {
+				return true
+			}
+		}
+		return false
+	}
+	return reason.Summary() == summary &&
+		reason.Details() == details &&
+		reason.DetectionType() == cmv1.DetectionTypeManual
+}
+
+// updateLimitedSupportReasons records the IDs of the limited support reasons posted by the agent for the hosted
+// cluster on the ManagedFleetNotificationRecord holding its items, which is created if needed
+func (h *WebhookRHOBSReceiverHandler) updateLimitedSupportReasons(ctx context.Context, mcID, hcID string, mfn *oav1alpha1.ManagedFleetNotification, update func(ids []string) []string) error {
+	key := limitedSupportReasonsKey(mfn.Spec.FleetNotification.Name, hcID)
+
+	return retryOnConflictOrAlreadyExists(ctx, retryConfig, func() error {
+		mfnr, err := h.getOrCreateManagedFleetNotificationRecord(ctx, mcID, hcID, mfn)
+		if err != nil {
+			return err
+		}
+
+		base := mfnr.DeepCopy()
+		reasons := limitedSupportReasons(mfnr)
+		if ids := update(reasons[key]); len(ids) > 0 {
+			reasons[key] = ids
+		} else {
+			delete(reasons, key)
+		}
+
+		if err := setLimitedSupportReasons(mfnr, reasons); err != nil {
+			return err
+		}
+		// Only the annotation is patched, the optimistic lock keeps the IDs recorded concurrently
+		return h.c.Patch(ctx, mfnr, client.MergeFromWithOptions(base, client.MergeFromWithOptimisticLock{}))
+	})
+}
+
+// setLimitedSupportReasons records the IDs of the limited support reasons posted by the agent on the
+// ManagedFleetNotificationRecord or ManagedNotification, see limitedSupportReasons
+func setLimitedSupportReasons(obj client.Object, reasons map[string][]string) error {
+	annotations := obj.GetAnnotations()
+	if len(reasons) == 0 {
+		delete(annotations, consts.LimitedSupportReasonsAnnotation)
+		obj.SetAnnotations(annotations)
+		return nil
+	}
+	value, err := json.Marshal(reasons)
+	if err != nil {
+		return err
+	}
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[consts.LimitedSupportReasonsAnnotation] = string(value)
+	obj.SetAnnotations(annotations)
+	return nil
+}
+
+// managedLimitedSupportReasons returns the IDs of the limited support reasons the agent posted for the
+// notification, recorded on the ManagedNotification, and whether they are known. They aren't for the
+// notifications recorded before the IDs were, which show an outstanding limited support: the reasons are
+// then matched on their content.
+func managedLimitedSupportReasons(mn *oav1alpha1.ManagedNotification, name string) (ids []string, known bool) {
+	ids, known = limitedSupportReasons(mn)[name]
+	if known {
+		return ids, true
+	}
+
+	// The limited support is outstanding when it was set for the alert still firing
+	record, err := mn.Status.GetNotificationRecord(name)
+	if err != nil {
+		return nil, true
+	}
+	firing := record.Conditions.GetCondition(oav1alpha1.ConditionAlertFiring)
+	sent := record.Conditions.GetCondition(oav1alpha1.ConditionServiceLogSent)
+	if firing == nil || firing.Status != corev1.ConditionTrue || sent == nil || sent.Status != corev1.ConditionTrue {
+		return nil, true
+	}
+	return nil, false
+}
+
+// updateManagedLimitedSupportReasons records the IDs of the limited support reasons posted by the agent for the
+// notification on its ManagedNotification
+func (h *WebhookReceiverHandler) updateManagedLimitedSupportReasons(ctx context.Context, mn *oav1alpha1.ManagedNotification, name string, update func(ids []string) []string) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		m := &oav1alpha1.ManagedNotification{}
+		if err := h.c.Get(ctx, client.ObjectKeyFromObject(mn), m); err != nil {
+			return err
+		}
+		base := m.DeepCopy()
+
+		reasons := limitedSupportReasons(m)
+		if ids := update(reasons[name]); len(ids) > 0 {
+			reasons[name] = ids
+		} else {
+			delete(reasons, name)
+		}
+
+		if err := setLimitedSupportReasons(m, reasons); err != nil {
+			return err
+		}
+		return h.c.Patch(ctx, m, client.MergeFromWithOptions(base, client.MergeFromWithOptimisticLock{}))
+	})
+}
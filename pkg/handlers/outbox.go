@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/prometheus/alertmanager/template"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/openshift/ocm-agent/pkg/metrics"
+	"github.com/openshift/ocm-agent/pkg/ocm"
+	"github.com/openshift/ocm-agent/pkg/outbox"
+)
+
+// errDeferred is returned for an alert whose OCM write was added to the outbox, to be replayed once OCM recovers
+var errDeferred = errors.New("OCM write deferred to the outbox")
+
+// errOutboxPending is returned for an alert of a cluster whose earlier OCM writes are waiting in the outbox
+var errOutboxPending = errors.New("earlier OCM writes of the cluster are waiting in the outbox")
+
+// deferToOutbox adds the OCM write of the alert which failed with err to the outbox, rather than relying on
+// Alertmanager delivering the alert again. Only the transient failures are deferred, err is returned as is
+// otherwise or without an outbox.
+func deferToOutbox(ctx context.Context, o *outbox.Outbox, entry outbox.Entry, err error) error {
+	if o == nil || !isRetriable(err) || !ocm.IsTransient(err) {
+		return err
+	}
+	if addErr := o.Add(context.WithoutCancel(ctx), entry); addErr != nil {
+		log.WithError(addErr).WithField(LogFieldNotificationName, entry.Notification).Error("unable to add the OCM write to the outbox")
+		return err
+	}
+	return fmt.Errorf("%w: %w", errDeferred, err)
+}
+
+// dropResolvedFromOutbox removes from the outbox the firing OCM writes of an alert which has since resolved
+func dropResolvedFromOutbox(ctx context.Context, o *outbox.Outbox, cluster, notification string, alert template.Alert) {
+	dropped, err := o.Drop(ctx, func(e outbox.Entry) bool {
+		return e.Firing() && e.Cluster == cluster && e.Notification == notification && e.Alert.Fingerprint == alert.Fingerprint
+	})
+	if err != nil {
+		log.WithError(err).WithField(LogFieldNotificationName, notification).Warning("unable to drop the writes of the resolved alert from the outbox")
+		return
+	}
+	if dropped > 0 {
+		log.WithFields(log.Fields{LogFieldNotificationName: notification, LogFieldFingerprint: alert.Fingerprint}).Infof("dropped %d OCM writes of the resolved alert from the outbox", dropped)
+	}
+}
+
+// replayResult turns the outcome of the replay of an outbox entry into the error keeping it in the outbox, the
+// entries which can't be delivered on a new attempt are dropped
+func replayResult(entry outbox.Entry, err error) error {
+	if err == nil || (isRetriable(err) && ocm.IsTransient(err)) {
+		return err
+	}
+	log.WithError(err).WithFields(log.Fields{LogFieldNotificationName: entry.Notification, "cluster": entry.Cluster}).Error("dropping an OCM write of the outbox which can't be delivered")
+	metrics.CountOutboxDeadLetter(entry.Operation, "rejected")
+	return nil
+}
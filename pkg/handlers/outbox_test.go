@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/alertmanager/template"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	testconst "github.com/openshift/ocm-agent/pkg/consts/test"
+	"github.com/openshift/ocm-agent/pkg/ocm"
+	webhookreceivermock "github.com/openshift/ocm-agent/pkg/ocm/mocks"
+	"github.com/openshift/ocm-agent/pkg/outbox"
+	clientmocks "github.com/openshift/ocm-agent/pkg/util/test/generated/mocks/client"
+)
+
+// memoryOutboxStore keeps the entries of the outbox in memory
+type memoryOutboxStore struct {
+	mu      sync.Mutex
+	entries []outbox.Entry
+}
+
+func (s *memoryOutboxStore) Load(_ context.Context) ([]outbox.Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]outbox.Entry(nil), s.entries...), nil
+}
+
+func (s *memoryOutboxStore) Save(_ context.Context, entries []outbox.Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = append([]outbox.Entry(nil), entries...)
+	return nil
+}
+
+var _ = Describe("Outbox", func() {
+	var (
+		mockCtrl      *gomock.Controller
+		mockClient    *clientmocks.MockClient
+		mockOCMClient *webhookreceivermock.MockOCMClient
+		testHandler   *WebhookRHOBSReceiverHandler
+		testOutbox    *outbox.Outbox
+		alert         template.Alert
+		mfn           = testconst.NewManagedFleetNotification(false)
+		entry         outbox.Entry
+	)
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		mockClient = clientmocks.NewMockClient(mockCtrl)
+		mockOCMClient = webhookreceivermock.NewMockOCMClient(mockCtrl)
+		testOutbox = outbox.New(&memoryOutboxStore{})
+		Expect(testOutbox.Load(context.TODO())).To(Succeed())
+		testHandler = (&WebhookRHOBSReceiverHandler{c: mockClient, ocm: mockOCMClient}).WithOutbox(testOutbox)
+		alert = testconst.NewTestAlert(false, true)
+		entry = outbox.Entry{Cluster: testconst.TestHostedClusterID, Operation: ocm.OperationSendServiceLog, Notification: mfn.Spec.FleetNotification.Name, Alert: alert}
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	// expectServiceLog expects the processing of the alert up to sending its service log
+	expectServiceLog := func(err error) {
+		gomock.InOrder(
+			mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).SetArg(2, mfn),
+			mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(kerrors.NewNotFound(schema.GroupResource{}, "not-found")),
+			mockOCMClient.EXPECT().SendServiceLog(gomock.Any(), gomock.Any()).Return("", err),
+		)
+	}
+
+	DescribeTable("only defers the transient failures",
+		func(err error, deferred bool) {
+			result := deferToOutbox(context.TODO(), testOutbox, entry, err)
+			Expect(errors.Is(result, errDeferred)).To(Equal(deferred))
+			Expect(testOutbox.Pending(entry.Cluster)).To(Equal(deferred))
+		},
+		Entry("OCM unreachable", retriable(errors.New("connection refused")), true),
+		Entry("an OCM server error", retriable(&ocm.ServerError{ResponseError: &ocm.ResponseError{Status: http.StatusBadGateway}}), true),
+		Entry("a request rejected by OCM", retriable(&ocm.ResponseError{Status: http.StatusBadRequest}), false),
+		Entry("a failure which isn't retriable", errors.New("invalid template"), false),
+	)
+
+	It("doesn't defer without an outbox", func() {
+		err := retriable(errors.New("connection refused"))
+		Expect(deferToOutbox(context.TODO(), nil, entry, err)).To(Equal(err))
+	})
+
+	It("defers the service log which can't be sent, and the next alerts of the cluster", func() {
+		expectServiceLog(errors.New("OCM unavailable"))
+		response := testHandler.processAMReceiver(AMReceiverData{Alerts: []template.Alert{alert}}, context.Background())
+		Expect(response.Code).To(Equal(http.StatusOK))
+		Expect(response.Alerts).To(HaveLen(1))
+		Expect(response.Alerts[0].Outcome).To(Equal(AlertOutcomeDeferred))
+		Expect(response.Alerts[0].Error).To(ContainSubstring("OCM unavailable"))
+		Expect(testOutbox.Len()).To(Equal(1))
+
+		next := testconst.NewTestAlert(false, true)
+		next.Fingerprint = "next-fingerprint"
+		mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).SetArg(2, mfn)
+		response = testHandler.processAMReceiver(AMReceiverData{Alerts: []template.Alert{next}}, context.Background())
+		Expect(response.Alerts[0].Outcome).To(Equal(AlertOutcomeDeferred))
+		Expect(testOutbox.Len()).To(Equal(2))
+	})
+
+	It("drops the firing entry of an alert which resolved", func() {
+		Expect(testOutbox.Add(context.TODO(), entry)).To(Succeed())
+		dropResolvedFromOutbox(context.TODO(), testOutbox, entry.Cluster, entry.Notification, testconst.NewTestAlert(true, true))
+		Expect(testOutbox.Len()).To(BeZero())
+	})
+
+	Context("When replaying an entry", func() {
+		It("keeps the entry while OCM fails transiently", func() {
+			expectServiceLog(errors.New("OCM unavailable"))
+			Expect(testHandler.ReplayOutboxEntry(context.TODO(), entry)).To(MatchError(ContainSubstring("OCM unavailable")))
+		})
+
+		It("drops the entry OCM rejects", func() {
+			expectServiceLog(&ocm.ResponseError{Status: http.StatusBadRequest})
+			Expect(testHandler.ReplayOutboxEntry(context.TODO(), entry)).To(Succeed())
+		})
+
+		It("doesn't wait on the entries of the cluster", func() {
+			Expect(testOutbox.Add(context.TODO(), entry)).To(Succeed())
+			expectServiceLog(errors.New("OCM unavailable"))
+			Expect(testHandler.ReplayOutboxEntry(context.TODO(), entry)).To(HaveOccurred())
+			Expect(testOutbox.Len()).To(Equal(1))
+		})
+	})
+})
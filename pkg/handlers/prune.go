@@ -0,0 +1,277 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	oav1alpha1 "github.com/openshift/ocm-agent-operator/api/v1alpha1"
+
+	"github.com/openshift/ocm-agent/pkg/consts"
+	"github.com/openshift/ocm-agent/pkg/metrics"
+	"github.com/openshift/ocm-agent/pkg/ocm"
+)
+
+const (
+	// DefaultRecordPruneInterval is the time between two prunings of the ManagedFleetNotificationRecords
+	DefaultRecordPruneInterval = time.Hour
+	// DefaultRecordRetention is how long the item of a hosted cluster is kept without a notification
+	DefaultRecordRetention = 30 * 24 * time.Hour
+
+	// PruneReasonClusterGone is the item of a hosted cluster OCM no longer knows about
+	PruneReasonClusterGone = "cluster_gone"
+	// PruneReasonIdle is the item of a hosted cluster which wasn't notified within the retention
+	PruneReasonIdle = "idle"
+	// PruneReasonMigrated is the item of the record of a management cluster migrated to the record of its hosted
+	// cluster, see RecordLayoutHostedCluster
+	PruneReasonMigrated = "migrated"
+
+	// recentlyNotified is the time the hosted clusters notified are assumed to still exist, so that the pruning
+	// doesn't look up every cluster in OCM
+	recentlyNotified = time.Hour
+)
+
+// prunedItem identifies a record item to prune, along with the last transition it was seen with so that an item
+// notified since isn't pruned
+type prunedItem struct {
+	notification   string
+	hcID           string
+	lastTransition *time.Time
+	reason         string
+}
+
+// RunRecordPruner prunes the ManagedFleetNotificationRecords every interval until ctx is cancelled, it is meant to
+// run on the leader
+func (h *WebhookRHOBSReceiverHandler) RunRecordPruner(ctx context.Context, interval, retention time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := h.PruneNotificationRecords(ctx, retention); err != nil {
+			log.WithError(err).Error("Unable to prune the notification records, retrying later")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// PruneNotificationRecords removes from the ManagedFleetNotificationRecords the items of the hosted clusters OCM
+// reports as gone, and the items which weren't notified within the retention, 0 keeping them. In the hosted cluster
+// layout, the items migrated to the records of the hosted clusters are removed from the records of the management
+// clusters, and the records of the hosted clusters left empty are deleted. The size of the records is reported by
+// metrics.
+func (h *WebhookRHOBSReceiverHandler) PruneNotificationRecords(ctx context.Context, retention time.Duration) error {
+	mfnList := &oav1alpha1.ManagedFleetNotificationList{}
+	if err := h.reader().List(ctx, mfnList, client.InNamespace(OCMAgentNamespaceName)); err != nil {
+		return fmt.Errorf("unable to list the ManagedFleetNotifications: %w", err)
+	}
+	limitedSupport := map[string]bool{}
+	for _, mfn := range mfnList.Items {
+		limitedSupport[mfn.Spec.FleetNotification.Name] = mfn.Spec.FleetNotification.LimitedSupport
+	}
+
+	mfnrList := &oav1alpha1.ManagedFleetNotificationRecordList{}
+	if err := h.c.List(ctx, mfnrList, client.InNamespace(OCMAgentNamespaceName)); err != nil {
+		return fmt.Errorf("unable to list the ManagedFleetNotificationRecords: %w", err)
+	}
+
+	var migrated map[string]bool
+	if h.shardRecords {
+		migrated = migratedRecordItems(mfnrList.Items)
+	}
+
+	// OCM is only asked about the clusters which weren't notified recently, unless it is unavailable
+	checkClusters := h.health.Available() == nil
+	gone := map[string]bool{}
+	items := map[string]int{}
+	size := map[string]int{}
+	for i := range mfnrList.Items {
+		mfnr := &mfnrList.Items[i]
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		var pruned []prunedItem
+		legacy := h.shardRecords && !isHostedClusterRecord(mfnr)
+		legacyReasons := limitedSupportReasons(mfnr)
+		for _, recordByName := range mfnr.Status.NotificationRecordByName {
+			for _, item := range recordByName.NotificationRecordItems {
+				idle := time.Duration(math.MaxInt64)
+				var lastTransition *time.Time
+				if item.LastTransitionTime != nil {
+					idle = time.Since(item.LastTransitionTime.Time)
+					lastTransition = &item.LastTransitionTime.Time
+				}
+				// A firing limited support is kept until it resolves, unless its notification was retired
+				outstanding := item.FiringNotificationSentCount > item.ResolvedNotificationSentCount
+				keepLimitedSupport := limitedSupport[recordByName.NotificationName] && outstanding
+				// Pruning the item before the resend wait elapsed would let the notification be sent again
+				resendWait := time.Duration(recordByName.ResendWait) * time.Hour
+
+				p := prunedItem{notification: recordByName.NotificationName, hcID: item.HostedClusterID, lastTransition: lastTransition}
+				switch {
+				case legacy && isMigrated(migrated, legacyReasons, recordByName.NotificationName, item.HostedClusterID):
+					p.reason = PruneReasonMigrated
+				case retention > 0 && idle > retention && idle > resendWait && !keepLimitedSupport:
+					p.reason = PruneReasonIdle
+				case checkClusters && idle > recentlyNotified && h.clusterGone(ctx, item.HostedClusterID, gone):
+					p.reason = PruneReasonClusterGone
+				default:
+					continue
+				}
+				pruned = append(pruned, p)
+			}
+		}
+
+		if len(pruned) > 0 {
+			updated, err := h.pruneNotificationRecord(ctx, mfnr.Name, pruned)
+			if err != nil {
+				log.WithError(err).WithField(LogFieldNotificationRecordName, mfnr.Name).Warning("unable to prune the notification record")
+			} else {
+				mfnr = updated
+			}
+		}
+
+		if mfnr == nil {
+			// The record of the hosted cluster was deleted
+			continue
+		}
+		mcID := managementCluster(mfnr)
+		for _, recordByName := range mfnr.Status.NotificationRecordByName {
+			items[mcID] += len(recordByName.NotificationRecordItems)
+		}
+		if data, err := json.Marshal(mfnr); err == nil {
+			size[mcID] += len(data)
+		}
+	}
+	metrics.SetNotificationRecordSize(items, size)
+	return nil
+}
+
+// clusterGone indicates whether OCM reports the hosted cluster as gone, the answers are kept in gone. The cluster is
+// assumed to exist when OCM can't tell.
+func (h *WebhookRHOBSReceiverHandler) clusterGone(ctx context.Context, hcID string, gone map[string]bool) bool {
+	if g, ok := gone[hcID]; ok {
+		return g
+	}
+	_, err := h.ocm.GetClusterByExternalID(ctx, hcID)
+	if err != nil && !errors.Is(err, ocm.ErrClusterNotFound) {
+		log.WithError(err).WithField("cluster", hcID).Debug("unable to tell whether the hosted cluster still exists")
+		return false
+	}
+	gone[hcID] = err != nil
+	return gone[hcID]
+}
+
+// managementCluster returns the management cluster of the ManagedFleetNotificationRecord
+func managementCluster(mfnr *oav1alpha1.ManagedFleetNotificationRecord) string {
+	if mcID, ok := mfnr.Labels[consts.ManagementClusterLabel]; ok {
+		return mcID
+	}
+	return mfnr.Name
+}
+
+// pruneNotificationRecord removes the items from the ManagedFleetNotificationRecord, along with the limited support
+// reasons recorded for them, and returns the updated record. The record of a hosted cluster left empty is deleted,
+// nil is then returned.
+func (h *WebhookRHOBSReceiverHandler) pruneNotificationRecord(ctx context.Context, name string, pruned []prunedItem) (*oav1alpha1.ManagedFleetNotificationRecord, error) {
+	var mfnr *oav1alpha1.ManagedFleetNotificationRecord
+	total := map[string]int{}
+	err := retryOnConflictOrAlreadyExists(ctx, retryConfig, func() error {
+		// The record is read again from the API server, the items notified since are kept
+		mfnr = &oav1alpha1.ManagedFleetNotificationRecord{}
+		if err := h.c.Get(ctx, client.ObjectKey{Namespace: OCMAgentNamespaceName, Name: name}, mfnr); err != nil {
+			return err
+		}
+
+		counts := map[string]int{}
+		var byName []oav1alpha1.NotificationRecordByName
+		for _, recordByName := range mfnr.Status.NotificationRecordByName {
+			var kept []oav1alpha1.NotificationRecordItem
+			for _, item := range recordByName.NotificationRecordItems {
+				if reason := pruneReason(pruned, recordByName.NotificationName, item); reason != "" {
+					counts[reason]++
+					continue
+				}
+				kept = append(kept, item)
+			}
+			if len(kept) > 0 {
+				recordByName.NotificationRecordItems = kept
+				byName = append(byName, recordByName)
+			}
+		}
+		if len(counts) > 0 {
+			mfnr.Status.NotificationRecordByName = byName
+			if err := h.c.Status().Update(ctx, mfnr); err != nil {
+				return err
+			}
+			for reason, count := range counts {
+				total[reason] += count
+			}
+		}
+
+		// The limited support reasons of the pruned items are no longer tracked
+		reasons := limitedSupportReasons(mfnr)
+		removed := false
+		for _, p := range pruned {
+			key := limitedSupportReasonsKey(p.notification, p.hcID)
+			if _, ok := reasons[key]; ok {
+				if _, err := mfnr.GetNotificationRecordItem(mfnr.Status.ManagementCluster, p.notification, p.hcID); err != nil {
+					delete(reasons, key)
+					removed = true
+				}
+			}
+		}
+		if removed {
+			if err := setLimitedSupportReasons(mfnr, reasons); err != nil {
+				return err
+			}
+			if err := h.c.Update(ctx, mfnr); err != nil {
+				return err
+			}
+		}
+
+		// The record of a hosted cluster is created again when it is notified, the one left by a deleted cluster
+		// would otherwise be kept forever
+		if isHostedClusterRecord(mfnr) && len(mfnr.Status.NotificationRecordByName) == 0 && len(reasons) == 0 {
+			resourceVersion := mfnr.ResourceVersion
+			if err := h.c.Delete(ctx, mfnr, client.Preconditions{ResourceVersion: &resourceVersion}); client.IgnoreNotFound(err) != nil {
+				return err
+			}
+			mfnr = nil
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for reason, count := range total {
+		metrics.CountNotificationRecordItemsPruned(reason, count)
+		log.WithFields(log.Fields{LogFieldNotificationRecordName: name, "reason": reason}).Infof("pruned %d items of the notification record", count)
+	}
+	return mfnr, nil
+}
+
+// pruneReason returns the reason the item of the notification is pruned for, or an empty string if it is kept
+func pruneReason(pruned []prunedItem, notification string, item oav1alpha1.NotificationRecordItem) string {
+	for _, p := range pruned {
+		if p.notification != notification || p.hcID != item.HostedClusterID {
+			continue
+		}
+		// The item was notified since it was found to be prunable
+		if item.LastTransitionTime != nil && (p.lastTransition == nil || !item.LastTransitionTime.Time.Equal(*p.lastTransition)) {
+			return ""
+		}
+		return p.reason
+	}
+	return ""
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	oav1alpha1 "github.com/openshift/ocm-agent-operator/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/openshift/ocm-agent/pkg/consts"
+	testconst "github.com/openshift/ocm-agent/pkg/consts/test"
+	"github.com/openshift/ocm-agent/pkg/ocm"
+	webhookreceivermock "github.com/openshift/ocm-agent/pkg/ocm/mocks"
+	clientmocks "github.com/openshift/ocm-agent/pkg/util/test/generated/mocks/client"
+)
+
+var _ = Describe("Notification record pruning", func() {
+	const (
+		retention = 30 * 24 * time.Hour
+		recentHC  = "recent-hosted-cluster-id"
+	)
+	var (
+		mockCtrl         *gomock.Controller
+		mockClient       *clientmocks.MockClient
+		mockStatusWriter *clientmocks.MockStatusWriter
+		mockOCMClient    *webhookreceivermock.MockOCMClient
+		testHandler      *WebhookRHOBSReceiverHandler
+		mfn              oav1alpha1.ManagedFleetNotification
+		mfnr             oav1alpha1.ManagedFleetNotificationRecord
+		idleSince        = metav1.NewTime(time.Now().Add(-40 * 24 * time.Hour))
+		notifiedAt       = metav1.NewTime(time.Now().Add(-time.Minute))
+	)
+
+	// expectLists expects the notifications and their records to be listed
+	expectLists := func() {
+		gomock.InOrder(
+			mockClient.EXPECT().List(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+				func(ctx context.Context, list *oav1alpha1.ManagedFleetNotificationList, opts ...client.ListOption) error {
+					list.Items = []oav1alpha1.ManagedFleetNotification{mfn}
+					return nil
+				}),
+			mockClient.EXPECT().List(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+				func(ctx context.Context, list *oav1alpha1.ManagedFleetNotificationRecordList, opts ...client.ListOption) error {
+					list.Items = []oav1alpha1.ManagedFleetNotificationRecord{*mfnr.DeepCopy()}
+					return nil
+				}),
+		)
+	}
+
+	// expectRemainingItems expects the record to be updated with the items of the given hosted clusters
+	expectRemainingItems := func(hcIDs ...string) *gomock.Call {
+		return mockStatusWriter.EXPECT().Update(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, record *oav1alpha1.ManagedFleetNotificationRecord, opts ...client.SubResourceUpdateOption) error {
+				var remaining []string
+				for _, recordByName := range record.Status.NotificationRecordByName {
+					for _, item := range recordByName.NotificationRecordItems {
+						remaining = append(remaining, item.HostedClusterID)
+					}
+				}
+				Expect(remaining).To(ConsistOf(hcIDs))
+				return nil
+			})
+	}
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		mockClient = clientmocks.NewMockClient(mockCtrl)
+		mockStatusWriter = clientmocks.NewMockStatusWriter(mockCtrl)
+		mockOCMClient = webhookreceivermock.NewMockOCMClient(mockCtrl)
+		testHandler = &WebhookRHOBSReceiverHandler{c: mockClient, ocm: mockOCMClient}
+		mfn = testconst.NewManagedFleetNotification(false)
+		mfnr = testconst.NewManagedFleetNotificationRecordWithStatus()
+		mfnr.Status.NotificationRecordByName[0].NotificationRecordItems = []oav1alpha1.NotificationRecordItem{
+			{HostedClusterID: testconst.TestHostedClusterID, FiringNotificationSentCount: 1, LastTransitionTime: &idleSince},
+			{HostedClusterID: recentHC, FiringNotificationSentCount: 1, LastTransitionTime: &notifiedAt},
+		}
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	It("prunes the items idle past the retention", func() {
+		expectLists()
+		gomock.InOrder(
+			mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).SetArg(2, *mfnr.DeepCopy()),
+			mockClient.EXPECT().Status().Return(mockStatusWriter),
+			expectRemainingItems(recentHC),
+		)
+		Expect(testHandler.PruneNotificationRecords(context.TODO(), retention)).To(Succeed())
+	})
+
+	It("keeps a firing limited support, unless the cluster is gone", func() {
+		mfn = testconst.NewManagedFleetNotification(true)
+		mfnr.Annotations = map[string]string{consts.LimitedSupportReasonsAnnotation: `{"` + testconst.TestNotificationName + `/` + testconst.TestHostedClusterID + `":["1234"]}`}
+		expectLists()
+		gomock.InOrder(
+			mockOCMClient.EXPECT().GetClusterByExternalID(gomock.Any(), testconst.TestHostedClusterID).Return(nil,
+				fmt.Errorf("cluster with external id %s %w", testconst.TestHostedClusterID, ocm.ErrClusterNotFound)),
+			mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).SetArg(2, *mfnr.DeepCopy()),
+			mockClient.EXPECT().Status().Return(mockStatusWriter),
+			expectRemainingItems(recentHC),
+			// The limited support reasons of the pruned item are no longer tracked
+			mockClient.EXPECT().Update(gomock.Any(), gomock.Any()).DoAndReturn(
+				func(ctx context.Context, record *oav1alpha1.ManagedFleetNotificationRecord, opts ...client.UpdateOption) error {
+					Expect(record.Annotations).NotTo(HaveKey(consts.LimitedSupportReasonsAnnotation))
+					return nil
+				}),
+		)
+		Expect(testHandler.PruneNotificationRecords(context.TODO(), retention)).To(Succeed())
+	})
+
+	It("keeps the items of the clusters OCM can't tell about", func() {
+		expectLists()
+		mockOCMClient.EXPECT().GetClusterByExternalID(gomock.Any(), testconst.TestHostedClusterID).Return(nil, errors.New("OCM unavailable"))
+		Expect(testHandler.PruneNotificationRecords(context.TODO(), 0)).To(Succeed())
+	})
+
+	It("keeps the item notified since it was found idle", func() {
+		expectLists()
+		fresh := mfnr.DeepCopy()
+		fresh.Status.NotificationRecordByName[0].NotificationRecordItems[0].LastTransitionTime = &notifiedAt
+		mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).SetArg(2, *fresh)
+		Expect(testHandler.PruneNotificationRecords(context.TODO(), retention)).To(Succeed())
+	})
+})
@@ -7,16 +7,32 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+const (
+	ReadyzStatusOK       = "ok"
+	ReadyzStatusNotReady = "not ready"
+)
+
+// ReadyzCheck reports an error while a dependency of the service is not ready
+type ReadyzCheck struct {
+	Name  string
+	Check func() error
+	// Informational checks are reported in the response without making the service not ready, e.g. for a
+	// dependency whose outage is handled by the service and wouldn't be solved by restarting or bypassing it
+	Informational bool
+}
+
 type ReadyzHandler struct {
+	checks []ReadyzCheck
 }
 
 // ready probe endpoint response
 type ReadyzResponse struct {
 	Status string
+	Checks map[string]string `json:",omitempty"`
 }
 
-func NewReadyzHandler() *ReadyzHandler {
-	return &ReadyzHandler{}
+func NewReadyzHandler(checks ...ReadyzCheck) *ReadyzHandler {
+	return &ReadyzHandler{checks: checks}
 }
 
 func (h *ReadyzHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -27,12 +43,28 @@ func (h *ReadyzHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	var err error
-	// write response
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
 	response := ReadyzResponse{
-		Status: "ok",
+		Status: ReadyzStatusOK,
+	}
+	code := http.StatusOK
+	for _, c := range h.checks {
+		if response.Checks == nil {
+			response.Checks = map[string]string{}
+		}
+		if err := c.Check(); err != nil {
+			log.WithError(err).WithField("check", c.Name).Debug("readiness check failed")
+			response.Checks[c.Name] = err.Error()
+			if !c.Informational {
+				response.Status = ReadyzStatusNotReady
+				code = http.StatusServiceUnavailable
+			}
+			continue
+		}
+		response.Checks[c.Name] = ReadyzStatusOK
 	}
+	// write response
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
 	err = json.NewEncoder(w).Encode(response)
 	if err != nil {
 		log.Errorf("Failed to write to response: %s\n", err)
@@ -3,6 +3,7 @@ package handlers
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 
@@ -90,4 +91,70 @@ var _ = Describe("Readyz tests", func() {
 			Expect(response).Should(Equal(expected))
 		})
 	})
+	Context("Readyz handler get with checks", func() {
+		var (
+			failing bool
+			resp    *http.Response
+			err     error
+		)
+		BeforeEach(func() {
+			failing = false
+			readyzHandler = NewReadyzHandler(
+				ReadyzCheck{Name: "always", Check: func() error { return nil }},
+				ReadyzCheck{Name: "ocm", Check: func() error {
+					if failing {
+						return errors.New("OCM circuit breaker is open")
+					}
+					return nil
+				}},
+			)
+		})
+		JustBeforeEach(func() {
+			server.AppendHandlers(readyzHandler.ServeHTTP)
+			resp, err = http.Get(server.URL())
+		})
+		It("Reports the passing checks", func() {
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(resp.StatusCode).Should(Equal(http.StatusOK))
+			var response ReadyzResponse
+			_ = json.NewDecoder(resp.Body).Decode(&response)
+			Expect(response).Should(Equal(ReadyzResponse{
+				Status: ReadyzStatusOK,
+				Checks: map[string]string{"always": ReadyzStatusOK, "ocm": ReadyzStatusOK},
+			}))
+		})
+		When("a check fails", func() {
+			BeforeEach(func() {
+				failing = true
+			})
+			It("Reports the service as not ready", func() {
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(resp.StatusCode).Should(Equal(http.StatusServiceUnavailable))
+				var response ReadyzResponse
+				_ = json.NewDecoder(resp.Body).Decode(&response)
+				Expect(response).Should(Equal(ReadyzResponse{
+					Status: ReadyzStatusNotReady,
+					Checks: map[string]string{"always": ReadyzStatusOK, "ocm": "OCM circuit breaker is open"},
+				}))
+			})
+		})
+		When("an informational check fails", func() {
+			BeforeEach(func() {
+				readyzHandler = NewReadyzHandler(
+					ReadyzCheck{Name: "always", Check: func() error { return nil }},
+					ReadyzCheck{Name: "ocm", Check: func() error { return errors.New("OCM circuit breaker is open") }, Informational: true},
+				)
+			})
+			It("Reports the failure without making the service not ready", func() {
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(resp.StatusCode).Should(Equal(http.StatusOK))
+				var response ReadyzResponse
+				_ = json.NewDecoder(resp.Body).Decode(&response)
+				Expect(response).Should(Equal(ReadyzResponse{
+					Status: ReadyzStatusOK,
+					Checks: map[string]string{"always": ReadyzStatusOK, "ocm": "OCM circuit breaker is open"},
+				}))
+			})
+		})
+	})
 })
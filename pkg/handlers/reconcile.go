@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+	log "github.com/sirupsen/logrus"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	oav1alpha1 "github.com/openshift/ocm-agent-operator/api/v1alpha1"
+
+	"github.com/openshift/ocm-agent/pkg/events"
+	"github.com/openshift/ocm-agent/pkg/metrics"
+	"github.com/openshift/ocm-agent/pkg/ocm"
+)
+
+// DefaultLimitedSupportReconcileInterval is the time between two reconciliations of the limited support reasons
+const DefaultLimitedSupportReconcileInterval = 10 * time.Minute
+
+const (
+	// LimitedSupportDriftStale is a limited support reason posted by the agent which is still active although its
+	// notification resolved
+	LimitedSupportDriftStale = "stale"
+	// LimitedSupportDriftMissing is a firing limited support notification without an active reason posted by the agent
+	LimitedSupportDriftMissing = "missing"
+)
+
+// RunLimitedSupportReconciler reconciles the limited support reasons with the notification records every interval
+// until ctx is cancelled, it is meant to run on the leader
+func (h *WebhookRHOBSReceiverHandler) RunLimitedSupportReconciler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := h.ReconcileLimitedSupport(ctx); err != nil {
+			log.WithError(err).Error("Unable to reconcile the limited support reasons, retrying later")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ReconcileLimitedSupport compares the limited support reasons of the hosted clusters in OCM with their
+// ManagedFleetNotificationRecord, which only changes when alerts are received. The stale reasons are removed and the
+// missing ones reported, the drifts found are reported by the ocm_agent_limited_support_drift metric.
+func (h *WebhookRHOBSReceiverHandler) ReconcileLimitedSupport(ctx context.Context) error {
+	if err := h.health.Available(); err != nil {
+		return fmt.Errorf("OCM is unavailable: %w", err)
+	}
+
+	mfnList := &oav1alpha1.ManagedFleetNotificationList{}
+	if err := h.reader().List(ctx, mfnList, client.InNamespace(OCMAgentNamespaceName)); err != nil {
+		return fmt.Errorf("unable to list the ManagedFleetNotifications: %w", err)
+	}
+	// The records refer to the notifications by the name of their fleet notification
+	notifications := map[string]*oav1alpha1.ManagedFleetNotification{}
+	for i := range mfnList.Items {
+		mfn := &mfnList.Items[i]
+		if mfn.Spec.FleetNotification.LimitedSupport {
+			notifications[mfn.Spec.FleetNotification.Name] = mfn
+		}
+	}
+
+	mfnrList := &oav1alpha1.ManagedFleetNotificationRecordList{}
+	if err := h.c.List(ctx, mfnrList, client.InNamespace(OCMAgentNamespaceName)); err != nil {
+		return fmt.Errorf("unable to list the ManagedFleetNotificationRecords: %w", err)
+	}
+
+	// In the hosted cluster layout, the items left in the records of the management clusters once migrated are stale
+	var migrated map[string]bool
+	if h.shardRecords {
+		migrated = migratedRecordItems(mfnrList.Items)
+	}
+
+	drift := map[string]map[string]int{}
+	for i := range mfnrList.Items {
+		mfnr := &mfnrList.Items[i]
+		legacy := h.shardRecords && !isHostedClusterRecord(mfnr)
+		legacyReasons := limitedSupportReasons(mfnr)
+		for _, recordByName := range mfnr.Status.NotificationRecordByName {
+			mfn, ok := notifications[recordByName.NotificationName]
+			if !ok {
+				continue
+			}
+			for _, item := range recordByName.NotificationRecordItems {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				// The limited support of a migrated item is reconciled with the record of its hosted cluster
+				if legacy && isMigrated(migrated, legacyReasons, recordByName.NotificationName, item.HostedClusterID) {
+					continue
+				}
+				kind, err := h.reconcileLimitedSupportItem(ctx, mfnr, mfn, item)
+				if err != nil {
+					log.WithError(err).WithFields(log.Fields{LogFieldNotificationName: recordByName.NotificationName, "cluster": item.HostedClusterID}).Warning("unable to reconcile the limited support of the cluster")
+				}
+				if kind == "" {
+					continue
+				}
+				if drift[recordByName.NotificationName] == nil {
+					drift[recordByName.NotificationName] = map[string]int{}
+				}
+				drift[recordByName.NotificationName][kind]++
+			}
+		}
+	}
+	metrics.SetLimitedSupportDrift(drift)
+	log.WithField("drift", drift).Debug("Reconciled the limited support reasons")
+	return nil
+}
+
+// reconcileLimitedSupportItem compares the limited support reasons of the hosted cluster of the record item with the
+// item, and removes the stale reasons. It returns the drift found, if any.
+func (h *WebhookRHOBSReceiverHandler) reconcileLimitedSupportItem(ctx context.Context, mfnr *oav1alpha1.ManagedFleetNotificationRecord, mfn *oav1alpha1.ManagedFleetNotification, item oav1alpha1.NotificationRecordItem) (string, error) {
+	fn := mfn.Spec.FleetNotification
+	mcID := mfnr.Status.ManagementCluster
+	hcID := item.HostedClusterID
+	logFields := log.Fields{LogFieldNotificationName: fn.Name, "cluster": hcID}
+
+	// The writes of the cluster waiting in the outbox are about to change its limited support
+	if h.outbox.Pending(hcID) {
+		return "", nil
+	}
+
+	outstanding := item.FiringNotificationSentCount > item.ResolvedNotificationSentCount
+	ids, known := recordedLimitedSupportReasons(mfnr, mcID, hcID, fn.Name)
+	if !outstanding && len(ids) == 0 {
+		// Nothing the agent posted can be left behind
+		return "", nil
+	}
+
+	reasons, err := h.ocm.GetLimitedSupportReasons(ctx, hcID)
+	if err != nil {
+		return "", err
+	}
+	active := map[string]bool{}
+	var owned, stale []*cmv1.LimitedSupportReason
+	for _, reason := range reasons {
+		active[reason.ID()] = true
+		if !ownsLimitedSupportReason(reason, fn.Summary, fn.NotificationMessage, ids, known) {
+			continue
+		}
+		owned = append(owned, reason)
+		// A reason posted since the notification resolved is the one of an alert firing again, whose record
+		// isn't updated yet
+		created := reason.CreationTimestamp()
+		if !outstanding && item.LastTransitionTime != nil && !created.IsZero() && created.Before(item.LastTransitionTime.Time) {
+			stale = append(stale, reason)
+		}
+	}
+
+	if outstanding {
+		if len(owned) == 0 {
+			log.WithFields(logFields).Warning("the limited support reason of the firing notification is no longer active")
+			return LimitedSupportDriftMissing, nil
+		}
+		return "", nil
+	}
+
+	ocmClient, dryRun := h.dryRun.clientFor(mfn, h.ocm)
+	removed := map[string]bool{}
+	var removeErr error
+	for _, reason := range stale {
+		log.WithFields(logFields).Warningf("removing the limited support reason '%s' left behind by the resolved notification", reason.ID())
+		if !dryRun {
+			if removeErr = throttle(ctx, h.limiter, ocm.OperationRemoveLimitedSupport, hcID, fn.Name); removeErr != nil {
+				break
+			}
+		}
+		var operationID string
+		if operationID, removeErr = ocmClient.RemoveLimitedSupport(ctx, hcID, reason.ID()); removeErr != nil {
+			metrics.IncrementFailedLimitedSupportRemoved(fn.Name)
+			h.events.SendFailed(mfn, events.Notification{Template: fn.Name, Cluster: hcID}, ocm.OperationRemoveLimitedSupport, removeErr)
+			removeErr = fmt.Errorf("limited support reason with ID '%s' couldn't be removed: %w", reason.ID(), removeErr)
+			break
+		}
+		removed[reason.ID()] = true
+		if !dryRun {
+			metrics.IncrementLimitedSupportRemovedCount(fn.Name)
+			metrics.CountLimitedSupportDriftRepaired(fn.Name)
+			h.events.LimitedSupportRemoved(mfn, events.Notification{Template: fn.Name, Cluster: hcID}, operationID, reason.ID())
+		}
+	}
+
+	// The recorded reasons which are gone are no longer tracked, the ones recorded since are kept
+	if !dryRun && (len(removed) > 0 || len(ids) > len(owned)) {
+		err := h.updateLimitedSupportReasons(ctx, mcID, hcID, mfn, func(current []string) []string {
+			var kept []string
+			for _, id := range current {
+				if removed[id] || (slices.Contains(ids, id) && !active[id]) {
+					continue
+				}
+				kept = append(kept, id)
+			}
+			return kept
+		})
+		if err != nil {
+			log.WithError(err).WithFields(logFields).Warning("unable to clear the removed limited support reasons from the notification record")
+		}
+	}
+
+	if len(stale) == 0 {
+		return "", removeErr
+	}
+	return LimitedSupportDriftStale, removeErr
+}
@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+	oav1alpha1 "github.com/openshift/ocm-agent-operator/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/openshift/ocm-agent/pkg/consts"
+	testconst "github.com/openshift/ocm-agent/pkg/consts/test"
+	"github.com/openshift/ocm-agent/pkg/ocm"
+	webhookreceivermock "github.com/openshift/ocm-agent/pkg/ocm/mocks"
+	"github.com/openshift/ocm-agent/pkg/outbox"
+	clientmocks "github.com/openshift/ocm-agent/pkg/util/test/generated/mocks/client"
+)
+
+var _ = Describe("Limited support reconciliation", func() {
+	var (
+		mockCtrl      *gomock.Controller
+		mockClient    *clientmocks.MockClient
+		mockOCMClient *webhookreceivermock.MockOCMClient
+		testHandler   *WebhookRHOBSReceiverHandler
+		mfn           oav1alpha1.ManagedFleetNotification
+		mfnr          oav1alpha1.ManagedFleetNotificationRecord
+		resolvedAt    = time.Now().Add(-time.Hour)
+		reasonsKey    = testconst.TestNotificationName + "/" + testconst.TestHostedClusterID
+	)
+
+	// newReason returns an active limited support reason of the notification created at the given time
+	newReason := func(id string, created time.Time) *cmv1.LimitedSupportReason {
+		fn := mfn.Spec.FleetNotification
+		reason, err := cmv1.NewLimitedSupportReason().ID(id).CreationTimestamp(created).
+			Summary(fn.Summary).Details(fn.NotificationMessage).DetectionType(cmv1.DetectionTypeManual).Build()
+		Expect(err).NotTo(HaveOccurred())
+		return reason
+	}
+
+	// setRecord sets the notification record item of the hosted cluster and the recorded reasons
+	setRecord := func(firing, resolved int, reasons string) {
+		mfnr.Status.NotificationRecordByName[0].NotificationRecordItems[0].FiringNotificationSentCount = firing
+		mfnr.Status.NotificationRecordByName[0].NotificationRecordItems[0].ResolvedNotificationSentCount = resolved
+		mfnr.Status.NotificationRecordByName[0].NotificationRecordItems[0].LastTransitionTime = &metav1.Time{Time: resolvedAt}
+		if reasons != "" {
+			mfnr.Annotations = map[string]string{consts.LimitedSupportReasonsAnnotation: reasons}
+		}
+	}
+
+	// expectLists expects the notifications and their records to be listed
+	expectLists := func() {
+		gomock.InOrder(
+			mockClient.EXPECT().List(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+				func(ctx context.Context, list *oav1alpha1.ManagedFleetNotificationList, opts ...client.ListOption) error {
+					list.Items = []oav1alpha1.ManagedFleetNotification{mfn}
+					return nil
+				}),
+			mockClient.EXPECT().List(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+				func(ctx context.Context, list *oav1alpha1.ManagedFleetNotificationRecordList, opts ...client.ListOption) error {
+					list.Items = []oav1alpha1.ManagedFleetNotificationRecord{mfnr}
+					return nil
+				}),
+		)
+	}
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		mockClient = clientmocks.NewMockClient(mockCtrl)
+		mockOCMClient = webhookreceivermock.NewMockOCMClient(mockCtrl)
+		testHandler = &WebhookRHOBSReceiverHandler{c: mockClient, ocm: mockOCMClient}
+		mfn = testconst.NewManagedFleetNotification(true)
+		mfnr = testconst.NewManagedFleetNotificationRecordWithStatus()
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	It("removes the reason left behind by a resolved notification", func() {
+		setRecord(1, 1, `{"`+reasonsKey+`":["1234"]}`)
+		mfnr.ResourceVersion = "1"
+		expectLists()
+		gomock.InOrder(
+			mockOCMClient.EXPECT().GetLimitedSupportReasons(gomock.Any(), testconst.TestHostedClusterID).Return(
+				[]*cmv1.LimitedSupportReason{newReason("1234", resolvedAt.Add(-time.Hour)), newReason("5678", resolvedAt.Add(-time.Hour))}, nil),
+			mockOCMClient.EXPECT().RemoveLimitedSupport(gomock.Any(), testconst.TestHostedClusterID, "1234").Return("", nil),
+			mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).SetArg(2, mfnr),
+			mockClient.EXPECT().Patch(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+				func(ctx context.Context, record *oav1alpha1.ManagedFleetNotificationRecord, patch client.Patch, opts ...client.PatchOption) error {
+					Expect(record.Annotations).NotTo(HaveKey(consts.LimitedSupportReasonsAnnotation))
+					// Only the annotation is removed, provided the record didn't change since it was read
+					data, err := patch.Data(record)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(string(data)).To(Equal(`{"metadata":{"annotations":null,"resourceVersion":"1"}}`))
+					return nil
+				}),
+		)
+		Expect(testHandler.ReconcileLimitedSupport(context.TODO())).To(Succeed())
+	})
+
+	It("leaves alone the reason posted since the notification resolved", func() {
+		setRecord(1, 1, `{"`+reasonsKey+`":["1234","9999"]}`)
+		mockOCMClient.EXPECT().GetLimitedSupportReasons(gomock.Any(), testconst.TestHostedClusterID).Return(
+			[]*cmv1.LimitedSupportReason{newReason("9999", time.Now())}, nil)
+		// The reason which is gone is no longer tracked
+		gomock.InOrder(
+			mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).SetArg(2, mfnr),
+			mockClient.EXPECT().Patch(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+				func(ctx context.Context, record *oav1alpha1.ManagedFleetNotificationRecord, patch client.Patch, opts ...client.PatchOption) error {
+					Expect(record.Annotations).To(HaveKeyWithValue(consts.LimitedSupportReasonsAnnotation, `{"`+reasonsKey+`":["9999"]}`))
+					return nil
+				}),
+		)
+		kind, err := testHandler.reconcileLimitedSupportItem(context.TODO(), &mfnr, &mfn, mfnr.Status.NotificationRecordByName[0].NotificationRecordItems[0])
+		Expect(err).NotTo(HaveOccurred())
+		Expect(kind).To(BeEmpty())
+	})
+
+	It("reports the reason missing for a firing notification", func() {
+		setRecord(2, 1, `{"`+reasonsKey+`":["1234"]}`)
+		mockOCMClient.EXPECT().GetLimitedSupportReasons(gomock.Any(), testconst.TestHostedClusterID).Return(
+			[]*cmv1.LimitedSupportReason{newReason("5678", resolvedAt)}, nil)
+		kind, err := testHandler.reconcileLimitedSupportItem(context.TODO(), &mfnr, &mfn, mfnr.Status.NotificationRecordByName[0].NotificationRecordItems[0])
+		Expect(err).NotTo(HaveOccurred())
+		Expect(kind).To(Equal(LimitedSupportDriftMissing))
+	})
+
+	It("matches the reason of a firing notification on its content without recorded reasons", func() {
+		setRecord(1, 0, "")
+		mockOCMClient.EXPECT().GetLimitedSupportReasons(gomock.Any(), testconst.TestHostedClusterID).Return(
+			[]*cmv1.LimitedSupportReason{newReason("1234", resolvedAt)}, nil)
+		kind, err := testHandler.reconcileLimitedSupportItem(context.TODO(), &mfnr, &mfn, mfnr.Status.NotificationRecordByName[0].NotificationRecordItems[0])
+		Expect(err).NotTo(HaveOccurred())
+		Expect(kind).To(BeEmpty())
+	})
+
+	It("reports the stale reason which couldn't be removed", func() {
+		setRecord(1, 1, `{"`+reasonsKey+`":["1234"]}`)
+		gomock.InOrder(
+			mockOCMClient.EXPECT().GetLimitedSupportReasons(gomock.Any(), testconst.TestHostedClusterID).Return(
+				[]*cmv1.LimitedSupportReason{newReason("1234", resolvedAt.Add(-time.Hour))}, nil),
+			mockOCMClient.EXPECT().RemoveLimitedSupport(gomock.Any(), testconst.TestHostedClusterID, "1234").Return("", errors.New("OCM unavailable")),
+		)
+		kind, err := testHandler.reconcileLimitedSupportItem(context.TODO(), &mfnr, &mfn, mfnr.Status.NotificationRecordByName[0].NotificationRecordItems[0])
+		Expect(err).To(MatchError(ContainSubstring("OCM unavailable")))
+		Expect(kind).To(Equal(LimitedSupportDriftStale))
+	})
+
+	It("doesn't query OCM for a resolved notification without recorded reasons", func() {
+		setRecord(1, 1, "")
+		expectLists()
+		Expect(testHandler.ReconcileLimitedSupport(context.TODO())).To(Succeed())
+	})
+
+	It("leaves alone the clusters with writes waiting in the outbox", func() {
+		setRecord(1, 1, `{"`+reasonsKey+`":["1234"]}`)
+		testOutbox := outbox.New(&memoryOutboxStore{})
+		Expect(testOutbox.Load(context.TODO())).To(Succeed())
+		Expect(testOutbox.Add(context.TODO(), outbox.Entry{Cluster: testconst.TestHostedClusterID, Operation: ocm.OperationRemoveLimitedSupport})).To(Succeed())
+		testHandler.WithOutbox(testOutbox)
+		kind, err := testHandler.reconcileLimitedSupportItem(context.TODO(), &mfnr, &mfn, mfnr.Status.NotificationRecordByName[0].NotificationRecordItems[0])
+		Expect(err).NotTo(HaveOccurred())
+		Expect(kind).To(BeEmpty())
+	})
+})
@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"context"
+	"slices"
+
+	oav1alpha1 "github.com/openshift/ocm-agent-operator/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/openshift/ocm-agent/pkg/consts"
+)
+
+const (
+	// RecordLayoutManagementCluster keeps the items of all the hosted clusters of a management cluster in the
+	// ManagedFleetNotificationRecord named after the management cluster
+	RecordLayoutManagementCluster = "management-cluster"
+	// RecordLayoutHostedCluster keeps the items of each hosted cluster in its own ManagedFleetNotificationRecord, so
+	// that the alerts of different hosted clusters don't update the same record
+	RecordLayoutHostedCluster = "hosted-cluster"
+)
+
+// RecordLayouts lists the layouts of the ManagedFleetNotificationRecords
+var RecordLayouts = []string{RecordLayoutManagementCluster, RecordLayoutHostedCluster}
+
+// WithRecordLayout makes the handler keep the ManagedFleetNotificationRecords in the layout. In the hosted cluster
+// layout, the items of the records of the management clusters are migrated to the records of the hosted clusters as
+// they are notified.
+func (h *WebhookRHOBSReceiverHandler) WithRecordLayout(layout string) *WebhookRHOBSReceiverHandler {
+	h.shardRecords = layout == RecordLayoutHostedCluster
+	return h
+}
+
+// recordName returns the name of the ManagedFleetNotificationRecord holding the items of the hosted cluster
+func (h *WebhookRHOBSReceiverHandler) recordName(mcID, hcID string) string {
+	if h.shardRecords {
+		return mcID + "-" + hcID
+	}
+	return mcID
+}
+
+// newNotificationRecord returns the ManagedFleetNotificationRecord holding the items of the hosted cluster, which
+// isn't created yet
+func (h *WebhookRHOBSReceiverHandler) newNotificationRecord(mcID, hcID string) *oav1alpha1.ManagedFleetNotificationRecord {
+	mfnr := &oav1alpha1.ManagedFleetNotificationRecord{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      h.recordName(mcID, hcID),
+			Namespace: OCMAgentNamespaceName,
+		},
+	}
+	if h.shardRecords {
+		mfnr.Labels = map[string]string{
+			consts.ManagementClusterLabel: mcID,
+			consts.HostedClusterLabel:     hcID,
+		}
+		mfnr.Status.ManagementCluster = mcID
+	}
+	return mfnr
+}
+
+// isHostedClusterRecord indicates whether the ManagedFleetNotificationRecord holds the items of a single hosted cluster
+func isHostedClusterRecord(mfnr *oav1alpha1.ManagedFleetNotificationRecord) bool {
+	_, ok := mfnr.Labels[consts.HostedClusterLabel]
+	return ok
+}
+
+// notificationRecord reads the ManagedFleetNotificationRecord holding the items of the hosted cluster, see
+// mergeLegacyRecord. In the hosted cluster layout, the record isn't created yet when it doesn't exist.
+func (h *WebhookRHOBSReceiverHandler) notificationRecord(ctx context.Context, mcID, hcID string) (*oav1alpha1.ManagedFleetNotificationRecord, error) {
+	mfnr := &oav1alpha1.ManagedFleetNotificationRecord{}
+	err := h.c.Get(ctx, client.ObjectKey{Namespace: OCMAgentNamespaceName, Name: h.recordName(mcID, hcID)}, mfnr)
+	if err != nil {
+		if !h.shardRecords || !errors.IsNotFound(err) {
+			return nil, err
+		}
+		mfnr = h.newNotificationRecord(mcID, hcID)
+	}
+	if err := h.mergeLegacyRecord(ctx, mfnr, mcID, hcID); err != nil {
+		return nil, err
+	}
+	return mfnr, nil
+}
+
+// mergeLegacyRecord merges into the ManagedFleetNotificationRecord of the hosted cluster the items and limited
+// support reasons of the hosted cluster the record of its management cluster still holds, in the hosted cluster
+// layout. The ones of the record of the hosted cluster take precedence.
+func (h *WebhookRHOBSReceiverHandler) mergeLegacyRecord(ctx context.Context, mfnr *oav1alpha1.ManagedFleetNotificationRecord, mcID, hcID string) error {
+	if !h.shardRecords {
+		return nil
+	}
+	if mfnr.Status.ManagementCluster == "" {
+		mfnr.Status.ManagementCluster = mcID
+	}
+
+	legacy := &oav1alpha1.ManagedFleetNotificationRecord{}
+	if err := h.c.Get(ctx, client.ObjectKey{Namespace: OCMAgentNamespaceName, Name: mcID}, legacy); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+
+	// The items are migrated along with their limited support reasons, which are then no longer taken from the record
+	// of the management cluster
+	reasons := limitedSupportReasons(mfnr)
+	legacyReasons := limitedSupportReasons(legacy)
+	merged := false
+	for _, recordByName := range legacy.Status.NotificationRecordByName {
+		for _, item := range recordByName.NotificationRecordItems {
+			if item.HostedClusterID != hcID {
+				continue
+			}
+			i := slices.IndexFunc(mfnr.Status.NotificationRecordByName, func(r oav1alpha1.NotificationRecordByName) bool {
+				return r.NotificationName == recordByName.NotificationName
+			})
+			if i < 0 {
+				mfnr.Status.NotificationRecordByName = append(mfnr.Status.NotificationRecordByName, oav1alpha1.NotificationRecordByName{
+					NotificationName: recordByName.NotificationName,
+					ResendWait:       recordByName.ResendWait,
+				})
+				i = len(mfnr.Status.NotificationRecordByName) - 1
+			}
+			items := &mfnr.Status.NotificationRecordByName[i].NotificationRecordItems
+			if slices.ContainsFunc(*items, func(ri oav1alpha1.NotificationRecordItem) bool { return ri.HostedClusterID == hcID }) {
+				continue
+			}
+			*items = append(*items, *item.DeepCopy())
+
+			key := limitedSupportReasonsKey(recordByName.NotificationName, hcID)
+			if ids, ok := legacyReasons[key]; ok {
+				if _, ok := reasons[key]; !ok {
+					reasons[key] = ids
+					merged = true
+				}
+			}
+		}
+	}
+	if !merged {
+		return nil
+	}
+	return setLimitedSupportReasons(mfnr, reasons)
+}
+
+// migratedRecordItems returns the items held by the ManagedFleetNotificationRecords of the hosted clusters, by
+// notification and hosted cluster, along with whether their limited support reasons are recorded
+func migratedRecordItems(mfnrs []oav1alpha1.ManagedFleetNotificationRecord) map[string]bool {
+	migrated := map[string]bool{}
+	for i := range mfnrs {
+		mfnr := &mfnrs[i]
+		if !isHostedClusterRecord(mfnr) {
+			continue
+		}
+		reasons := limitedSupportReasons(mfnr)
+		for _, recordByName := range mfnr.Status.NotificationRecordByName {
+			for _, item := range recordByName.NotificationRecordItems {
+				key := limitedSupportReasonsKey(recordByName.NotificationName, item.HostedClusterID)
+				_, recorded := reasons[key]
+				migrated[key] = recorded
+			}
+		}
+	}
+	return migrated
+}
+
+// isMigrated indicates whether the item of the ManagedFleetNotificationRecord of a management cluster, whose limited
+// support reasons are given, was migrated to the record of its hosted cluster, see migratedRecordItems
+func isMigrated(migrated map[string]bool, legacyReasons map[string][]string, notification, hcID string) bool {
+	key := limitedSupportReasonsKey(notification, hcID)
+	recorded, ok := migrated[key]
+	if !ok {
+		return false
+	}
+	// The limited support reasons are only recorded on the record of the hosted cluster once it is updated for them
+	_, legacyRecorded := legacyReasons[key]
+	return recorded || !legacyRecorded
+}
@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	oav1alpha1 "github.com/openshift/ocm-agent-operator/api/v1alpha1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/openshift/ocm-agent/pkg/consts"
+	testconst "github.com/openshift/ocm-agent/pkg/consts/test"
+	webhookreceivermock "github.com/openshift/ocm-agent/pkg/ocm/mocks"
+	clientmocks "github.com/openshift/ocm-agent/pkg/util/test/generated/mocks/client"
+)
+
+var _ = Describe("Hosted cluster record layout", func() {
+	const otherHC = "other-hosted-cluster-id"
+	var (
+		mockCtrl         *gomock.Controller
+		mockClient       *clientmocks.MockClient
+		mockStatusWriter *clientmocks.MockStatusWriter
+		testHandler      *WebhookRHOBSReceiverHandler
+		mfn              oav1alpha1.ManagedFleetNotification
+		legacy           oav1alpha1.ManagedFleetNotificationRecord
+		shardKey         = client.ObjectKey{Namespace: OCMAgentNamespaceName, Name: testconst.TestManagedClusterID + "-" + testconst.TestHostedClusterID}
+		legacyKey        = client.ObjectKey{Namespace: OCMAgentNamespaceName, Name: testconst.TestManagedClusterID}
+		reasonsKey       = testconst.TestNotificationName + "/" + testconst.TestHostedClusterID
+		notifiedAt       = metav1.NewTime(time.Now().Add(-time.Minute))
+	)
+
+	notFound := func(name string) error {
+		return kerrors.NewNotFound(schema.GroupResource{Group: oav1alpha1.GroupVersion.Group, Resource: "ManagedFleetNotificationRecord"}, name)
+	}
+
+	// newShard returns the record of the hosted cluster holding an item of the notification
+	newShard := func(hcID string, lastTransition *metav1.Time) oav1alpha1.ManagedFleetNotificationRecord {
+		shard := *testHandler.newNotificationRecord(testconst.TestManagedClusterID, hcID)
+		shard.Status.NotificationRecordByName = []oav1alpha1.NotificationRecordByName{{
+			NotificationName:        testconst.TestNotificationName,
+			NotificationRecordItems: []oav1alpha1.NotificationRecordItem{{HostedClusterID: hcID, FiringNotificationSentCount: 1, LastTransitionTime: lastTransition}},
+		}}
+		return shard
+	}
+
+	// expectLists expects the notifications and the given records to be listed
+	expectLists := func(mfnrs ...oav1alpha1.ManagedFleetNotificationRecord) {
+		gomock.InOrder(
+			mockClient.EXPECT().List(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+				func(ctx context.Context, list *oav1alpha1.ManagedFleetNotificationList, opts ...client.ListOption) error {
+					list.Items = []oav1alpha1.ManagedFleetNotification{mfn}
+					return nil
+				}),
+			mockClient.EXPECT().List(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+				func(ctx context.Context, list *oav1alpha1.ManagedFleetNotificationRecordList, opts ...client.ListOption) error {
+					list.Items = mfnrs
+					return nil
+				}),
+		)
+	}
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		mockClient = clientmocks.NewMockClient(mockCtrl)
+		mockStatusWriter = clientmocks.NewMockStatusWriter(mockCtrl)
+		testHandler = (&WebhookRHOBSReceiverHandler{c: mockClient}).WithRecordLayout(RecordLayoutHostedCluster)
+		mfn = testconst.NewManagedFleetNotification(true)
+		legacy = testconst.NewManagedFleetNotificationRecordWithStatus()
+		legacy.Annotations = map[string]string{consts.LimitedSupportReasonsAnnotation: `{"` + reasonsKey + `":["1234"]}`}
+		legacy.Status.NotificationRecordByName[0].NotificationRecordItems = []oav1alpha1.NotificationRecordItem{
+			{HostedClusterID: testconst.TestHostedClusterID, FiringNotificationSentCount: 1, LastTransitionTime: &notifiedAt},
+			{HostedClusterID: otherHC, FiringNotificationSentCount: 1, LastTransitionTime: &notifiedAt},
+		}
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	It("reads the items not migrated yet from the record of the management cluster", func() {
+		gomock.InOrder(
+			mockClient.EXPECT().Get(gomock.Any(), shardKey, gomock.Any()).Return(notFound(shardKey.Name)),
+			mockClient.EXPECT().Get(gomock.Any(), legacyKey, gomock.Any()).Return(nil).SetArg(2, legacy),
+		)
+		// The limited support posted before the migration didn't resolve
+		Expect(testHandler.firingCanBeSent(context.TODO(), testconst.NewTestAlert(false, true), &mfn)).To(BeFalse())
+	})
+
+	It("creates the record of the hosted cluster with the items not migrated yet", func() {
+		gomock.InOrder(
+			mockClient.EXPECT().Get(gomock.Any(), shardKey, gomock.Any()).Return(notFound(shardKey.Name)),
+			mockClient.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(
+				func(ctx context.Context, mfnr *oav1alpha1.ManagedFleetNotificationRecord, opts ...client.CreateOption) error {
+					Expect(mfnr.Name).To(Equal(shardKey.Name))
+					Expect(mfnr.Labels).To(HaveKeyWithValue(consts.ManagementClusterLabel, testconst.TestManagedClusterID))
+					Expect(mfnr.Labels).To(HaveKeyWithValue(consts.HostedClusterLabel, testconst.TestHostedClusterID))
+					return nil
+				}),
+			mockClient.EXPECT().Get(gomock.Any(), legacyKey, gomock.Any()).Return(nil).SetArg(2, legacy),
+			mockClient.EXPECT().Status().Return(mockStatusWriter),
+			mockStatusWriter.EXPECT().Update(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+				func(ctx context.Context, mfnr *oav1alpha1.ManagedFleetNotificationRecord, opts ...client.SubResourceUpdateOption) error {
+					// Only the item of the hosted cluster is migrated, along with its limited support reasons
+					item, err := mfnr.GetNotificationRecordItem(testconst.TestManagedClusterID, testconst.TestNotificationName, testconst.TestHostedClusterID)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(item.ResolvedNotificationSentCount).To(Equal(1))
+					Expect(mfnr.HasNotificationRecordItem(testconst.TestManagedClusterID, testconst.TestNotificationName, otherHC)).To(BeFalse())
+					Expect(mfnr.Annotations).To(HaveKeyWithValue(consts.LimitedSupportReasonsAnnotation, `{"`+reasonsKey+`":["1234"]}`))
+					return nil
+				}),
+		)
+		Expect(testHandler.updateManagedFleetNotificationRecord(context.TODO(), testconst.NewTestAlert(true, true), &mfn)).To(Succeed())
+	})
+
+	It("prunes the migrated items from the record of the management cluster", func() {
+		shard := newShard(testconst.TestHostedClusterID, &notifiedAt)
+		shard.Annotations = legacy.Annotations
+		expectLists(legacy, shard)
+		gomock.InOrder(
+			mockClient.EXPECT().Get(gomock.Any(), legacyKey, gomock.Any()).Return(nil).SetArg(2, legacy),
+			mockClient.EXPECT().Status().Return(mockStatusWriter),
+			mockStatusWriter.EXPECT().Update(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+				func(ctx context.Context, mfnr *oav1alpha1.ManagedFleetNotificationRecord, opts ...client.SubResourceUpdateOption) error {
+					Expect(mfnr.HasNotificationRecordItem(testconst.TestManagedClusterID, testconst.TestNotificationName, testconst.TestHostedClusterID)).To(BeFalse())
+					Expect(mfnr.HasNotificationRecordItem(testconst.TestManagedClusterID, testconst.TestNotificationName, otherHC)).To(BeTrue())
+					return nil
+				}),
+			mockClient.EXPECT().Update(gomock.Any(), gomock.Any()).DoAndReturn(
+				func(ctx context.Context, mfnr *oav1alpha1.ManagedFleetNotificationRecord, opts ...client.UpdateOption) error {
+					Expect(mfnr.Annotations).NotTo(HaveKey(consts.LimitedSupportReasonsAnnotation))
+					return nil
+				}),
+		)
+		Expect(testHandler.PruneNotificationRecords(context.TODO(), 0)).To(Succeed())
+	})
+
+	It("keeps the migrated item until its limited support reasons are recorded on the record of the hosted cluster", func() {
+		expectLists(legacy, newShard(testconst.TestHostedClusterID, &notifiedAt))
+		Expect(testHandler.PruneNotificationRecords(context.TODO(), 0)).To(Succeed())
+	})
+
+	It("reconciles the limited support of the migrated item until its reasons are recorded on the record of the hosted cluster", func() {
+		mockOCMClient := webhookreceivermock.NewMockOCMClient(mockCtrl)
+		testHandler.ocm = mockOCMClient
+		shard := newShard(testconst.TestHostedClusterID, &notifiedAt)
+		shard.Status.NotificationRecordByName[0].NotificationRecordItems[0].ResolvedNotificationSentCount = 1
+		expectLists(legacy, shard)
+		// The item of the management cluster still holds the reasons to reconcile, the one of the hosted cluster has none
+		mockOCMClient.EXPECT().GetLimitedSupportReasons(gomock.Any(), testconst.TestHostedClusterID).Return(nil, errors.New("OCM unavailable"))
+		mockOCMClient.EXPECT().GetLimitedSupportReasons(gomock.Any(), otherHC).Return(nil, errors.New("OCM unavailable"))
+		Expect(testHandler.ReconcileLimitedSupport(context.TODO())).To(Succeed())
+	})
+
+	It("deletes the record of the hosted cluster left empty", func() {
+		idleSince := metav1.NewTime(time.Now().Add(-40 * 24 * time.Hour))
+		mfn = testconst.NewManagedFleetNotification(false)
+		shard := newShard(otherHC, &idleSince)
+		expectLists(shard)
+		gomock.InOrder(
+			mockClient.EXPECT().Get(gomock.Any(), client.ObjectKeyFromObject(&shard), gomock.Any()).Return(nil).SetArg(2, shard),
+			mockClient.EXPECT().Status().Return(mockStatusWriter),
+			mockStatusWriter.EXPECT().Update(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil),
+			mockClient.EXPECT().Delete(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil),
+		)
+		Expect(testHandler.PruneNotificationRecords(context.TODO(), DefaultRecordRetention)).To(Succeed())
+	})
+})
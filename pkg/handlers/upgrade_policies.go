@@ -30,11 +30,11 @@ func NewUpgradePoliciesHandler(o ocm.OCMClient, clusterId string) *UpgradePolici
 func (g *UpgradePoliciesHandler) ServeUpgradePolicyList(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case "GET":
-		policies, operationIdHeader, err := g.ocm.GetUpgradePolicies(g.clusterID)
+		policies, operationIdHeader, err := g.ocm.GetUpgradePolicies(r.Context(), g.clusterID)
 		w.Header().Set(ocm.OcmOperationIdHeader, operationIdHeader)
 
 		if err != nil {
-			errorMessageResponse(err, w)
+			ocmErrorResponse(err, w)
 			return
 		}
 
@@ -56,11 +56,11 @@ func (g *UpgradePoliciesHandler) ServeUpgradePolicyGet(w http.ResponseWriter, r
 
 	switch r.Method {
 	case "GET":
-		policy, operationIdHeader, err := g.ocm.GetUpgradePolicy(g.clusterID, upgradePolicyID)
+		policy, operationIdHeader, err := g.ocm.GetUpgradePolicy(r.Context(), g.clusterID, upgradePolicyID)
 		w.Header().Set(OCM_OPERATION_ID_HEADER, operationIdHeader)
 
 		if err != nil {
-			errorMessageResponse(err, w)
+			ocmErrorResponse(err, w)
 			return
 		}
 
@@ -82,11 +82,11 @@ func (g *UpgradePoliciesHandler) ServeUpgradePolicyState(w http.ResponseWriter,
 
 	switch r.Method {
 	case "GET":
-		policyState, operationIdHeader, err := g.ocm.GetUpgradePolicyState(g.clusterID, upgradePolicyID)
+		policyState, operationIdHeader, err := g.ocm.GetUpgradePolicyState(r.Context(), g.clusterID, upgradePolicyID)
 
 		w.Header().Set(OCM_OPERATION_ID_HEADER, operationIdHeader)
 		if err != nil {
-			errorMessageResponse(err, w)
+			ocmErrorResponse(err, w)
 			return
 		}
 
@@ -103,10 +103,10 @@ func (g *UpgradePoliciesHandler) ServeUpgradePolicyState(w http.ResponseWriter,
 			return
 		}
 
-		policy, operationIdHeader, err := g.ocm.UpdateUpgradePolicyState(g.clusterID, upgradePolicyID, updatedPolicyState)
+		policy, operationIdHeader, err := g.ocm.UpdateUpgradePolicyState(r.Context(), g.clusterID, upgradePolicyID, updatedPolicyState)
 		w.Header().Set(OCM_OPERATION_ID_HEADER, operationIdHeader)
 		if err != nil {
-			errorMessageResponse(err, w)
+			ocmErrorResponse(err, w)
 			return
 		}
 
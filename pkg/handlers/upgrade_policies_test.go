@@ -343,7 +343,7 @@ var _ = Describe("UpgradePolicies", func() {
 		upgradePoliciesHandler.ServeUpgradePolicyList(responseRecorder, req)
 
 		Expect(reflect.DeepEqual(ocmOperationId, responseRecorder.Header().Get(handlers.OCM_OPERATION_ID_HEADER))).To(BeTrue())
-		Expect(responseRecorder.Result().StatusCode).To(Equal(http.StatusBadRequest))
+		Expect(responseRecorder.Result().StatusCode).To(Equal(http.StatusBadGateway))
 
 		req = httptest.NewRequest("GET", fmt.Sprintf("/upgrade_policies/%s", upgradePolicyId), nil)
 		req = mux.SetURLVars(
@@ -355,7 +355,7 @@ var _ = Describe("UpgradePolicies", func() {
 
 		upgradePoliciesHandler.ServeUpgradePolicyGet(responseRecorder, req)
 		Expect(reflect.DeepEqual(ocmOperationId, responseRecorder.Header().Get(handlers.OCM_OPERATION_ID_HEADER))).To(BeTrue())
-		Expect(responseRecorder.Result().StatusCode).To(Equal(http.StatusBadRequest))
+		Expect(responseRecorder.Result().StatusCode).To(Equal(http.StatusBadGateway))
 
 		req = httptest.NewRequest("PATCH", fmt.Sprintf("/upgrade_policies/%s", upgradePolicyId), nil)
 		req = mux.SetURLVars(
@@ -367,7 +367,7 @@ var _ = Describe("UpgradePolicies", func() {
 
 		upgradePoliciesHandler.ServeUpgradePolicyGet(responseRecorder, req)
 		Expect(reflect.DeepEqual(ocmOperationId, responseRecorder.Header().Get(handlers.OCM_OPERATION_ID_HEADER))).To(BeTrue())
-		Expect(responseRecorder.Result().StatusCode).To(Equal(http.StatusBadRequest))
+		Expect(responseRecorder.Result().StatusCode).To(Equal(http.StatusBadGateway))
 
 		req = httptest.NewRequest("DELETE", fmt.Sprintf("/upgrade_policies/%s", upgradePolicyId), nil)
 
@@ -380,7 +380,7 @@ var _ = Describe("UpgradePolicies", func() {
 
 		upgradePoliciesHandler.ServeUpgradePolicyGet(responseRecorder, req)
 		Expect(reflect.DeepEqual(ocmOperationId, responseRecorder.Header().Get(handlers.OCM_OPERATION_ID_HEADER))).To(BeTrue())
-		Expect(responseRecorder.Result().StatusCode).To(Equal(http.StatusBadRequest))
+		Expect(responseRecorder.Result().StatusCode).To(Equal(http.StatusBadGateway))
 
 		req = httptest.NewRequest("GET", fmt.Sprintf("/upgrade_policies/%s/state", upgradePolicyId), nil)
 
@@ -393,7 +393,7 @@ var _ = Describe("UpgradePolicies", func() {
 
 		upgradePoliciesHandler.ServeUpgradePolicyState(responseRecorder, req)
 		Expect(reflect.DeepEqual(ocmOperationId, responseRecorder.Header().Get(handlers.OCM_OPERATION_ID_HEADER))).To(BeTrue())
-		Expect(responseRecorder.Result().StatusCode).To(Equal(http.StatusBadRequest))
+		Expect(responseRecorder.Result().StatusCode).To(Equal(http.StatusBadGateway))
 
 		req = httptest.NewRequest("PATCH", fmt.Sprintf("/upgrade_policies/%s/state", upgradePolicyId), nil)
 		req = mux.SetURLVars(
@@ -405,6 +405,6 @@ var _ = Describe("UpgradePolicies", func() {
 
 		upgradePoliciesHandler.ServeUpgradePolicyState(responseRecorder, req)
 		Expect(reflect.DeepEqual(ocmOperationId, responseRecorder.Header().Get(handlers.OCM_OPERATION_ID_HEADER))).To(BeTrue())
-		Expect(responseRecorder.Result().StatusCode).To(Equal(http.StatusBadRequest))
+		Expect(responseRecorder.Result().StatusCode).To(Equal(http.StatusBadGateway))
 	})
 })
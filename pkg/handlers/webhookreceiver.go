@@ -5,16 +5,22 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/openshift/ocm-agent/pkg/config"
+	"github.com/openshift/ocm-agent/pkg/events"
 	"github.com/openshift/ocm-agent/pkg/httpchecker"
 	"github.com/openshift/ocm-agent/pkg/ocm"
+	"github.com/openshift/ocm-agent/pkg/outbox"
+	"github.com/openshift/ocm-agent/pkg/queue"
+	"github.com/openshift/ocm-agent/pkg/ratelimit"
 	"github.com/spf13/viper"
 
 	"github.com/prometheus/alertmanager/template"
 	log "github.com/sirupsen/logrus"
 
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
 	oav1alpha1 "github.com/openshift/ocm-agent-operator/api/v1alpha1"
 	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -28,11 +34,108 @@ import (
 
 func NewWebhookReceiverHandler(c client.Client, o ocm.OCMClient) *WebhookReceiverHandler {
 	return &WebhookReceiverHandler{
-		c:   c,
-		ocm: o,
+		c:        c,
+		ocm:      o,
+		clusters: ocm.NewClusterCache(o, ocm.DefaultClusterCacheTTL),
 	}
 }
 
+// WithQueue makes the handler acknowledge alerts as soon as they are queued, the alerts are then
+// processed by the queue workers through ProcessQueuedItem
+func (h *WebhookReceiverHandler) WithQueue(q *queue.Queue) *WebhookReceiverHandler {
+	h.queue = q
+	return h
+}
+
+// WithDryRun records the notifications in the recorder instead of sending them, for all the notifications
+// if all is set or only for the ManagedNotifications annotated with the dry-run annotation otherwise
+func (h *WebhookReceiverHandler) WithDryRun(recorder *ocm.DryRunRecorder, all bool) *WebhookReceiverHandler {
+	h.dryRun = dryRunConfig{ocm: ocm.NewDryRunClient(h.ocm, recorder), all: all}
+	return h
+}
+
+// WithRateLimiter throttles the notifications sent to OCM with the limiter
+func (h *WebhookReceiverHandler) WithRateLimiter(limiter *ratelimit.Limiter) *WebhookReceiverHandler {
+	h.limiter = limiter
+	return h
+}
+
+// WithHealthMonitor makes the handler fail the alerts as retriable without sending them while OCM is unavailable
+func (h *WebhookReceiverHandler) WithHealthMonitor(health *httpchecker.HealthMonitor) *WebhookReceiverHandler {
+	h.health = health
+	return h
+}
+
+// WithOutbox makes the handler add the service logs which couldn't be sent to OCM to the outbox, they are
+// replayed through ReplayOutboxEntry
+func (h *WebhookReceiverHandler) WithOutbox(o *outbox.Outbox) *WebhookReceiverHandler {
+	h.outbox = o
+	return h
+}
+
+// WithEvents makes the handler emit the events of the notifications on their ManagedNotification
+func (h *WebhookReceiverHandler) WithEvents(r *events.Recorder) *WebhookReceiverHandler {
+	h.events = r
+	return h
+}
+
+// WithCache makes the handler read the ManagedNotifications from the cache rather than from the API server. Their
+// status is still read from the API server, as whether a notification was already sent is decided from it and the
+// cache trails the agent's own writes.
+func (h *WebhookReceiverHandler) WithCache(r client.Reader) *WebhookReceiverHandler {
+	h.cache = r
+	return h
+}
+
+// reader returns the reader of the notifications which don't have to be read from the API server
+func (h *WebhookReceiverHandler) reader() client.Reader {
+	if h.cache != nil {
+		return h.cache
+	}
+	return h.c
+}
+
+// currentManagedNotification reads from the API server the ManagedNotification read from the cache, if any
+func (h *WebhookReceiverHandler) currentManagedNotification(ctx context.Context, mn *oav1alpha1.ManagedNotification) (*oav1alpha1.ManagedNotification, error) {
+	if h.cache == nil {
+		return mn, nil
+	}
+	current := &oav1alpha1.ManagedNotification{}
+	if err := h.c.Get(ctx, client.ObjectKeyFromObject(mn), current); err != nil {
+		return nil, fmt.Errorf("unable to read ManagedNotification %s: %w", mn.Name, err)
+	}
+	return current, nil
+}
+
+// ReplayOutboxEntry processes the alert of an outbox entry again, the entry is kept in the outbox when it fails
+// for a transient reason
+func (h *WebhookReceiverHandler) ReplayOutboxEntry(ctx context.Context, entry outbox.Entry) error {
+	// The alert mustn't be added back to the outbox, nor wait on its own entry
+	replayer := *h
+	replayer.outbox = nil
+
+	mnl := &oav1alpha1.ManagedNotificationList{}
+	err := h.reader().List(ctx, mnl, client.InNamespace(OCMAgentNamespaceName))
+	if err != nil {
+		return fmt.Errorf("unable to list managed notifications: %w", err)
+	}
+	return replayResult(entry, replayer.processAlert(ctx, entry.Alert, mnl, entry.Firing()))
+}
+
+// ProcessQueuedItem processes the alert data held by an item of the queue
+func (h *WebhookReceiverHandler) ProcessQueuedItem(ctx context.Context, item queue.Item) error {
+	d, err := decodeQueuedItem(item)
+	if err != nil {
+		return err
+	}
+	return queuedResponseError(h.processAMReceiver(d, ctx))
+}
+
+// queueKey keeps the alerts of the same notification template in order
+func (h *WebhookReceiverHandler) queueKey(alert template.Alert) string {
+	return alert.Labels[AMLabelTemplateName]
+}
+
 func (h *WebhookReceiverHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// validate request
 	if r != nil && r.Method != http.MethodPost {
@@ -50,7 +153,12 @@ func (h *WebhookReceiverHandler) ServeHTTP(w http.ResponseWriter, r *http.Reques
 	}
 
 	// process request
-	response := h.processAMReceiver(alertData, r.Context())
+	var response *AMReceiverResponse
+	if h.queue != nil {
+		response = enqueueAMReceiver(h.queue, alertData, h.queueKey)
+	} else {
+		response = h.processAMReceiver(alertData, r.Context())
+	}
 
 	// write response
 	w.Header().Set("Content-Type", "application/json")
@@ -74,37 +182,41 @@ func (h *WebhookReceiverHandler) processAMReceiver(d AMReceiverData, ctx context
 	listOptions := []client.ListOption{
 		client.InNamespace("openshift-ocm-agent-operator"),
 	}
-	err := h.c.List(ctx, mnl, listOptions...)
+	err := h.reader().List(ctx, mnl, listOptions...)
 	if err != nil {
 		log.WithError(err).Error("unable to list managed notifications")
 		return &AMReceiverResponse{Error: err, Status: "unable to list managed notifications", Code: http.StatusInternalServerError}
 	}
 
+	var results []AMReceiverAlertResult
+
 	// Handle each firing alert
 	for _, alert := range d.Alerts.Firing() {
-		err = h.processAlert(alert, mnl, true)
+		err = h.processAlert(ctx, alert, mnl, true)
 		if err != nil {
 			log.WithError(err).Error("a firing alert could not be successfully processed")
 		}
+		results = append(results, alertResult(alert, err))
 	}
 
 	// Handle resolved alerts
 	for _, alert := range d.Alerts.Resolved() {
-		err := h.processAlert(alert, mnl, false)
+		err := h.processAlert(ctx, alert, mnl, false)
 		if err != nil {
 			log.WithError(err).Error("a resolved alert could not be successfully processed")
 		}
+		results = append(results, alertResult(alert, err))
 	}
-	return &AMReceiverResponse{Error: nil, Status: "ok", Code: http.StatusOK}
+	return newAMReceiverResponse(results)
 }
 
 // processAlert handles the pre-check verification and sending of a notification for a particular alert
 // and returns an error if that process completed successfully or false otherwise
-func (h *WebhookReceiverHandler) processAlert(alert template.Alert, mnl *oav1alpha1.ManagedNotificationList, firing bool) error {
+func (h *WebhookReceiverHandler) processAlert(ctx context.Context, alert template.Alert, mnl *oav1alpha1.ManagedNotificationList, firing bool) error {
 	// Should this alert be handled?
 	if !isValidAlert(alert, false) {
 		log.WithField(LogFieldAlert, fmt.Sprintf("%+v", alert)).Info("alert does not meet valid criteria")
-		return fmt.Errorf("alert does not meet valid criteria")
+		return errInvalidAlert
 	}
 
 	// Can the alert be mapped to an existing notification definition?
@@ -114,17 +226,46 @@ func (h *WebhookReceiverHandler) processAlert(alert template.Alert, mnl *oav1alp
 		return err
 	}
 
-	// Has a servicelog already been sent and we are within the notification's "do-not-resend" window?
-	canBeSent, err := managedNotifications.CanBeSent(notification.Name, firing)
+	// Whether the notification was already sent is decided from the current status
+	managedNotifications, err = h.currentManagedNotification(ctx, managedNotifications)
 	if err != nil {
-		log.WithError(err).WithField(LogFieldNotificationName, notification.Name).Error("unable to validate if notification can be sent")
 		return err
 	}
+
+	// In dry-run mode the notification is recorded instead of being sent, and its status is left untouched
+	ocmClient, dryRun := h.dryRun.clientFor(managedNotifications, h.ocm)
+
+	// The firing notification waiting to be replayed is obsolete once the alert resolved
+	externalID := viper.GetString(config.ExternalClusterID)
+	if !firing {
+		dropResolvedFromOutbox(ctx, h.outbox, externalID, notification.Name, alert)
+	}
+	limitedSupport := isLimitedSupport(managedNotifications, notification.Name)
+	n := events.Notification{Template: notification.Name, Cluster: externalID}
+	entry := outbox.Entry{Cluster: externalID, Operation: classicOperation(limitedSupport, firing), Notification: notification.Name, Alert: alert}
+
+	// Has the notification already been delivered for this alert instance, e.g. by a previous delivery of the alert?
+	if isDelivered(managedNotifications, notification, alert, firing) {
+		log.WithFields(log.Fields{LogFieldNotificationName: notification.Name, LogFieldIsFiring: firing, LogFieldFingerprint: alert.Fingerprint}).Info("not sending a notification already delivered for this alert")
+		return nil
+	}
+
+	// Has a servicelog already been sent and we are within the notification's "do-not-resend" window?
+	// The limited support reasons posted for the notification are removed on resolve regardless.
+	canBeSent := limitedSupport && !firing
+	if !canBeSent {
+		canBeSent, err = managedNotifications.CanBeSent(notification.Name, firing)
+		if err != nil {
+			log.WithError(err).WithField(LogFieldNotificationName, notification.Name).Error("unable to validate if notification can be sent")
+			return err
+		}
+	}
 	if !canBeSent {
 		if firing {
 			log.WithFields(log.Fields{"notification": notification.Name,
 				LogFieldResendInterval: notification.ResendWait,
 			}).Info("not sending a notification as one was already sent recently")
+			h.events.Suppressed(managedNotifications, n, notification.ResendWait)
 			// Reset the metric for correct service log response from OCM
 			metrics.ResetResponseMetricFailure(config.ServiceLogService, notification.Name, alert.Labels["alertname"])
 		} else {
@@ -135,9 +276,9 @@ func (h *WebhookReceiverHandler) processAlert(alert template.Alert, mnl *oav1alp
 				return err
 			}
 			firingStatus := s.Conditions.GetCondition(oav1alpha1.ConditionAlertFiring).Status
-			if firingStatus == corev1.ConditionTrue {
+			if firingStatus == corev1.ConditionTrue && !dryRun {
 				// Update the notification status for the resolved alert without sending resolved SL
-				_, err := h.updateNotificationStatus(notification, managedNotifications, firing, corev1.ConditionTrue)
+				_, err := h.updateNotificationStatus(ctx, notification, managedNotifications, firing, alert, corev1.ConditionTrue)
 				if err != nil {
 					log.WithFields(log.Fields{LogFieldNotificationName: notification.Name, LogFieldManagedNotification: managedNotifications.Name}).WithError(err).Error("unable to update notification status")
 					return err
@@ -148,33 +289,59 @@ func (h *WebhookReceiverHandler) processAlert(alert template.Alert, mnl *oav1alp
 		return nil
 	}
 
-	var attempts int = 3
-	var sleep time.Duration = 30 * time.Second
-	ocmURL := viper.GetString(config.OcmURL)
-	if ocmURL == "" {
-		return fmt.Errorf("OCM URL is missing or empty in the configuration")
+	// The notifications of the cluster are sent in order, after the ones waiting in the outbox
+	if !dryRun && h.outbox.Pending(externalID) {
+		return deferToOutbox(ctx, h.outbox, entry, retriable(errOutboxPending))
 	}
-	err = checkURLWithRetries(ocmURL, attempts, sleep)
-	if err != nil {
-		return err
+
+	if err := h.health.Available(); err != nil {
+		// OCM being unreachable is transient, the alert should be delivered again
+		return deferToOutbox(ctx, h.outbox, entry, retriable(fmt.Errorf("OCM is unavailable: %w", err)))
+	}
+
+	if limitedSupport {
+		return h.processLimitedSupport(ctx, alert, notification, managedNotifications, firing, entry)
 	}
 
 	// Send the servicelog for the alert
 	log.WithFields(log.Fields{LogFieldNotificationName: notification.Name}).Info("will send servicelog for notification")
-	slerr := ocm.BuildAndSendServiceLog(
-		ocm.NewServiceLogBuilder(notification.Summary, notification.ActiveDesc, notification.ResolvedDesc, viper.GetString(config.ExternalClusterID), notification.Severity, notification.LogType, notification.References),
-		firing, &alert, h.ocm)
+	slBuilder := ocm.NewServiceLogBuilder(notification.Summary, notification.ActiveDesc, notification.ResolvedDesc, externalID, notification.Severity, notification.LogType, notification.References).
+		WithTemplateEngine(managedNotifications.Annotations[consts.TemplateEngineAnnotation])
+	if err := withClusterData(ctx, slBuilder, h.clusters, externalID); err != nil {
+		return err
+	}
+	logEntry, err := slBuilder.Build(firing, &alert)
+	if err != nil {
+		// A notification which can't be built won't be built on a new attempt either
+		log.WithError(err).WithFields(log.Fields{LogFieldNotificationName: notification.Name}).Error("unable to build a notification")
+		return err
+	}
+	if !dryRun {
+		if err := throttle(ctx, h.limiter, ocm.OperationSendServiceLog, externalID, notification.Name); err != nil {
+			return err
+		}
+	}
+	operationID, slerr := ocmClient.SendServiceLog(ctx, logEntry)
 	if slerr != nil {
-		log.WithError(err).WithFields(log.Fields{LogFieldNotificationName: notification.Name, LogFieldIsFiring: true}).Error("unable to send a notification")
-		_, err := h.updateNotificationStatus(notification, managedNotifications, firing, corev1.ConditionFalse)
+		log.WithError(slerr).WithFields(log.Fields{LogFieldNotificationName: notification.Name, LogFieldIsFiring: true}).Error("unable to send a notification")
+		h.events.SendFailed(managedNotifications, n, ocm.OperationSendServiceLog, slerr)
+		_, err := h.updateNotificationStatus(ctx, notification, managedNotifications, firing, alert, corev1.ConditionFalse)
 		if err != nil {
 			log.WithFields(log.Fields{LogFieldNotificationName: notification.Name, LogFieldManagedNotification: managedNotifications.Name}).WithError(err).Error("unable to update notification status")
 		}
 		// Set the metric for failed service log response from OCM
 		metrics.SetResponseMetricFailure(config.ServiceLogService, notification.Name, alert.Labels["alertname"])
 		metrics.CountFailedServiceLogs(notification.Name)
-		return slerr
+		return deferToOutbox(ctx, h.outbox, entry, retriableOCMError(slerr))
+	}
+
+	if dryRun {
+		return nil
 	}
+	h.events.ServiceLogSent(managedNotifications, n, operationID, firing)
+
+	// The notification was sent, recording it must not be aborted with the request
+	ctx = context.WithoutCancel(ctx)
 
 	// Reset the metric for correct service log response from OCM
 	metrics.ResetResponseMetricFailure(config.ServiceLogService, notification.Name, alert.Labels["alertname"])
@@ -185,8 +352,8 @@ func (h *WebhookReceiverHandler) processAlert(alert template.Alert, mnl *oav1alp
 	} else {
 		metrics.CountServiceLogSent(notification.Name, "resolved")
 	}
-	// Update the notification status to indicate a servicelog has been sent
-	m, err := h.updateNotificationStatus(notification, managedNotifications, firing, corev1.ConditionTrue)
+	// Update the notification status to indicate a servicelog has been sent, and for which alert instance
+	m, err := h.updateNotificationStatus(ctx, notification, managedNotifications, firing, alert, corev1.ConditionTrue)
 	if err != nil {
 		log.WithFields(log.Fields{LogFieldNotificationName: notification.Name, LogFieldManagedNotification: managedNotifications.Name}).WithError(err).Error("unable to update notification status")
 		return err
@@ -201,6 +368,153 @@ func (h *WebhookReceiverHandler) processAlert(alert template.Alert, mnl *oav1alp
 	return nil
 }
 
+// processLimitedSupport places the cluster into limited support for a firing alert of the notification, and
+// removes the limited support reason of the notification once the alert resolves
+func (h *WebhookReceiverHandler) processLimitedSupport(ctx context.Context, alert template.Alert, notification *oav1alpha1.Notification, mn *oav1alpha1.ManagedNotification, firing bool, entry outbox.Entry) error {
+	externalID := entry.Cluster
+	ocmClient, dryRun := h.dryRun.clientFor(mn, h.ocm)
+	n := events.Notification{Template: notification.Name, Cluster: externalID}
+
+	// The limited support reasons posted for the notification, the ones matching it otherwise
+	ownedIDs, known := managedLimitedSupportReasons(mn, notification.Name)
+
+	if firing {
+		if len(ownedIDs) > 0 || !known {
+			log.WithFields(log.Fields{LogFieldNotificationName: notification.Name}).Info("not sending a limited support notification as the previous one didn't resolve yet")
+			return nil
+		}
+		log.WithFields(log.Fields{LogFieldNotificationName: notification.Name}).Info("will send limited support for notification")
+		reason, err := cmv1.NewLimitedSupportReason().
+			Summary(notification.Summary).
+			Details(notification.ActiveDesc).
+			DetectionType(cmv1.DetectionTypeManual).
+			Build()
+		if err != nil {
+			return fmt.Errorf("unable to build limited support for notification '%s' reason: %w", notification.Name, err)
+		}
+		if !dryRun {
+			if err := throttle(ctx, h.limiter, ocm.OperationSendLimitedSupport, externalID, notification.Name); err != nil {
+				return err
+			}
+		}
+		created, operationID, err := ocmClient.SendLimitedSupport(ctx, externalID, reason)
+		if err != nil {
+			log.WithError(err).WithFields(log.Fields{LogFieldNotificationName: notification.Name, LogFieldIsFiring: true}).Error("unable to send limited support for notification")
+			h.events.SendFailed(mn, n, ocm.OperationSendLimitedSupport, err)
+			_, statusErr := h.updateNotificationStatus(ctx, notification, mn, firing, alert, corev1.ConditionFalse)
+			if statusErr != nil {
+				log.WithFields(log.Fields{LogFieldNotificationName: notification.Name, LogFieldManagedNotification: mn.Name}).WithError(statusErr).Error("unable to update notification status")
+			}
+			// Set the metric for failed limited support response from OCM
+			metrics.SetResponseMetricFailure(config.ClustersService, notification.Name, alert.Labels["alertname"])
+			metrics.IncrementFailedLimitedSupportSend(notification.Name)
+			return deferToOutbox(ctx, h.outbox, entry, retriableOCMError(fmt.Errorf("limited support reason for notification '%s' could not be set for cluster %s, err: %w", notification.Name, externalID, err)))
+		}
+		if !dryRun {
+			metrics.IncrementLimitedSupportSentCount(notification.Name)
+			h.events.LimitedSupportSet(mn, n, operationID, created.ID())
+
+			// The limited support was set, recording it must not be aborted with the request
+			ctx = context.WithoutCancel(ctx)
+			// Record the reason ID first so that it is known to be owned by the agent, the status recorded next
+			// still shows the limited support outstanding if this fails
+			if err := h.updateManagedLimitedSupportReasons(ctx, mn, notification.Name, func(ids []string) []string {
+				return append(ids, created.ID())
+			}); err != nil {
+				log.WithError(err).WithFields(log.Fields{LogFieldNotificationName: notification.Name, LogFieldManagedNotification: mn.Name}).Warning("unable to record the limited support reason")
+			}
+		}
+	} else if len(ownedIDs) > 0 || !known {
+		activeLSReasons, err := h.ocm.GetLimitedSupportReasons(ctx, externalID)
+		if err != nil {
+			return deferToOutbox(ctx, h.outbox, entry, retriableOCMError(fmt.Errorf("unable to get limited support reasons for cluster %s:, %w", externalID, err)))
+		}
+		for _, reason := range activeLSReasons {
+			if !ownsLimitedSupportReason(reason, notification.Summary, notification.ActiveDesc, ownedIDs, known) {
+				continue
+			}
+			log.WithFields(log.Fields{LogFieldNotificationName: notification.Name}).Infof("will remove limited support reason '%s' for notification", reason.ID())
+			if !dryRun {
+				if err := throttle(ctx, h.limiter, ocm.OperationRemoveLimitedSupport, externalID, notification.Name); err != nil {
+					return err
+				}
+			}
+			operationID, err := ocmClient.RemoveLimitedSupport(ctx, externalID, reason.ID())
+			if err != nil {
+				metrics.IncrementFailedLimitedSupportRemoved(notification.Name)
+				h.events.SendFailed(mn, n, ocm.OperationRemoveLimitedSupport, err)
+				// Set the metric for failed limited support response from OCM
+				metrics.SetResponseMetricFailure(config.ClustersService, notification.Name, alert.Labels["alertname"])
+				return deferToOutbox(ctx, h.outbox, entry, retriableOCMError(fmt.Errorf("limited support reason with ID '%s' couldn't be removed for cluster %s, err: %w", reason.ID(), externalID, err)))
+			}
+			if !dryRun {
+				metrics.IncrementLimitedSupportRemovedCount(notification.Name)
+				h.events.LimitedSupportRemoved(mn, n, operationID, reason.ID())
+			}
+		}
+		if !dryRun {
+			// The reasons were removed, they are no longer recorded as owned by the agent
+			if err := h.updateManagedLimitedSupportReasons(context.WithoutCancel(ctx), mn, notification.Name, func([]string) []string {
+				return nil
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	// Reset the metric for correct limited support response from OCM
+	metrics.ResetResponseMetricFailure(config.ClustersService, notification.Name, alert.Labels["alertname"])
+
+	if dryRun {
+		return nil
+	}
+
+	// The limited support was changed, recording it must not be aborted with the request
+	ctx = context.WithoutCancel(ctx)
+	if !firing {
+		// Only a status showing the alert firing is resolved, updating any other would record the alert firing
+		// again and hold back its next limited support
+		record, err := mn.Status.GetNotificationRecord(notification.Name)
+		if err != nil {
+			return nil
+		}
+		if firingCondition := record.Conditions.GetCondition(oav1alpha1.ConditionAlertFiring); firingCondition == nil || firingCondition.Status != corev1.ConditionTrue {
+			return nil
+		}
+		if _, err := h.updateNotificationStatus(ctx, notification, mn, false, alert, corev1.ConditionTrue); err != nil {
+			log.WithFields(log.Fields{LogFieldNotificationName: notification.Name, LogFieldManagedNotification: mn.Name}).WithError(err).Error("unable to update notification status")
+			return err
+		}
+		return nil
+	}
+	if _, err := h.updateNotificationStatus(ctx, notification, mn, true, alert, corev1.ConditionTrue); err != nil {
+		log.WithFields(log.Fields{LogFieldNotificationName: notification.Name, LogFieldManagedNotification: mn.Name}).WithError(err).Error("unable to update notification status")
+		return err
+	}
+	return nil
+}
+
+// isLimitedSupport indicates whether the notification places the cluster into limited support rather than
+// sending a service log, as listed by the limited support annotation of its ManagedNotification
+func isLimitedSupport(mn *oav1alpha1.ManagedNotification, name string) bool {
+	for _, n := range strings.Split(mn.Annotations[consts.LimitedSupportAnnotation], ",") {
+		if strings.TrimSpace(n) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// classicOperation returns the OCM write made for an alert of a notification
+func classicOperation(limitedSupport, firing bool) string {
+	switch {
+	case limitedSupport && firing:
+		return ocm.OperationSendLimitedSupport
+	case limitedSupport:
+		return ocm.OperationRemoveLimitedSupport
+	}
+	return ocm.OperationSendServiceLog
+}
+
 // getNotification returns the notification from the ManagedNotification bundle if one exists, or error if one does not
 func getNotification(name string, m *oav1alpha1.ManagedNotificationList) (*oav1alpha1.Notification, *oav1alpha1.ManagedNotification, error) {
 	for _, mn := range m.Items {
@@ -212,27 +526,17 @@ func getNotification(name string, m *oav1alpha1.ManagedNotificationList) (*oav1a
 	return nil, nil, fmt.Errorf("matching managed notification not found for %s", name)
 }
 
-// checkURLWithRetries returns err for response code outside >=200 and <300
-func checkURLWithRetries(url string, attempts int, sleep time.Duration) error {
-	// Use the default HTTP client
-	urlchecker := httpchecker.NewHTTPChecker(nil)
-	err := httpchecker.Reattempt(attempts, sleep, func() error {
-		return urlchecker.UrlAvailabilityCheck(url)
-	})
-	if err != nil {
-		return err
-	}
-	return nil
-}
-
-func (h *WebhookReceiverHandler) updateNotificationStatus(n *oav1alpha1.Notification, mn *oav1alpha1.ManagedNotification, firing bool, slsentstatus corev1.ConditionStatus) (*oav1alpha1.ManagedNotification, error) {
+// updateNotificationStatus updates the status of the notification for the alert. When the notification was sent,
+// the alert instance is recorded first on the ManagedNotification so that a new delivery of the alert doesn't send
+// it again.
+func (h *WebhookReceiverHandler) updateNotificationStatus(ctx context.Context, n *oav1alpha1.Notification, mn *oav1alpha1.ManagedNotification, firing bool, alert template.Alert, slsentstatus corev1.ConditionStatus) (*oav1alpha1.ManagedNotification, error) {
 	var m *oav1alpha1.ManagedNotification
 
 	// Update lastSent timestamp
 	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
 		m = &oav1alpha1.ManagedNotification{}
 
-		err := h.c.Get(context.TODO(), client.ObjectKey{
+		err := h.c.Get(ctx, client.ObjectKey{
 			Namespace: mn.Namespace,
 			Name:      mn.Name,
 		}, m)
@@ -241,6 +545,18 @@ func (h *WebhookReceiverHandler) updateNotificationStatus(n *oav1alpha1.Notifica
 		}
 
 		timeNow := &v1.Time{Time: time.Now()}
+
+		// The delivery is recorded first, the status update retried on a conflict records it again if needed
+		if slsentstatus == corev1.ConditionTrue && alert.Fingerprint != "" {
+			base := m.DeepCopy()
+			if err := recordDelivery(m, n.Name, firing, deliveredAlert{Fingerprint: alert.Fingerprint, StartsAt: alert.StartsAt, SentAt: timeNow.Time}); err != nil {
+				return err
+			}
+			if err := h.c.Patch(ctx, m, client.MergeFromWithOptions(base, client.MergeFromWithOptimisticLock{})); err != nil {
+				return err
+			}
+		}
+
 		status, err := m.Status.GetNotificationRecord(n.Name)
 		if err != nil {
 			// Status does not exist, create it
@@ -293,7 +609,7 @@ func (h *WebhookReceiverHandler) updateNotificationStatus(n *oav1alpha1.Notifica
 
 		m.Status.NotificationRecords.SetNotificationRecord(*status)
 
-		err = h.c.Status().Update(context.TODO(), m)
+		err = h.c.Status().Update(ctx, m)
 
 		return err
 	})
@@ -10,6 +10,7 @@ import (
 	"io"
 	"net/http"
 	"reflect"
+	"strings"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -19,6 +20,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/golang/mock/gomock"
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
 	"github.com/prometheus/alertmanager/template"
 
 	corev1 "k8s.io/api/core/v1"
@@ -28,9 +30,13 @@ import (
 	ocmagentv1alpha1 "github.com/openshift/ocm-agent-operator/api/v1alpha1"
 
 	"github.com/openshift/ocm-agent/pkg/config"
+	"github.com/openshift/ocm-agent/pkg/consts"
 	testconst "github.com/openshift/ocm-agent/pkg/consts/test"
+	"github.com/openshift/ocm-agent/pkg/httpchecker"
+	httpcheckermock "github.com/openshift/ocm-agent/pkg/httpchecker/mocks"
 	"github.com/openshift/ocm-agent/pkg/ocm"
 	webhookreceivermock "github.com/openshift/ocm-agent/pkg/ocm/mocks"
+	"github.com/openshift/ocm-agent/pkg/queue"
 	clientmocks "github.com/openshift/ocm-agent/pkg/util/test/generated/mocks/client"
 )
 
@@ -43,10 +49,10 @@ func (fn RoundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
 var _ = Describe("Webhook Handlers", func() {
 
 	var (
-		mockCtrl         *gomock.Controller
-		mockClient       *clientmocks.MockClient
-		mockStatusWriter *clientmocks.MockStatusWriter
-		//mockHTTPChecker             *httpcheckermock.MockHTTPChecker
+		mockCtrl                    *gomock.Controller
+		mockClient                  *clientmocks.MockClient
+		mockStatusWriter            *clientmocks.MockStatusWriter
+		mockHTTPChecker             *httpcheckermock.MockHTTPChecker
 		mockOCMClient               *webhookreceivermock.MockOCMClient
 		webhookReceiverHandler      *WebhookReceiverHandler
 		server                      *ghttp.Server
@@ -72,7 +78,7 @@ var _ = Describe("Webhook Handlers", func() {
 		mockClient = clientmocks.NewMockClient(mockCtrl)
 		mockStatusWriter = clientmocks.NewMockStatusWriter(mockCtrl)
 		server = ghttp.NewServer()
-		//mockHTTPChecker = httpcheckermock.NewMockHTTPChecker(mockCtrl)
+		mockHTTPChecker = httpcheckermock.NewMockHTTPChecker(mockCtrl)
 		mockOCMClient = webhookreceivermock.NewMockOCMClient(mockCtrl)
 		webhookReceiverHandler = &WebhookReceiverHandler{
 			c:   mockClient,
@@ -164,6 +170,43 @@ var _ = Describe("Webhook Handlers", func() {
 			Expect(response).Should(Equal(expected))
 		})
 	})
+	Context("AMReceiver handler post with a processing queue", func() {
+		var (
+			q    *queue.Queue
+			resp *http.Response
+			err  error
+		)
+		BeforeEach(func() {
+			// workers are not started so the queued alerts stay in the queue
+			q, err = queue.NewQueue(1, 1, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			webhookReceiverHandler.WithQueue(q)
+			server.AppendHandlers(webhookReceiverHandler.ServeHTTP)
+		})
+		It("Queues the alerts and acknowledges the request without processing them", func() {
+			postDataJson, _ := json.Marshal(AMReceiverData{Status: "firing", Alerts: template.Alerts{testAlert}})
+			resp, err = http.Post(server.URL(), "application/json", bytes.NewBuffer(postDataJson))
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(resp.StatusCode).Should(Equal(http.StatusOK))
+			var response AMReceiverResponse
+			_ = json.NewDecoder(resp.Body).Decode(&response)
+			Expect(response.Status).Should(Equal("queued"))
+			Expect(q.Len()).Should(Equal(1))
+		})
+		It("Asks for a redelivery without queuing any of the alerts when the queue is full", func() {
+			postDataJson, _ := json.Marshal(AMReceiverData{Status: "firing", Alerts: template.Alerts{testAlert, testAlert}})
+			resp, err = http.Post(server.URL(), "application/json", bytes.NewBuffer(postDataJson))
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(resp.StatusCode).Should(Equal(http.StatusServiceUnavailable))
+			Expect(q.Len()).Should(Equal(0))
+		})
+		It("Processes a queued item", func() {
+			mockClient.EXPECT().List(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
+			payload, _ := json.Marshal(AMReceiverData{Status: "foo"})
+			err = webhookReceiverHandler.ProcessQueuedItem(context.TODO(), queue.Item{Key: testconst.TestNotificationName, Payload: payload})
+			Expect(err).ShouldNot(HaveOccurred())
+		})
+	})
 	Context("AMReceiver handler post bad data", func() {
 		var resp *http.Response
 		var err error
@@ -231,17 +274,17 @@ var _ = Describe("Webhook Handlers", func() {
 		Context("Check if an alert is valid or not", func() {
 			It("Reports error if alert does not have alertname label", func() {
 				delete(testAlert.Labels, "alertname")
-				err := webhookReceiverHandler.processAlert(testAlert, testconst.TestManagedNotificationList, true)
+				err := webhookReceiverHandler.processAlert(context.TODO(), testAlert, testconst.TestManagedNotificationList, true)
 				Expect(err).Should(HaveOccurred())
 			})
 			It("Reports error if alert does not have managed_notification_template label", func() {
 				delete(testAlert.Labels, "managed_notification_template")
-				err := webhookReceiverHandler.processAlert(testAlert, testconst.TestManagedNotificationList, true)
+				err := webhookReceiverHandler.processAlert(context.TODO(), testAlert, testconst.TestManagedNotificationList, true)
 				Expect(err).Should(HaveOccurred())
 			})
 			It("Reports error if alert does not have send_managed_notification label", func() {
 				delete(testAlert.Labels, "send_managed_notification")
-				err := webhookReceiverHandler.processAlert(testAlert, testconst.TestManagedNotificationList, true)
+				err := webhookReceiverHandler.processAlert(context.TODO(), testAlert, testconst.TestManagedNotificationList, true)
 				Expect(err).Should(HaveOccurred())
 			})
 		})
@@ -260,7 +303,7 @@ var _ = Describe("Webhook Handlers", func() {
 			})
 			It("Reports failure if cannot fetch notification for a valid alert", func() {
 				testManagedNotificationList = &ocmagentv1alpha1.ManagedNotificationList{}
-				err := webhookReceiverHandler.processAlert(testAlert, testManagedNotificationList, true)
+				err := webhookReceiverHandler.processAlert(context.TODO(), testAlert, testManagedNotificationList, true)
 				Expect(err).ToNot(BeNil())
 			})
 		})
@@ -292,7 +335,71 @@ var _ = Describe("Webhook Handlers", func() {
 						},
 					},
 				}
-				err := webhookReceiverHandler.processAlert(testAlert, testManagedNotificationList, true)
+				err := webhookReceiverHandler.processAlert(context.TODO(), testAlert, testManagedNotificationList, true)
+				Expect(err).ShouldNot(HaveOccurred())
+			})
+			It("Should decide from the status read from the API server rather than from the cache", func() {
+				cached := ocmagentv1alpha1.ManagedNotification{
+					ObjectMeta: metav1.ObjectMeta{Name: "test-mn", Namespace: OCMAgentNamespaceName},
+					Spec: ocmagentv1alpha1.ManagedNotificationSpec{
+						Notifications: []ocmagentv1alpha1.Notification{testconst.TestNotification},
+					},
+				}
+				// The service log was just sent, the cache didn't see the status update yet
+				current := *cached.DeepCopy()
+				current.Status.NotificationRecords = ocmagentv1alpha1.NotificationRecords{{
+					Name: testconst.TestNotificationName,
+					Conditions: []ocmagentv1alpha1.NotificationCondition{{
+						Type:               ocmagentv1alpha1.ConditionServiceLogSent,
+						Status:             corev1.ConditionTrue,
+						LastTransitionTime: &metav1.Time{Time: time.Now()},
+					}},
+				}}
+				webhookReceiverHandler.WithCache(clientmocks.NewMockClient(mockCtrl))
+				mockClient.EXPECT().Get(gomock.Any(), client.ObjectKeyFromObject(&cached), gomock.Any()).Return(nil).SetArg(2, current)
+
+				mnl := &ocmagentv1alpha1.ManagedNotificationList{Items: []ocmagentv1alpha1.ManagedNotification{cached}}
+				err := webhookReceiverHandler.processAlert(context.TODO(), testAlert, mnl, true)
+				Expect(err).ShouldNot(HaveOccurred())
+			})
+			It("Should not send service log for an alert instance it was already delivered for", func() {
+				testAlertResolved.Fingerprint = "abc123"
+				record := ocmagentv1alpha1.NotificationRecord{
+					Name:                testconst.TestNotificationName,
+					ServiceLogSentCount: 1,
+					Conditions: []ocmagentv1alpha1.NotificationCondition{
+						{
+							Type:               ocmagentv1alpha1.ConditionAlertFiring,
+							Status:             corev1.ConditionTrue,
+							LastTransitionTime: &metav1.Time{Time: time.Now()},
+						},
+						{
+							Type:               ocmagentv1alpha1.ConditionAlertResolved,
+							Status:             corev1.ConditionFalse,
+							LastTransitionTime: &metav1.Time{Time: time.Now()},
+						},
+					},
+				}
+				mn := ocmagentv1alpha1.ManagedNotification{
+					Spec: ocmagentv1alpha1.ManagedNotificationSpec{
+						Notifications: []ocmagentv1alpha1.Notification{
+							testconst.TestNotification,
+						},
+					},
+					Status: ocmagentv1alpha1.ManagedNotificationStatus{
+						NotificationRecords: ocmagentv1alpha1.NotificationRecords{record},
+					},
+				}
+				Expect(recordDelivery(&mn, testconst.TestNotificationName, false, deliveredAlert{
+					Fingerprint: testAlertResolved.Fingerprint,
+					StartsAt:    testAlertResolved.StartsAt,
+					SentAt:      time.Now(),
+				})).To(Succeed())
+				testManagedNotificationList = &ocmagentv1alpha1.ManagedNotificationList{
+					Items: []ocmagentv1alpha1.ManagedNotification{mn},
+				}
+				// Neither the service log nor the status are expected to be updated
+				err := webhookReceiverHandler.processAlert(context.TODO(), testAlertResolved, testManagedNotificationList, false)
 				Expect(err).ShouldNot(HaveOccurred())
 			})
 			It("Should send service log for a firing alert if one hasn't already sent after resend time and update notification", func() {
@@ -352,15 +459,35 @@ var _ = Describe("Webhook Handlers", func() {
 					},
 				}
 				gomock.InOrder(
-					//mockHTTPChecker.EXPECT().UrlAvailabilityCheck(gomock.Any().String()).Return(nil).Times(5),
-					mockOCMClient.EXPECT().SendServiceLog(activeServiceLog).Return(nil),
+					mockOCMClient.EXPECT().SendServiceLog(gomock.Any(), activeServiceLog).Return("", nil),
 					mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).SetArg(2, testManagedNotificationList.Items[0]),
 					mockClient.EXPECT().Status().Return(mockStatusWriter),
 					mockStatusWriter.EXPECT().Update(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil),
 				)
-				err := webhookReceiverHandler.processAlert(alerttest, testManagedNotificationList, true)
+				err := webhookReceiverHandler.processAlert(context.TODO(), alerttest, testManagedNotificationList, true)
 				Expect(err).ShouldNot(HaveOccurred())
 			})
+			It("Should not send service log while OCM is unavailable", func() {
+				testManagedNotificationList = &ocmagentv1alpha1.ManagedNotificationList{
+					Items: []ocmagentv1alpha1.ManagedNotification{
+						{
+							Spec: ocmagentv1alpha1.ManagedNotificationSpec{
+								Notifications: []ocmagentv1alpha1.Notification{
+									testconst.TestNotification,
+								},
+							},
+						},
+					},
+				}
+				health := httpchecker.NewHealthMonitor(mockHTTPChecker, "https://api.openshift.com", time.Hour)
+				for i := 0; i < httpchecker.HealthFailureThreshold; i++ {
+					health.Observe(fmt.Errorf("failed to connect"))
+				}
+				webhookReceiverHandler.WithHealthMonitor(health)
+				err := webhookReceiverHandler.processAlert(context.TODO(), testAlert, testManagedNotificationList, true)
+				Expect(err).Should(MatchError(ContainSubstring("OCM is unavailable")))
+				Expect(isRetriable(err)).To(BeTrue())
+			})
 			It("Should not send service log for a firing alert if some place holder cannot be resolved with an alert label or annotation", func() {
 				testAlert.Labels = nil
 				testManagedNotificationList = &ocmagentv1alpha1.ManagedNotificationList{
@@ -399,7 +526,7 @@ var _ = Describe("Webhook Handlers", func() {
 						},
 					},
 				}
-				err := webhookReceiverHandler.processAlert(testAlert, testManagedNotificationList, true)
+				err := webhookReceiverHandler.processAlert(context.TODO(), testAlert, testManagedNotificationList, true)
 				Expect(err).Should(HaveOccurred())
 			})
 			It("Should not send servicelog if the alert was not in firing state and is resolved", func() {
@@ -439,7 +566,7 @@ var _ = Describe("Webhook Handlers", func() {
 						},
 					},
 				}
-				err := webhookReceiverHandler.processAlert(testAlert, testManagedNotificationList, false)
+				err := webhookReceiverHandler.processAlert(context.TODO(), testAlert, testManagedNotificationList, false)
 				Expect(err).ShouldNot(HaveOccurred())
 			})
 			It("Should send servicelog if the alert was in firing state and is resolved", func() {
@@ -480,12 +607,12 @@ var _ = Describe("Webhook Handlers", func() {
 					},
 				}
 				gomock.InOrder(
-					mockOCMClient.EXPECT().SendServiceLog(resolvedServiceLog).Return(nil),
+					mockOCMClient.EXPECT().SendServiceLog(gomock.Any(), resolvedServiceLog).Return("", nil),
 					mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).SetArg(2, testManagedNotificationList.Items[0]),
 					mockClient.EXPECT().Status().Return(mockStatusWriter),
 					mockStatusWriter.EXPECT().Update(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil),
 				)
-				err := webhookReceiverHandler.processAlert(testAlertResolved, testManagedNotificationList, false)
+				err := webhookReceiverHandler.processAlert(context.TODO(), testAlertResolved, testManagedNotificationList, false)
 				Expect(err).ShouldNot(HaveOccurred())
 			})
 			It("Should not send resolved servicelog if the resolved body is empty", func() {
@@ -530,7 +657,7 @@ var _ = Describe("Webhook Handlers", func() {
 					mockClient.EXPECT().Status().Return(mockStatusWriter),
 					mockStatusWriter.EXPECT().Update(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil),
 				)
-				err := webhookReceiverHandler.processAlert(testAlertResolved, testManagedNotificationList, false)
+				err := webhookReceiverHandler.processAlert(context.TODO(), testAlertResolved, testManagedNotificationList, false)
 				Expect(err).ShouldNot(HaveOccurred())
 			})
 			It("Should report error if not able to send service log", func() {
@@ -590,12 +717,12 @@ var _ = Describe("Webhook Handlers", func() {
 					},
 				}
 				gomock.InOrder(
-					mockOCMClient.EXPECT().SendServiceLog(activeServiceLog).Return(k8serrs.NewInternalError(fmt.Errorf("a fake error"))),
+					mockOCMClient.EXPECT().SendServiceLog(gomock.Any(), activeServiceLog).Return("", k8serrs.NewInternalError(fmt.Errorf("a fake error"))),
 					mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).SetArg(2, testManagedNotificationList.Items[0]),
 					mockClient.EXPECT().Status().Return(mockStatusWriter),
 					mockStatusWriter.EXPECT().Update(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil),
 				)
-				err := webhookReceiverHandler.processAlert(alerttest, testManagedNotificationList, true)
+				err := webhookReceiverHandler.processAlert(context.TODO(), alerttest, testManagedNotificationList, true)
 				Expect(err).Should(HaveOccurred())
 			})
 			It("Should report error if not able to update NotificationStatus", func() {
@@ -636,24 +763,204 @@ var _ = Describe("Webhook Handlers", func() {
 					},
 				}
 				gomock.InOrder(
-					mockOCMClient.EXPECT().SendServiceLog(activeServiceLog).Return(nil),
+					mockOCMClient.EXPECT().SendServiceLog(gomock.Any(), activeServiceLog).Return("", nil),
 					mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).SetArg(2, testManagedNotificationList.Items[0]),
 					mockClient.EXPECT().Status().Return(mockStatusWriter),
 					mockStatusWriter.EXPECT().Update(gomock.Any(), gomock.Any(), gomock.Any()).Return(k8serrs.NewInternalError(fmt.Errorf("a fake error"))),
 				)
-				err := webhookReceiverHandler.processAlert(testAlert, testManagedNotificationList, true)
+				err := webhookReceiverHandler.processAlert(context.TODO(), testAlert, testManagedNotificationList, true)
 				Expect(err).Should(HaveOccurred())
 			})
 		})
 	})
 
+	Context("When processing an alert of a limited support notification", func() {
+		var mn ocmagentv1alpha1.ManagedNotification
+
+		// setStatus records the notification status of the alert firing or resolved after a limited support was set
+		setStatus := func(firing bool) {
+			alertFiring, alertResolved := corev1.ConditionFalse, corev1.ConditionTrue
+			if firing {
+				alertFiring, alertResolved = corev1.ConditionTrue, corev1.ConditionFalse
+			}
+			mn.Status.NotificationRecords = ocmagentv1alpha1.NotificationRecords{
+				{
+					Name: testconst.TestNotificationName,
+					Conditions: []ocmagentv1alpha1.NotificationCondition{
+						{Type: ocmagentv1alpha1.ConditionAlertFiring, Status: alertFiring, LastTransitionTime: &metav1.Time{Time: time.Now()}},
+						{Type: ocmagentv1alpha1.ConditionAlertResolved, Status: alertResolved, LastTransitionTime: &metav1.Time{Time: time.Now()}},
+						{Type: ocmagentv1alpha1.ConditionServiceLogSent, Status: corev1.ConditionTrue, LastTransitionTime: &metav1.Time{Time: time.Now().Add(-48 * time.Hour)}},
+					},
+				},
+			}
+			testManagedNotificationList = &ocmagentv1alpha1.ManagedNotificationList{Items: []ocmagentv1alpha1.ManagedNotification{mn}}
+		}
+
+		// setReasons records the IDs of the limited support reasons posted for the notification
+		setReasons := func(ids ...string) {
+			mn.Annotations[consts.LimitedSupportReasonsAnnotation] = fmt.Sprintf(`{"%s":["%s"]}`, testconst.TestNotificationName, strings.Join(ids, `","`))
+			testManagedNotificationList = &ocmagentv1alpha1.ManagedNotificationList{Items: []ocmagentv1alpha1.ManagedNotification{mn}}
+		}
+
+		// expectReasons expects the IDs of the limited support reasons recorded for the notification to be updated
+		expectReasons := func(ids ...string) *gomock.Call {
+			return mockClient.EXPECT().Patch(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ context.Context, obj client.Object, _ client.Patch, _ ...client.PatchOption) error {
+					recorded, known := limitedSupportReasons(obj)[testconst.TestNotificationName]
+					if len(ids) == 0 {
+						Expect(known).To(BeFalse())
+					} else {
+						Expect(recorded).To(Equal(ids))
+					}
+					return nil
+				})
+		}
+
+		BeforeEach(func() {
+			mn = ocmagentv1alpha1.ManagedNotification{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{consts.LimitedSupportAnnotation: "other-notification, " + testconst.TestNotificationName},
+				},
+				Spec: ocmagentv1alpha1.ManagedNotificationSpec{
+					Notifications: []ocmagentv1alpha1.Notification{testconst.TestNotification},
+				},
+			}
+			setStatus(false)
+		})
+
+		It("Should place the cluster into limited support when the alert fires", func() {
+			posted, _ := cmv1.NewLimitedSupportReason().ID("posted").Build()
+			gomock.InOrder(
+				mockOCMClient.EXPECT().SendLimitedSupport(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+					func(_ context.Context, _ string, reason *cmv1.LimitedSupportReason) (*cmv1.LimitedSupportReason, string, error) {
+						Expect(reason.Summary()).To(Equal(testconst.TestNotification.Summary))
+						Expect(reason.Details()).To(Equal(testconst.TestNotification.ActiveDesc))
+						return posted, "", nil
+					}),
+				mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).SetArg(2, mn),
+				expectReasons("posted"),
+				mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).SetArg(2, mn),
+				mockClient.EXPECT().Status().Return(mockStatusWriter),
+				mockStatusWriter.EXPECT().Update(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil),
+			)
+			err := webhookReceiverHandler.processAlert(context.TODO(), testAlert, testManagedNotificationList, true)
+			Expect(err).ShouldNot(HaveOccurred())
+		})
+
+		It("Should not place the cluster into limited support again while the previous one is outstanding", func() {
+			setStatus(true)
+			setReasons("posted")
+			mockOCMClient.EXPECT().SendLimitedSupport(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			err := webhookReceiverHandler.processAlert(context.TODO(), testAlert, testManagedNotificationList, true)
+			Expect(err).ShouldNot(HaveOccurred())
+		})
+
+		It("Should not place the cluster into limited support again while a limited support set before the IDs were recorded is outstanding", func() {
+			setStatus(true)
+			mockOCMClient.EXPECT().SendLimitedSupport(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			err := webhookReceiverHandler.processAlert(context.TODO(), testAlert, testManagedNotificationList, true)
+			Expect(err).ShouldNot(HaveOccurred())
+		})
+
+		It("Should only remove the limited support reasons posted for the notification when the alert resolves", func() {
+			setStatus(true)
+			setReasons("posted")
+			posted, _ := cmv1.NewLimitedSupportReason().ID("posted").Details(testconst.TestNotification.ActiveDesc).Build()
+			manual, _ := cmv1.NewLimitedSupportReason().ID("manual").Summary(testconst.TestNotification.Summary).Details(testconst.TestNotification.ActiveDesc).DetectionType(cmv1.DetectionTypeManual).Build()
+			gomock.InOrder(
+				mockOCMClient.EXPECT().GetLimitedSupportReasons(gomock.Any(), gomock.Any()).Return([]*cmv1.LimitedSupportReason{posted, manual}, nil),
+				mockOCMClient.EXPECT().RemoveLimitedSupport(gomock.Any(), gomock.Any(), "posted").Return("", nil),
+				mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).SetArg(2, mn),
+				expectReasons(),
+				mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).SetArg(2, mn),
+				mockClient.EXPECT().Status().Return(mockStatusWriter),
+				mockStatusWriter.EXPECT().Update(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+					func(_ context.Context, obj client.Object, _ ...client.SubResourceUpdateOption) error {
+						record, err := obj.(*ocmagentv1alpha1.ManagedNotification).Status.GetNotificationRecord(testconst.TestNotificationName)
+						Expect(err).NotTo(HaveOccurred())
+						Expect(record.Conditions.GetCondition(ocmagentv1alpha1.ConditionAlertFiring).Status).To(Equal(corev1.ConditionFalse))
+						Expect(record.Conditions.GetCondition(ocmagentv1alpha1.ConditionAlertResolved).Status).To(Equal(corev1.ConditionTrue))
+						return nil
+					}),
+			)
+			err := webhookReceiverHandler.processAlert(context.TODO(), testAlertResolved, testManagedNotificationList, false)
+			Expect(err).ShouldNot(HaveOccurred())
+		})
+
+		It("Should only remove the limited support reason identical to the notification one when its ID wasn't recorded", func() {
+			setStatus(true)
+			matching, _ := cmv1.NewLimitedSupportReason().ID("matching").Summary(testconst.TestNotification.Summary).Details(testconst.TestNotification.ActiveDesc).DetectionType(cmv1.DetectionTypeManual).Build()
+			other, _ := cmv1.NewLimitedSupportReason().ID("other").Summary(testconst.TestNotification.Summary).Details(testconst.TestNotification.ActiveDesc + " and more").DetectionType(cmv1.DetectionTypeManual).Build()
+			gomock.InOrder(
+				mockOCMClient.EXPECT().GetLimitedSupportReasons(gomock.Any(), gomock.Any()).Return([]*cmv1.LimitedSupportReason{matching, other}, nil),
+				mockOCMClient.EXPECT().RemoveLimitedSupport(gomock.Any(), gomock.Any(), "matching").Return("", nil),
+				mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).SetArg(2, mn),
+				expectReasons(),
+				mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).SetArg(2, mn),
+				mockClient.EXPECT().Status().Return(mockStatusWriter),
+				mockStatusWriter.EXPECT().Update(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil),
+			)
+			err := webhookReceiverHandler.processAlert(context.TODO(), testAlertResolved, testManagedNotificationList, false)
+			Expect(err).ShouldNot(HaveOccurred())
+		})
+
+		It("Should not remove any limited support reason when none is outstanding", func() {
+			mockOCMClient.EXPECT().GetLimitedSupportReasons(gomock.Any(), gomock.Any()).Times(0)
+			// Updating the status already resolved would record the alert firing again
+			mockClient.EXPECT().Status().Times(0)
+			mockStatusWriter.EXPECT().Update(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			err := webhookReceiverHandler.processAlert(context.TODO(), testAlertResolved, testManagedNotificationList, false)
+			Expect(err).ShouldNot(HaveOccurred())
+		})
+
+		It("Should not record the alert firing when it resolves without a notification status", func() {
+			mn.Status.NotificationRecords = nil
+			testManagedNotificationList = &ocmagentv1alpha1.ManagedNotificationList{Items: []ocmagentv1alpha1.ManagedNotification{mn}}
+			mockOCMClient.EXPECT().GetLimitedSupportReasons(gomock.Any(), gomock.Any()).Times(0)
+			mockClient.EXPECT().Status().Times(0)
+			mockStatusWriter.EXPECT().Update(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			err := webhookReceiverHandler.processAlert(context.TODO(), testAlertResolved, testManagedNotificationList, false)
+			Expect(err).ShouldNot(HaveOccurred())
+		})
+
+		It("Should ask for a redelivery when the limited support can't be set", func() {
+			gomock.InOrder(
+				mockOCMClient.EXPECT().SendLimitedSupport(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, "", fmt.Errorf("OCM unavailable")),
+				mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).SetArg(2, mn),
+				mockClient.EXPECT().Status().Return(mockStatusWriter),
+				mockStatusWriter.EXPECT().Update(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil),
+			)
+			err := webhookReceiverHandler.processAlert(context.TODO(), testAlert, testManagedNotificationList, true)
+			Expect(err).Should(MatchError(ContainSubstring("OCM unavailable")))
+			Expect(isRetriable(err)).To(BeTrue())
+		})
+
+		It("Should not ask for a redelivery when OCM rejects the limited support", func() {
+			gomock.InOrder(
+				mockOCMClient.EXPECT().SendLimitedSupport(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, "", &ocm.ForbiddenError{ResponseError: &ocm.ResponseError{Operation: ocm.OperationSendLimitedSupport, Status: http.StatusForbidden}}),
+				mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).SetArg(2, mn),
+				mockClient.EXPECT().Status().Return(mockStatusWriter),
+				mockStatusWriter.EXPECT().Update(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil),
+			)
+			err := webhookReceiverHandler.processAlert(context.TODO(), testAlert, testManagedNotificationList, true)
+			Expect(err).Should(HaveOccurred())
+			Expect(isRetriable(err)).To(BeFalse())
+		})
+
+		It("Should only handle the notifications listed by the annotation", func() {
+			Expect(isLimitedSupport(&mn, testconst.TestNotificationName)).To(BeTrue())
+			Expect(isLimitedSupport(&mn, "another-notification")).To(BeFalse())
+			Expect(isLimitedSupport(&ocmagentv1alpha1.ManagedNotification{}, testconst.TestNotificationName)).To(BeFalse())
+		})
+	})
+
 	Context("When updating Notification status", func() {
 		It("Report error if not able to get ManagedNotification", func() {
 			fakeError := k8serrs.NewInternalError(fmt.Errorf("a fake error"))
 			gomock.InOrder(
 				mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(fakeError),
 			)
-			_, err := webhookReceiverHandler.updateNotificationStatus(&testconst.TestNotification, &testconst.TestManagedNotification, true, corev1.ConditionTrue)
+			_, err := webhookReceiverHandler.updateNotificationStatus(context.TODO(), &testconst.TestNotification, &testconst.TestManagedNotification, true, testAlert, corev1.ConditionTrue)
 			Expect(err).ShouldNot(BeNil())
 		})
 		When("Getting NotificationRecord for which status does not exist", func() {
@@ -669,7 +976,7 @@ var _ = Describe("Webhook Handlers", func() {
 							return nil
 						}),
 				)
-				_, err := webhookReceiverHandler.updateNotificationStatus(&ocmagentv1alpha1.Notification{Name: "randomnotification"}, &testconst.TestManagedNotificationWithoutStatus, true, corev1.ConditionTrue)
+				_, err := webhookReceiverHandler.updateNotificationStatus(context.TODO(), &ocmagentv1alpha1.Notification{Name: "randomnotification"}, &testconst.TestManagedNotificationWithoutStatus, true, testAlert, corev1.ConditionTrue)
 				Expect(err).Should(BeNil())
 				Expect(&testconst.TestManagedNotificationWithoutStatus).ToNot(BeNil())
 			})
@@ -687,7 +994,7 @@ var _ = Describe("Webhook Handlers", func() {
 							return nil
 						}),
 				)
-				_, err := webhookReceiverHandler.updateNotificationStatus(&testconst.TestNotification, &testconst.TestManagedNotification, true, corev1.ConditionTrue)
+				_, err := webhookReceiverHandler.updateNotificationStatus(context.TODO(), &testconst.TestNotification, &testconst.TestManagedNotification, true, testAlert, corev1.ConditionTrue)
 				Expect(err).Should(BeNil())
 			})
 			It("should send service log for alert resolved when no longer firing", func() {
@@ -702,7 +1009,7 @@ var _ = Describe("Webhook Handlers", func() {
 							return nil
 						}),
 				)
-				_, err := webhookReceiverHandler.updateNotificationStatus(&testconst.TestNotification, &testconst.TestManagedNotification, false, corev1.ConditionTrue)
+				_, err := webhookReceiverHandler.updateNotificationStatus(context.TODO(), &testconst.TestNotification, &testconst.TestManagedNotification, false, testAlert, corev1.ConditionTrue)
 				Expect(err).Should(BeNil())
 			})
 		})
@@ -712,36 +1019,9 @@ var _ = Describe("Webhook Handlers", func() {
 				mockClient.EXPECT().Status().Return(mockStatusWriter),
 				mockStatusWriter.EXPECT().Update(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil),
 			)
-			_, err := webhookReceiverHandler.updateNotificationStatus(&testconst.TestNotification, &testconst.TestManagedNotification, true, corev1.ConditionTrue)
+			_, err := webhookReceiverHandler.updateNotificationStatus(context.TODO(), &testconst.TestNotification, &testconst.TestManagedNotification, true, testAlert, corev1.ConditionTrue)
 			Expect(err).Should(BeNil())
 		})
 	})
 
-	Context("Checking the response from OCM", func() {
-		var testOperationId = "test"
-		var testResponseBody = "{\"reason\": \"test\"}"
-
-		It("will treat 201 as a successful response", func() {
-			err := responseChecker(testOperationId, http.StatusCreated, []byte(testResponseBody))
-			Expect(err).To(BeNil())
-		})
-		It("should return an error for non-JSON input", func() {
-			testResponseBody := []byte(`This is not JSON data.`)
-			err := responseChecker(testOperationId, http.StatusMovedPermanently, []byte(testResponseBody))
-			Expect(err).Should(HaveOccurred())
-		})
-		It("will treat all other responses as failures", func() {
-			var testFailedResponseCodes = []int{
-				http.StatusForbidden,
-				http.StatusBadRequest,
-				http.StatusUnauthorized,
-				http.StatusInternalServerError,
-				http.StatusOK,
-			}
-			for _, code := range testFailedResponseCodes {
-				err := responseChecker(testOperationId, code, []byte(testResponseBody))
-				Expect(err).NotTo(BeNil())
-			}
-		})
-	})
 })
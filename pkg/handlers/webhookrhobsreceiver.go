@@ -5,7 +5,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"strings"
 	"time"
 
 	"github.com/prometheus/alertmanager/template"
@@ -15,14 +14,17 @@ import (
 	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
 	oav1alpha1 "github.com/openshift/ocm-agent-operator/api/v1alpha1"
 	"k8s.io/apimachinery/pkg/api/errors"
-	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/util/wait"
-	"k8s.io/client-go/util/retry"
 
+	"github.com/openshift/ocm-agent/pkg/backoff"
 	"github.com/openshift/ocm-agent/pkg/config"
 	"github.com/openshift/ocm-agent/pkg/consts"
+	"github.com/openshift/ocm-agent/pkg/events"
+	"github.com/openshift/ocm-agent/pkg/httpchecker"
 	"github.com/openshift/ocm-agent/pkg/metrics"
 	"github.com/openshift/ocm-agent/pkg/ocm"
+	"github.com/openshift/ocm-agent/pkg/outbox"
+	"github.com/openshift/ocm-agent/pkg/queue"
+	"github.com/openshift/ocm-agent/pkg/ratelimit"
 
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -34,28 +36,117 @@ const (
 var (
 	// We need a solid backoff duration and jitter as we expect a lot of webhooks
 	// to be executed at the exact same time when an alert initially is created.
-	retryConfig = wait.Backoff{
-		Steps:    5,
-		Duration: 2 * time.Second,
-		Factor:   1.0,
-		Jitter:   5,
+	retryConfig = backoff.Config{
+		InitialInterval: 2 * time.Second,
+		Multiplier:      2,
+		MaxInterval:     10 * time.Second,
+		MaxAttempts:     5,
 	}
 
 	customIs409 = func(err error) bool { return errors.IsConflict(err) || errors.IsAlreadyExists(err) }
 )
 
 type WebhookRHOBSReceiverHandler struct {
-	c   client.Client
-	ocm ocm.OCMClient
+	c        client.Client
+	ocm      ocm.OCMClient
+	clusters *ocm.ClusterCache
+	queue    *queue.Queue
+	dryRun   dryRunConfig
+	limiter  *ratelimit.Limiter
+	health   *httpchecker.HealthMonitor
+	outbox   *outbox.Outbox
+	cache    client.Reader
+	events   *events.Recorder
+	// shardRecords keeps a ManagedFleetNotificationRecord per hosted cluster, see WithRecordLayout
+	shardRecords bool
 }
 
 func NewWebhookRHOBSReceiverHandler(c client.Client, o ocm.OCMClient) *WebhookRHOBSReceiverHandler {
 	return &WebhookRHOBSReceiverHandler{
-		c:   c,
-		ocm: o,
+		c:        c,
+		ocm:      o,
+		clusters: ocm.NewClusterCache(o, ocm.DefaultClusterCacheTTL),
 	}
 }
 
+// WithQueue makes the handler acknowledge alerts as soon as they are queued, the alerts are then
+// processed by the queue workers through ProcessQueuedItem
+func (h *WebhookRHOBSReceiverHandler) WithQueue(q *queue.Queue) *WebhookRHOBSReceiverHandler {
+	h.queue = q
+	return h
+}
+
+// WithDryRun records the notifications in the recorder instead of sending them, for all the notifications
+// if all is set or only for the ManagedFleetNotifications annotated with the dry-run annotation otherwise
+func (h *WebhookRHOBSReceiverHandler) WithDryRun(recorder *ocm.DryRunRecorder, all bool) *WebhookRHOBSReceiverHandler {
+	h.dryRun = dryRunConfig{ocm: ocm.NewDryRunClient(h.ocm, recorder), all: all}
+	return h
+}
+
+// WithRateLimiter throttles the notifications sent to OCM with the limiter
+func (h *WebhookRHOBSReceiverHandler) WithRateLimiter(limiter *ratelimit.Limiter) *WebhookRHOBSReceiverHandler {
+	h.limiter = limiter
+	return h
+}
+
+// WithHealthMonitor makes the handler fail the alerts as retriable without sending them while OCM is unavailable
+func (h *WebhookRHOBSReceiverHandler) WithHealthMonitor(health *httpchecker.HealthMonitor) *WebhookRHOBSReceiverHandler {
+	h.health = health
+	return h
+}
+
+// WithOutbox makes the handler add the notifications which couldn't be sent to OCM to the outbox, they are
+// replayed through ReplayOutboxEntry
+func (h *WebhookRHOBSReceiverHandler) WithOutbox(o *outbox.Outbox) *WebhookRHOBSReceiverHandler {
+	h.outbox = o
+	return h
+}
+
+// WithCache makes the handler read the ManagedFleetNotifications from the cache rather than from the API server. Their
+// records are still read from the API server, as whether a notification was already sent is decided from them and the
+// cache trails the agent's own writes.
+func (h *WebhookRHOBSReceiverHandler) WithCache(r client.Reader) *WebhookRHOBSReceiverHandler {
+	h.cache = r
+	return h
+}
+
+// WithEvents makes the handler emit the events of the notifications on their ManagedFleetNotification
+func (h *WebhookRHOBSReceiverHandler) WithEvents(r *events.Recorder) *WebhookRHOBSReceiverHandler {
+	h.events = r
+	return h
+}
+
+// reader returns the reader of the notifications which don't have to be read from the API server
+func (h *WebhookRHOBSReceiverHandler) reader() client.Reader {
+	if h.cache != nil {
+		return h.cache
+	}
+	return h.c
+}
+
+// ReplayOutboxEntry processes the alert of an outbox entry again, the entry is kept in the outbox when it fails
+// for a transient reason
+func (h *WebhookRHOBSReceiverHandler) ReplayOutboxEntry(ctx context.Context, entry outbox.Entry) error {
+	// The alert mustn't be added back to the outbox, nor wait on its own entry
+	replayer := *h
+	replayer.outbox = nil
+	return replayResult(entry, replayer.processAlertData(ctx, entry.Alert))
+}
+
+// ProcessQueuedItem processes the alert data held by an item of the queue
+func (h *WebhookRHOBSReceiverHandler) ProcessQueuedItem(ctx context.Context, item queue.Item) error {
+	d, err := decodeQueuedItem(item)
+	if err != nil {
+		return err
+	}
+	return queuedResponseError(h.processAMReceiver(d, ctx))
+}
+
+// queueKey keeps the alerts of the same notification template for the same hosted cluster in order
+func (h *WebhookRHOBSReceiverHandler) queueKey(alert template.Alert) string {
+	return alert.Labels[AMLabelTemplateName] + "/" + alert.Labels[AMLabelAlertHCID]
+}
+
 func (h *WebhookRHOBSReceiverHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// validate request
 	if r != nil && r.Method != http.MethodPost {
@@ -73,7 +164,12 @@ func (h *WebhookRHOBSReceiverHandler) ServeHTTP(w http.ResponseWriter, r *http.R
 	}
 
 	// process request
-	response := h.processAMReceiver(alertData, r.Context())
+	var response *AMReceiverResponse
+	if h.queue != nil {
+		response = enqueueAMReceiver(h.queue, alertData, h.queueKey)
+	} else {
+		response = h.processAMReceiver(alertData, r.Context())
+	}
 
 	// write response
 	w.Header().Set("Content-Type", "application/json")
@@ -92,40 +188,66 @@ func (h *WebhookRHOBSReceiverHandler) ServeHTTP(w http.ResponseWriter, r *http.R
 func (h *WebhookRHOBSReceiverHandler) processAMReceiver(d AMReceiverData, ctx context.Context) *AMReceiverResponse {
 	log.WithField("AMReceiverData", fmt.Sprintf("%+v", d)).Info("Process alert data")
 
+	results := make([]AMReceiverAlertResult, 0, len(d.Alerts))
 	for _, alert := range d.Alerts {
-		// Can we find a notification template for this alert?
-		templateName := alert.Labels[AMLabelTemplateName]
-		mfn := &oav1alpha1.ManagedFleetNotification{}
-		err := h.c.Get(ctx, client.ObjectKey{
-			Namespace: OCMAgentNamespaceName,
-			Name:      templateName,
-		}, mfn)
+		err := h.processAlertData(ctx, alert)
 		if err != nil {
-			log.WithError(err).Error("unable to locate corresponding notification template")
-			return &AMReceiverResponse{Error: err,
-				Status: fmt.Sprintf("unable to find ManagedFleetNotification %s", templateName),
-				Code:   http.StatusInternalServerError}
+			log.WithError(err).Error("failed processing alert")
 		}
+		results = append(results, alertResult(alert, err))
+	}
 
-		// Filter actionable alert based on Label
-		if !isValidAlert(alert, true) {
-			log.WithField(LogFieldAlert, fmt.Sprintf("%+v", alert)).Info("alert does not meet valid criteria")
-			continue
-		}
+	return newAMReceiverResponse(results)
+}
 
-		err = h.processAlert(alert, mfn)
-		if err != nil {
-			log.WithError(err).Error("failed processing alert")
-		}
+// processAlertData looks up the notification template of a single alert and processes it
+func (h *WebhookRHOBSReceiverHandler) processAlertData(ctx context.Context, alert template.Alert) error {
+	// Filter actionable alert based on Label
+	if !isValidAlert(alert, true) {
+		log.WithField(LogFieldAlert, fmt.Sprintf("%+v", alert)).Info("alert does not meet valid criteria")
+		return errInvalidAlert
+	}
+
+	// Can we find a notification template for this alert?
+	templateName := alert.Labels[AMLabelTemplateName]
+	mfn := &oav1alpha1.ManagedFleetNotification{}
+	err := h.reader().Get(ctx, client.ObjectKey{
+		Namespace: OCMAgentNamespaceName,
+		Name:      templateName,
+	}, mfn)
+	if err != nil {
+		log.WithError(err).Error("unable to locate corresponding notification template")
+		return fmt.Errorf("unable to find ManagedFleetNotification %s: %w", templateName, err)
 	}
 
-	return &AMReceiverResponse{Error: nil, Status: "ok", Code: http.StatusOK}
+	return h.processAlert(ctx, alert, mfn)
 }
 
-func (h *WebhookRHOBSReceiverHandler) processAlert(alert template.Alert, mfn *oav1alpha1.ManagedFleetNotification) error {
+func (h *WebhookRHOBSReceiverHandler) processAlert(ctx context.Context, alert template.Alert, mfn *oav1alpha1.ManagedFleetNotification) error {
+	fn := mfn.Spec.FleetNotification
+	hcID := alert.Labels[AMLabelAlertHCID]
+	firing := alert.Status == string(model.AlertFiring)
+	_, dryRun := h.dryRun.clientFor(mfn, h.ocm)
+
+	// The firing notification waiting to be replayed is obsolete once the alert resolved
+	if !firing {
+		dropResolvedFromOutbox(ctx, h.outbox, hcID, fn.Name, alert)
+	}
+	entry := outbox.Entry{Cluster: hcID, Operation: fleetOperation(fn, firing), Notification: fn.Name, Alert: alert}
+
+	// The notifications of the cluster are sent in order, after the ones waiting in the outbox
+	if !dryRun && h.outbox.Pending(hcID) {
+		return deferToOutbox(ctx, h.outbox, entry, retriable(errOutboxPending))
+	}
+
+	if err := h.health.Available(); err != nil {
+		// OCM being unreachable is transient, the alert should be delivered again
+		return deferToOutbox(ctx, h.outbox, entry, retriable(fmt.Errorf("OCM is unavailable: %w", err)))
+	}
+
 	// Handle firing alerts
 	if alert.Status == string(model.AlertFiring) {
-		err := h.processFiringAlert(alert, mfn)
+		err := h.processFiringAlert(ctx, alert, mfn)
 		if err != nil {
 			return fmt.Errorf("a firing alert could not be successfully processed %w", err)
 		}
@@ -134,7 +256,7 @@ func (h *WebhookRHOBSReceiverHandler) processAlert(alert template.Alert, mfn *oa
 
 	// Handle resolving alerts
 	if alert.Status == string(model.AlertResolved) {
-		err := h.processResolvedAlert(alert, mfn)
+		err := h.processResolvedAlert(ctx, alert, mfn)
 		if err != nil {
 			return fmt.Errorf("a resolving alert could not be successfully processed %w", err)
 		}
@@ -144,122 +266,225 @@ func (h *WebhookRHOBSReceiverHandler) processAlert(alert template.Alert, mfn *oa
 	return fmt.Errorf("unable to process alert: unexpected status %s", alert.Status)
 }
 
-// processResolvedAlert handles resolve notifications for a particular alert
-// currently only handles removing limited support
-func (h *WebhookRHOBSReceiverHandler) processResolvedAlert(alert template.Alert, mfn *oav1alpha1.ManagedFleetNotification) error {
-	// MFN is not for limited support, thus we don't have an implementation for the alert resolving state yet
+// processResolvedAlert handles resolve notifications for a particular alert, removing the limited support or
+// sending the resolved service log of the notification
+func (h *WebhookRHOBSReceiverHandler) processResolvedAlert(ctx context.Context, alert template.Alert, mfn *oav1alpha1.ManagedFleetNotification) error {
 	if !mfn.Spec.FleetNotification.LimitedSupport {
-		return nil
+		// Without a resolved message there's nothing to send
+		if mfn.Annotations[consts.ResolvedMessageAnnotation] == "" {
+			return nil
+		}
+		if !h.resolvedCanBeSent(ctx, alert, mfn) {
+			log.WithFields(log.Fields{LogFieldNotificationName: mfn.Spec.FleetNotification.Name}).Info("not sending a resolve notification if it was not firing or was already resolved")
+			return nil
+		}
+		return h.sendServiceLog(ctx, alert, mfn, false)
 	}
 
 	hcID := alert.Labels[AMLabelAlertHCID]
 	fn := mfn.Spec.FleetNotification
-	fnLimitedSupportReason := fn.NotificationMessage
+	ocmClient, dryRun := h.dryRun.clientFor(mfn, h.ocm)
+
+	entry := outbox.Entry{Cluster: hcID, Operation: ocm.OperationRemoveLimitedSupport, Notification: fn.Name, Alert: alert}
+	n := events.Notification{Template: fn.Name, Cluster: hcID}
 
-	activeLSReasons, err := h.ocm.GetLimitedSupportReasons(hcID)
+	// Only the limited support reasons posted by the agent are removed, not the ones set by SREs or other systems
+	ownedIDs, known, err := h.ownedLimitedSupportReasons(ctx, alert, mfn)
 	if err != nil {
-		return fmt.Errorf("unable to get limited support reasons for cluster %s:, %w", hcID, err)
+		// The record is read from the API server, isRetriable tells its transient failures
+		return fmt.Errorf("unable to get the limited support reasons posted for cluster %s: %w", hcID, err)
+	}
+
+	activeLSReasons, err := h.ocm.GetLimitedSupportReasons(ctx, hcID)
+	if err != nil {
+		return deferToOutbox(ctx, h.outbox, entry, retriableOCMError(fmt.Errorf("unable to get limited support reasons for cluster %s:, %w", hcID, err)))
 	}
 
 	for _, reason := range activeLSReasons {
-		// If the reason matches the fleet notification LS reason, remove it
-		// TODO(Claudio): Find a way to make sure the removed LS was also posted by OA
-		if strings.Contains(reason.Details(), fnLimitedSupportReason) {
-			log.WithFields(log.Fields{LogFieldNotificationName: fn.Name}).Infof("will remove limited support reason '%s' for notification", reason.ID())
-			err := h.ocm.RemoveLimitedSupport(hcID, reason.ID())
-			if err != nil {
-				metrics.IncrementFailedLimitedSupportRemoved(fn.Name)
-				// Set the metric for failed limited support response from OCM
-				metrics.SetResponseMetricFailure(config.ClustersService, fn.Name, alert.Labels["alertname"])
-				return fmt.Errorf("limited support reason with ID '%s' couldn't be removed for cluster %s, err: %w", reason.ID(), hcID, err)
+		if !ownsLimitedSupportReason(reason, fn.Summary, fn.NotificationMessage, ownedIDs, known) {
+			continue
+		}
+		log.WithFields(log.Fields{LogFieldNotificationName: fn.Name}).Infof("will remove limited support reason '%s' for notification", reason.ID())
+		if !dryRun {
+			if err := throttle(ctx, h.limiter, ocm.OperationRemoveLimitedSupport, hcID, fn.Name); err != nil {
+				return err
 			}
+		}
+		operationID, err := ocmClient.RemoveLimitedSupport(ctx, hcID, reason.ID())
+		if err != nil {
+			metrics.IncrementFailedLimitedSupportRemoved(fn.Name)
+			h.events.SendFailed(mfn, n, ocm.OperationRemoveLimitedSupport, err)
+			// Set the metric for failed limited support response from OCM
+			metrics.SetResponseMetricFailure(config.ClustersService, fn.Name, alert.Labels["alertname"])
+			return deferToOutbox(ctx, h.outbox, entry, retriableOCMError(fmt.Errorf("limited support reason with ID '%s' couldn't be removed for cluster %s, err: %w", reason.ID(), hcID, err)))
+		}
+		if !dryRun {
 			metrics.IncrementLimitedSupportRemovedCount(fn.Name)
+			h.events.LimitedSupportRemoved(mfn, n, operationID, reason.ID())
 		}
 	}
 	// Reset the metric for correct limited support response from OCM
 	metrics.ResetResponseMetricFailure(config.ClustersService, fn.Name, alert.Labels["alertname"])
 
-	return h.updateManagedFleetNotificationRecord(alert, mfn)
+	if dryRun {
+		return nil
+	}
+	// The notification was sent, recording it must not be aborted with the request
+	ctx = context.WithoutCancel(ctx)
+	if len(ownedIDs) > 0 {
+		// The reasons were removed, or removed by someone else if they are no longer active
+		if err := h.updateLimitedSupportReasons(ctx, alert.Labels[AMLabelAlertMCID], hcID, mfn, func([]string) []string { return nil }); err != nil {
+			log.WithError(err).WithFields(log.Fields{LogFieldNotificationName: fn.Name}).Warning("unable to clear the removed limited support reasons from the notification record")
+		}
+	}
+	return h.updateManagedFleetNotificationRecord(ctx, alert, mfn)
 }
 
 // processFiringAlert handles the pre-check verification and sending of a notification for a particular alert
 // and returns an error if that process completed successfully or false otherwise
-func (h *WebhookRHOBSReceiverHandler) processFiringAlert(alert template.Alert, mfn *oav1alpha1.ManagedFleetNotification) error {
+func (h *WebhookRHOBSReceiverHandler) processFiringAlert(ctx context.Context, alert template.Alert, mfn *oav1alpha1.ManagedFleetNotification) error {
 	fn := mfn.Spec.FleetNotification
 	hcID := alert.Labels[AMLabelAlertHCID]
+	// In dry-run mode the notification is recorded instead of being sent, and the notification record is left untouched
+	ocmClient, dryRun := h.dryRun.clientFor(mfn, h.ocm)
+	n := events.Notification{Template: fn.Name, Cluster: hcID}
 
-	canBeSent := h.firingCanBeSent(alert, mfn)
+	canBeSent := h.firingCanBeSent(ctx, alert, mfn)
 	// There's no need to send a notification so just return
 	if !canBeSent {
 		log.WithFields(log.Fields{"notification": fn.Name,
 			LogFieldResendInterval: fn.ResendWait,
 		}).Info("not sending a notification as one was already sent recently")
+		h.events.Suppressed(mfn, n, fn.ResendWait)
 		// Reset the metric for correct service log response from OCM
 		metrics.ResetResponseMetricFailure(config.ServiceLogService, fn.Name, alert.Labels["alertname"])
 		return nil
 	}
 
-	if mfn.Spec.FleetNotification.LimitedSupport {
-		// Send the limited support for the alert
-		log.WithFields(log.Fields{LogFieldNotificationName: fn.Name}).Info("will send limited support for notification")
-		builder := &cmv1.LimitedSupportReasonBuilder{}
-		builder.Summary(fn.Summary)
-		builder.Details(fn.NotificationMessage)
-		builder.DetectionType(cmv1.DetectionTypeManual)
-		reason, err := builder.Build()
-		if err != nil {
-			return fmt.Errorf("unable to build limited support for fleetnotification '%s' reason: %w", fn.Name, err)
-		}
-		err = h.ocm.SendLimitedSupport(hcID, reason)
-		if err != nil {
-			// Set the metric for failed limited support response from OCM
-			metrics.SetResponseMetricFailure("clusters_mgmt", fn.Name, alert.Labels["alertname"])
-			metrics.IncrementFailedLimitedSupportSend(fn.Name)
-			return fmt.Errorf("limited support reason for fleetnotification '%s' could not be set for cluster %s, err: %w", fn.Name, hcID, err)
+	// Notification is for a service log
+	if !mfn.Spec.FleetNotification.LimitedSupport {
+		return h.sendServiceLog(ctx, alert, mfn, true)
+	}
+
+	// Send the limited support for the alert
+	log.WithFields(log.Fields{LogFieldNotificationName: fn.Name}).Info("will send limited support for notification")
+	builder := &cmv1.LimitedSupportReasonBuilder{}
+	builder.Summary(fn.Summary)
+	builder.Details(fn.NotificationMessage)
+	builder.DetectionType(cmv1.DetectionTypeManual)
+	reason, err := builder.Build()
+	if err != nil {
+		return fmt.Errorf("unable to build limited support for fleetnotification '%s' reason: %w", fn.Name, err)
+	}
+	if !dryRun {
+		if err := throttle(ctx, h.limiter, ocm.OperationSendLimitedSupport, hcID, fn.Name); err != nil {
+			return err
 		}
+	}
+	created, operationID, err := ocmClient.SendLimitedSupport(ctx, hcID, reason)
+	if err != nil {
+		h.events.SendFailed(mfn, n, ocm.OperationSendLimitedSupport, err)
+		// Set the metric for failed limited support response from OCM
+		metrics.SetResponseMetricFailure("clusters_mgmt", fn.Name, alert.Labels["alertname"])
+		metrics.IncrementFailedLimitedSupportSend(fn.Name)
+		entry := outbox.Entry{Cluster: hcID, Operation: ocm.OperationSendLimitedSupport, Notification: fn.Name, Alert: alert}
+		return deferToOutbox(ctx, h.outbox, entry, retriableOCMError(fmt.Errorf("limited support reason for fleetnotification '%s' could not be set for cluster %s, err: %w", fn.Name, hcID, err)))
+	}
+	if !dryRun {
 		metrics.IncrementLimitedSupportSentCount(fn.Name)
-		// Reset the metric for correct limited support response from OCM
-		metrics.ResetResponseMetricFailure(config.ClustersService, fn.Name, alert.Labels["alertname"])
-	} else { // Notification is for a service log
-		log.WithFields(log.Fields{LogFieldNotificationName: fn.Name}).Info("will send servicelog for notification")
-		err := ocm.BuildAndSendServiceLog(
-			ocm.NewServiceLogBuilder(fn.Summary, fn.NotificationMessage, "", hcID, fn.Severity, fn.LogType, fn.References),
-			true, &alert, h.ocm)
+		h.events.LimitedSupportSet(mfn, n, operationID, created.ID())
+	}
+	// Reset the metric for correct limited support response from OCM
+	metrics.ResetResponseMetricFailure(config.ClustersService, fn.Name, alert.Labels["alertname"])
+
+	if dryRun {
+		return nil
+	}
+	// The notification was sent, recording it must not be aborted with the request
+	ctx = context.WithoutCancel(ctx)
+	// Record the reason first so that it is known to be owned by the agent even if the status update fails
+	if created != nil && created.ID() != "" {
+		err := h.updateLimitedSupportReasons(ctx, alert.Labels[AMLabelAlertMCID], hcID, mfn, func(ids []string) []string { return append(ids, created.ID()) })
 		if err != nil {
-			log.WithError(err).WithFields(log.Fields{LogFieldNotificationName: fn.Name, LogFieldIsFiring: true}).Error("unable to send service log for notification")
-			// Set the metric for failed service log response from OCM
-			metrics.SetResponseMetricFailure(config.ServiceLogService, fn.Name, alert.Labels["alertname"])
-			metrics.CountFailedServiceLogs(fn.Name)
+			log.WithError(err).WithFields(log.Fields{LogFieldNotificationName: fn.Name}).Warning("unable to record the posted limited support reason")
+		}
+	}
+	return h.updateManagedFleetNotificationRecord(ctx, alert, mfn)
+}
+
+// sendServiceLog sends the firing or resolved service log of the notification for the alert, and records it
+func (h *WebhookRHOBSReceiverHandler) sendServiceLog(ctx context.Context, alert template.Alert, mfn *oav1alpha1.ManagedFleetNotification, firing bool) error {
+	fn := mfn.Spec.FleetNotification
+	hcID := alert.Labels[AMLabelAlertHCID]
+	ocmClient, dryRun := h.dryRun.clientFor(mfn, h.ocm)
+
+	log.WithFields(log.Fields{LogFieldNotificationName: fn.Name, LogFieldIsFiring: firing}).Info("will send servicelog for notification")
+	slBuilder := ocm.NewServiceLogBuilder(fn.Summary, fn.NotificationMessage, mfn.Annotations[consts.ResolvedMessageAnnotation], hcID, fn.Severity, fn.LogType, fn.References).
+		WithTemplateEngine(mfn.Annotations[consts.TemplateEngineAnnotation])
+	if err := withClusterData(ctx, slBuilder, h.clusters, hcID); err != nil {
+		return err
+	}
+	logEntry, err := slBuilder.Build(firing, &alert)
+	if err != nil {
+		return fmt.Errorf("unable to build service log for fleetnotification '%s': %w", fn.Name, err)
+	}
+	if !dryRun {
+		if err := throttle(ctx, h.limiter, ocm.OperationSendServiceLog, hcID, fn.Name); err != nil {
 			return err
 		}
-		// Count the service log sent by the template name
+	}
+	operationID, err := ocmClient.SendServiceLog(ctx, logEntry)
+	if err != nil {
+		log.WithError(err).WithFields(log.Fields{LogFieldNotificationName: fn.Name, LogFieldIsFiring: firing}).Error("unable to send service log for notification")
+		h.events.SendFailed(mfn, events.Notification{Template: fn.Name, Cluster: hcID}, ocm.OperationSendServiceLog, err)
+		// Set the metric for failed service log response from OCM
+		metrics.SetResponseMetricFailure(config.ServiceLogService, fn.Name, alert.Labels["alertname"])
+		metrics.CountFailedServiceLogs(fn.Name)
+		entry := outbox.Entry{Cluster: hcID, Operation: ocm.OperationSendServiceLog, Notification: fn.Name, Alert: alert}
+		return deferToOutbox(ctx, h.outbox, entry, retriableOCMError(err))
+	}
+	// Reset the metric for correct service log response from OCM
+	metrics.ResetResponseMetricFailure(config.ServiceLogService, fn.Name, alert.Labels["alertname"])
+
+	if dryRun {
+		return nil
+	}
+	h.events.ServiceLogSent(mfn, events.Notification{Template: fn.Name, Cluster: hcID}, operationID, firing)
+	// Count the service log sent by the template name
+	if firing {
 		metrics.CountServiceLogSent(fn.Name, "firing")
-		// Reset the metric for correct service log response from OCM
-		metrics.ResetResponseMetricFailure(config.ServiceLogService, fn.Name, alert.Labels["alertname"])
+	} else {
+		metrics.CountServiceLogSent(fn.Name, "resolved")
 	}
+	// The notification was sent, recording it must not be aborted with the request
+	return h.updateManagedFleetNotificationRecord(context.WithoutCancel(ctx), alert, mfn)
+}
 
-	return h.updateManagedFleetNotificationRecord(alert, mfn)
+// fleetOperation returns the OCM write made for an alert of the fleet notification
+func fleetOperation(fn oav1alpha1.FleetNotification, firing bool) string {
+	switch {
+	case fn.LimitedSupport && firing:
+		return ocm.OperationSendLimitedSupport
+	case fn.LimitedSupport:
+		return ocm.OperationRemoveLimitedSupport
+	}
+	return ocm.OperationSendServiceLog
 }
 
-// Get or create ManagedFleetNotificationRecord
-func (h *WebhookRHOBSReceiverHandler) getOrCreateManagedFleetNotificationRecord(mcID string, hcID string, mfn *oav1alpha1.ManagedFleetNotification) (*oav1alpha1.ManagedFleetNotificationRecord, error) {
+// Get or create the ManagedFleetNotificationRecord holding the items of the hosted cluster
+func (h *WebhookRHOBSReceiverHandler) getOrCreateManagedFleetNotificationRecord(ctx context.Context, mcID string, hcID string, mfn *oav1alpha1.ManagedFleetNotification) (*oav1alpha1.ManagedFleetNotificationRecord, error) {
 	mfnr := &oav1alpha1.ManagedFleetNotificationRecord{}
 
-	err := h.c.Get(context.Background(), client.ObjectKey{
+	err := h.c.Get(ctx, client.ObjectKey{
 		Namespace: OCMAgentNamespaceName,
-		Name:      mcID,
+		Name:      h.recordName(mcID, hcID),
 	}, mfnr)
 
 	if err != nil {
 		if errors.IsNotFound(err) {
 			// Record does not exist, attempt to create it
-			mfnr = &oav1alpha1.ManagedFleetNotificationRecord{
-				ObjectMeta: v1.ObjectMeta{
-					Name:      mcID,
-					Namespace: OCMAgentNamespaceName,
-				},
-			}
-			if err := h.c.Create(context.Background(), mfnr); err != nil {
+			mfnr = h.newNotificationRecord(mcID, hcID)
+			if err := h.c.Create(ctx, mfnr); err != nil {
 				return nil, err
 			}
 		} else {
@@ -267,6 +492,11 @@ func (h *WebhookRHOBSReceiverHandler) getOrCreateManagedFleetNotificationRecord(
 		}
 	}
 
+	// The items not migrated yet are written along with the ones of the alert
+	if err := h.mergeLegacyRecord(ctx, mfnr, mcID, hcID); err != nil {
+		return nil, err
+	}
+
 	return mfnr, nil
 }
 
@@ -276,22 +506,26 @@ func (h *WebhookRHOBSReceiverHandler) getOrCreateManagedFleetNotificationRecord(
 // This function implements a retry for errors of type Conflict or AlreadyExists (both status code 409):
 // - conflict errors are triggered when failing to  Update() a resource
 // - alreadyexists errors are triggered when failing to Create() a resource
-func retryOnConflictOrAlreadyExists(backoff wait.Backoff, fn func() error) error {
-	return retry.OnError(backoff, customIs409, fn)
+func retryOnConflictOrAlreadyExists(ctx context.Context, config backoff.Config, fn func() error) error {
+	config.Retriable = customIs409
+	config.OnRetry = func(attempt int, err error, delay time.Duration) {
+		log.WithError(err).WithFields(log.Fields{"attempt": attempt, "delay": delay}).Debug("retrying after a conflict")
+	}
+	return backoff.Retry(ctx, config, fn)
 }
 
 // Updates the managedfleetnotificationrecord with the alert's data
 // This function creates the notificationrecordbyname as well as the notificationrecorditem in case they don't exist yet
 // Increments the sent/resolved notification state based on the alert
-func (h *WebhookRHOBSReceiverHandler) updateManagedFleetNotificationRecord(alert template.Alert, mfn *oav1alpha1.ManagedFleetNotification) error {
+func (h *WebhookRHOBSReceiverHandler) updateManagedFleetNotificationRecord(ctx context.Context, alert template.Alert, mfn *oav1alpha1.ManagedFleetNotification) error {
 	fn := mfn.Spec.FleetNotification
 	mcID := alert.Labels[AMLabelAlertMCID]
 	hcID := alert.Labels[AMLabelAlertHCID]
 	firing := alert.Status == string(model.AlertFiring)
 
-	err := retryOnConflictOrAlreadyExists(retryConfig, func() error {
+	err := retryOnConflictOrAlreadyExists(ctx, retryConfig, func() error {
 		// Fetch the ManagedFleetNotificationRecord, or create it if it does not already exist
-		mfnr, err := h.getOrCreateManagedFleetNotificationRecord(mcID, hcID, mfn)
+		mfnr, err := h.getOrCreateManagedFleetNotificationRecord(ctx, mcID, hcID, mfn)
 		if err != nil {
 			log.WithFields(log.Fields{LogFieldNotificationRecordName: mcID}).Infof("getOrCreate of managedfleetnotificationrecord failed: %s. Retrying in case of conflict error", err.Error())
 			return err
@@ -332,7 +566,7 @@ func (h *WebhookRHOBSReceiverHandler) updateManagedFleetNotificationRecord(alert
 			return err
 		}
 
-		err = h.c.Status().Update(context.TODO(), mfnr)
+		err = h.c.Status().Update(ctx, mfnr)
 		if err != nil {
 			log.WithFields(log.Fields{LogFieldNotificationRecordName: mfnr.Name}).Infof("update of managedfleetnotificationrecord failed: %s. Retrying in case of conflict error", err.Error())
 			return err
@@ -343,21 +577,39 @@ func (h *WebhookRHOBSReceiverHandler) updateManagedFleetNotificationRecord(alert
 	return err
 }
 
+// resolvedCanBeSent indicates whether the resolved service log can be sent for the alert: more firing notifications
+// than resolved ones were sent for the hosted cluster. A firing notification suppressed within the resend wait
+// doesn't warrant another resolved notification, nor does a previous delivery of the resolved alert.
+func (h *WebhookRHOBSReceiverHandler) resolvedCanBeSent(ctx context.Context, alert template.Alert, mfn *oav1alpha1.ManagedFleetNotification) bool {
+	mcID := alert.Labels[AMLabelAlertMCID]
+	hcID := alert.Labels[AMLabelAlertHCID]
+
+	mfnr, err := h.notificationRecord(ctx, mcID, hcID)
+	if err != nil {
+		// there's no fleetnotificationrecord for the MC, nothing fired
+		return false
+	}
+
+	recordItem, err := mfnr.GetNotificationRecordItem(mcID, mfn.Spec.FleetNotification.Name, hcID)
+	if err != nil {
+		// no firing notification was sent for the hosted cluster
+		return false
+	}
+
+	return recordItem.FiringNotificationSentCount > recordItem.ResolvedNotificationSentCount
+}
+
 // Firing can be sent if:
 // - there's no fleetnotificationrecord for the MC
 // - there's no fleetnotificationrecorditem for the hosted cluster
 // - for limited support type notification specifically, we only resent if the previous one resolved
 // - if the recorditem exists and we don't run in the above limited support case, firingCanBeSent is true if we exceeded the resendWait interval
-func (h *WebhookRHOBSReceiverHandler) firingCanBeSent(alert template.Alert, mfn *oav1alpha1.ManagedFleetNotification) bool {
+func (h *WebhookRHOBSReceiverHandler) firingCanBeSent(ctx context.Context, alert template.Alert, mfn *oav1alpha1.ManagedFleetNotification) bool {
 	fn := mfn.Spec.FleetNotification
 	mcID := alert.Labels[AMLabelAlertMCID]
 	hcID := alert.Labels[AMLabelAlertHCID]
 
-	mfnr := &oav1alpha1.ManagedFleetNotificationRecord{}
-	err := h.c.Get(context.Background(), client.ObjectKey{
-		Namespace: OCMAgentNamespaceName,
-		Name:      mcID,
-	}, mfnr)
+	mfnr, err := h.notificationRecord(ctx, mcID, hcID)
 
 	if err != nil {
 		// there's no fleetnotificationrecord for the MC
@@ -19,6 +19,7 @@ import (
 	"github.com/prometheus/alertmanager/template"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/record"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -26,9 +27,12 @@ import (
 
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	"github.com/openshift/ocm-agent/pkg/consts"
 	testconst "github.com/openshift/ocm-agent/pkg/consts/test"
+	"github.com/openshift/ocm-agent/pkg/events"
 	"github.com/openshift/ocm-agent/pkg/ocm"
 	webhookreceivermock "github.com/openshift/ocm-agent/pkg/ocm/mocks"
+	"github.com/openshift/ocm-agent/pkg/ratelimit"
 	clientmocks "github.com/openshift/ocm-agent/pkg/util/test/generated/mocks/client"
 )
 
@@ -100,7 +104,7 @@ var _ = Describe("WebhookRHOBSReceiverHandler HTTP Tests", func() {
 					mockStatusWriter.EXPECT().Update(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil),
 				)
 
-				err := testHandler.updateManagedFleetNotificationRecord(testAlertFiring, &testMFN)
+				err := testHandler.updateManagedFleetNotificationRecord(context.TODO(), testAlertFiring, &testMFN)
 				Expect(err).ShouldNot(HaveOccurred())
 			})
 		})
@@ -115,7 +119,7 @@ var _ = Describe("WebhookRHOBSReceiverHandler HTTP Tests", func() {
 					mockStatusWriter.EXPECT().Update(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil),
 				)
 
-				err := testHandler.updateManagedFleetNotificationRecord(testAlertFiring, &testMFN)
+				err := testHandler.updateManagedFleetNotificationRecord(context.TODO(), testAlertFiring, &testMFN)
 				Expect(err).ShouldNot(HaveOccurred())
 			})
 		})
@@ -124,12 +128,22 @@ var _ = Describe("WebhookRHOBSReceiverHandler HTTP Tests", func() {
 	Context("When processing a firing alert", func() {
 		Context("When the MFN of type limited support for a firing alert", func() {
 			It("Sends limited support", func() {
+				createdReason, _ := cmv1.NewLimitedSupportReason().ID("1234").Build()
 				gomock.InOrder(
 					// Fetch the MFNR
 					mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).SetArg(2, testMFNRWithStatus),
 
 					// Send limited support
-					mockOCMClient.EXPECT().SendLimitedSupport(testconst.TestHostedClusterID, limitedSupportReason).Return(nil),
+					mockOCMClient.EXPECT().SendLimitedSupport(gomock.Any(), testconst.TestHostedClusterID, limitedSupportReason).Return(createdReason, "", nil),
+
+					// Record the ID of the created reason on the MFNR
+					mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).SetArg(2, testMFNRWithStatus),
+					mockClient.EXPECT().Patch(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+						func(_ context.Context, mfnr *oav1alpha1.ManagedFleetNotificationRecord, _ client.Patch, _ ...client.PatchOption) error {
+							Expect(mfnr.Annotations).To(HaveKeyWithValue(consts.LimitedSupportReasonsAnnotation,
+								`{"`+testconst.TestNotificationName+`/`+testconst.TestHostedClusterID+`":["1234"]}`))
+							return nil
+						}),
 
 					// Fetch the MFNR and update it's status
 					mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).SetArg(2, testMFNRWithStatus),
@@ -139,7 +153,7 @@ var _ = Describe("WebhookRHOBSReceiverHandler HTTP Tests", func() {
 					mockStatusWriter.EXPECT().Update(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil),
 				)
 
-				err := testHandler.processFiringAlert(testAlertFiring, &testLimitedSupportMFN)
+				err := testHandler.processFiringAlert(context.TODO(), testAlertFiring, &testLimitedSupportMFN)
 				Expect(err).ShouldNot(HaveOccurred())
 			})
 			Context("When the MFN of type limited support for a firing alert and a previous firing notification hasn't resolved yet", func() {
@@ -152,7 +166,7 @@ var _ = Describe("WebhookRHOBSReceiverHandler HTTP Tests", func() {
 					mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).SetArg(2, testMFNRWithStatus)
 					// Return right after as there was already a LS sent that didn't resolve yet
 
-					err = testHandler.processFiringAlert(testAlertFiring, &testLimitedSupportMFN)
+					err = testHandler.processFiringAlert(context.TODO(), testAlertFiring, &testLimitedSupportMFN)
 					Expect(err).ShouldNot(HaveOccurred())
 				})
 			})
@@ -161,8 +175,11 @@ var _ = Describe("WebhookRHOBSReceiverHandler HTTP Tests", func() {
 		Context("When the MFN of type limited support for a firing alert", func() {
 			It("Removes no limited support if none exist", func() {
 				gomock.InOrder(
+					// Fetch the MFNR for the reasons posted by the agent
+					mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).SetArg(2, testMFNRWithStatus),
+
 					// Get limited support reasons, returns empty so no limited supports will be removed
-					mockOCMClient.EXPECT().GetLimitedSupportReasons(testconst.TestHostedClusterID).Return([]*cmv1.LimitedSupportReason{}, nil),
+					mockOCMClient.EXPECT().GetLimitedSupportReasons(gomock.Any(), testconst.TestHostedClusterID).Return([]*cmv1.LimitedSupportReason{}, nil),
 
 					// Fetch the MFNR
 					mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).SetArg(2, testMFNRWithStatus),
@@ -172,18 +189,33 @@ var _ = Describe("WebhookRHOBSReceiverHandler HTTP Tests", func() {
 					mockStatusWriter.EXPECT().Update(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil),
 				)
 
-				err := testHandler.processAlert(testAlertResolved, &testLimitedSupportMFN)
+				err := testHandler.processAlert(context.TODO(), testAlertResolved, &testLimitedSupportMFN)
 				Expect(err).ShouldNot(HaveOccurred())
 			})
 			It("Removes limited support if it was previously set", func() {
 				// This reason has an ID which is used to test deleting it
 				limitedSupportReason, _ = cmv1.NewLimitedSupportReason().Summary(testMFN.Spec.FleetNotification.Summary).Details(testMFN.Spec.FleetNotification.NotificationMessage).ID("1234").DetectionType(cmv1.DetectionTypeManual).Build()
+				// A reason set by SREs with the same details is left alone
+				manualReason, _ := cmv1.NewLimitedSupportReason().Summary(testMFN.Spec.FleetNotification.Summary).Details(testMFN.Spec.FleetNotification.NotificationMessage).ID("5678").DetectionType(cmv1.DetectionTypeManual).Build()
+				testMFNRWithStatus.Annotations = map[string]string{
+					consts.LimitedSupportReasonsAnnotation: `{"` + testconst.TestNotificationName + `/` + testconst.TestHostedClusterID + `":["1234"]}`,
+				}
 
 				gomock.InOrder(
+					// Fetch the MFNR for the reasons posted by the agent
+					mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).SetArg(2, testMFNRWithStatus),
 					// LS reasons are fetched
-					mockOCMClient.EXPECT().GetLimitedSupportReasons(testconst.TestHostedClusterID).Return([]*cmv1.LimitedSupportReason{limitedSupportReason}, nil),
-					// LS reason matching for the MFN is removed
-					mockOCMClient.EXPECT().RemoveLimitedSupport(testconst.TestHostedClusterID, limitedSupportReason.ID()).Return(nil),
+					mockOCMClient.EXPECT().GetLimitedSupportReasons(gomock.Any(), testconst.TestHostedClusterID).Return([]*cmv1.LimitedSupportReason{limitedSupportReason, manualReason}, nil),
+					// LS reason posted for the MFN is removed
+					mockOCMClient.EXPECT().RemoveLimitedSupport(gomock.Any(), testconst.TestHostedClusterID, limitedSupportReason.ID()).Return("", nil),
+
+					// The removed reason is cleared from the MFNR
+					mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).SetArg(2, testMFNRWithStatus),
+					mockClient.EXPECT().Patch(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+						func(_ context.Context, mfnr *oav1alpha1.ManagedFleetNotificationRecord, _ client.Patch, _ ...client.PatchOption) error {
+							Expect(mfnr.Annotations).NotTo(HaveKey(consts.LimitedSupportReasonsAnnotation))
+							return nil
+						}),
 
 					// Fetch the MFNR
 					mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).SetArg(2, testMFNRWithStatus),
@@ -193,7 +225,118 @@ var _ = Describe("WebhookRHOBSReceiverHandler HTTP Tests", func() {
 					mockStatusWriter.EXPECT().Update(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil),
 				)
 
-				err := testHandler.processAlert(testAlertResolved, &testLimitedSupportMFN)
+				err := testHandler.processAlert(context.TODO(), testAlertResolved, &testLimitedSupportMFN)
+				Expect(err).ShouldNot(HaveOccurred())
+			})
+			It("Removes the identical limited support for a record created before the reasons were recorded", func() {
+				// The record shows a limited support which didn't resolve yet
+				_, err := testMFNRWithStatus.UpdateNotificationRecordItem(testconst.TestNotificationName, testconst.TestHostedClusterID, true)
+				Expect(err).ShouldNot(HaveOccurred())
+				fn := testLimitedSupportMFN.Spec.FleetNotification
+				identicalReason, _ := cmv1.NewLimitedSupportReason().Summary(fn.Summary).Details(fn.NotificationMessage).ID("1234").DetectionType(cmv1.DetectionTypeManual).Build()
+				otherReason, _ := cmv1.NewLimitedSupportReason().Summary("Set by SRE").Details(fn.NotificationMessage + " and more").ID("5678").DetectionType(cmv1.DetectionTypeManual).Build()
+
+				gomock.InOrder(
+					mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).SetArg(2, testMFNRWithStatus),
+					mockOCMClient.EXPECT().GetLimitedSupportReasons(gomock.Any(), testconst.TestHostedClusterID).Return([]*cmv1.LimitedSupportReason{identicalReason, otherReason}, nil),
+					mockOCMClient.EXPECT().RemoveLimitedSupport(gomock.Any(), testconst.TestHostedClusterID, "1234").Return("", nil),
+					mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).SetArg(2, testMFNRWithStatus),
+					mockClient.EXPECT().Status().Return(mockStatusWriter),
+					mockStatusWriter.EXPECT().Update(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil),
+				)
+
+				err = testHandler.processAlert(context.TODO(), testAlertResolved, &testLimitedSupportMFN)
+				Expect(err).ShouldNot(HaveOccurred())
+			})
+		})
+
+		Context("When the MFN has a resolved message for a resolved alert", func() {
+			var firedMFNR oav1alpha1.ManagedFleetNotificationRecord
+
+			BeforeEach(func() {
+				testMFN.Annotations = map[string]string{consts.ResolvedMessageAnnotation: "The issue was resolved"}
+				firedMFNR = testconst.NewManagedFleetNotificationRecordWithStatus()
+				firedMFNR.Status.NotificationRecordByName[0].NotificationRecordItems[0].FiringNotificationSentCount = 1
+				firedMFNR.Status.NotificationRecordByName[0].NotificationRecordItems[0].LastTransitionTime = &metav1.Time{Time: time.Now().Add(-time.Hour)}
+			})
+
+			It("Sends the resolved service log", func() {
+				gomock.InOrder(
+					// Fetch the MFNR to check a firing notification was sent
+					mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).SetArg(2, firedMFNR),
+					mockOCMClient.EXPECT().SendServiceLog(gomock.Any(), gomock.Any()).DoAndReturn(
+						func(_ context.Context, sl *ocm.ServiceLog) (string, error) {
+							Expect(sl.Summary()).To(HavePrefix(ocm.ServiceLogResolvePrefix))
+							Expect(sl.Description()).To(Equal("The issue was resolved"))
+							return "", nil
+						}),
+					// Record the resolved notification
+					mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).SetArg(2, firedMFNR),
+					mockClient.EXPECT().Status().Return(mockStatusWriter),
+					mockStatusWriter.EXPECT().Update(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+						func(_ context.Context, mfnr *oav1alpha1.ManagedFleetNotificationRecord, _ ...client.SubResourceUpdateOption) error {
+							Expect(mfnr.Status.NotificationRecordByName[0].NotificationRecordItems[0].ResolvedNotificationSentCount).To(Equal(1))
+							return nil
+						}),
+				)
+
+				err := testHandler.processAlert(context.TODO(), testAlertResolved, &testMFN)
+				Expect(err).ShouldNot(HaveOccurred())
+			})
+
+			It("Doesn't send it again once the alert resolution was notified", func() {
+				firedMFNR.Status.NotificationRecordByName[0].NotificationRecordItems[0].ResolvedNotificationSentCount = 1
+				mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).SetArg(2, firedMFNR)
+
+				err := testHandler.processAlert(context.TODO(), testAlertResolved, &testMFN)
+				Expect(err).ShouldNot(HaveOccurred())
+			})
+
+			It("Doesn't send it again when the alert fires again within the resend wait and resolves", func() {
+				// The record is read and written as the alert fires and resolves
+				record := testconst.NewManagedFleetNotificationRecordWithStatus()
+				mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+					func(_ context.Context, _ client.ObjectKey, obj client.Object, _ ...client.GetOption) error {
+						record.DeepCopyInto(obj.(*oav1alpha1.ManagedFleetNotificationRecord))
+						return nil
+					}).AnyTimes()
+				mockClient.EXPECT().Status().Return(mockStatusWriter).AnyTimes()
+				mockStatusWriter.EXPECT().Update(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+					func(_ context.Context, obj client.Object, _ ...client.SubResourceUpdateOption) error {
+						obj.(*oav1alpha1.ManagedFleetNotificationRecord).DeepCopyInto(&record)
+						return nil
+					}).AnyTimes()
+				var sent []string
+				mockOCMClient.EXPECT().SendServiceLog(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(_ context.Context, sl *ocm.ServiceLog) (string, error) {
+						sent = append(sent, sl.Summary())
+						return "", nil
+					}).AnyTimes()
+
+				refiring := testconst.NewTestAlert(false, true)
+				refiring.StartsAt = testAlertFiring.StartsAt.Add(time.Minute)
+				reresolved := testconst.NewTestAlert(true, true)
+				reresolved.StartsAt = refiring.StartsAt
+				reresolved.EndsAt = time.Now().Add(time.Minute)
+				for _, alert := range []template.Alert{testAlertFiring, testAlertResolved, refiring, reresolved} {
+					Expect(testHandler.processAlert(context.TODO(), alert, &testMFN)).To(Succeed())
+				}
+				Expect(sent).To(HaveLen(2))
+				Expect(sent[0]).To(HavePrefix(ocm.ServiceLogActivePrefix))
+				Expect(sent[1]).To(HavePrefix(ocm.ServiceLogResolvePrefix))
+			})
+
+			It("Doesn't send it when no firing notification was sent", func() {
+				mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).SetArg(2, testMFNRWithStatus)
+
+				err := testHandler.processAlert(context.TODO(), testAlertResolved, &testMFN)
+				Expect(err).ShouldNot(HaveOccurred())
+			})
+
+			It("Doesn't send anything without a resolved message", func() {
+				testMFN.Annotations = nil
+
+				err := testHandler.processAlert(context.TODO(), testAlertResolved, &testMFN)
 				Expect(err).ShouldNot(HaveOccurred())
 			})
 		})
@@ -209,7 +352,7 @@ var _ = Describe("WebhookRHOBSReceiverHandler HTTP Tests", func() {
 						// Fetch the MFNR
 						mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).SetArg(2, testMFNR),
 						// Send the SL
-						mockOCMClient.EXPECT().SendServiceLog(serviceLog).Return(nil),
+						mockOCMClient.EXPECT().SendServiceLog(gomock.Any(), serviceLog).Return("", nil),
 
 						// Update SL sent status
 						mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).SetArg(2, testMFNRWithStatus),
@@ -217,7 +360,7 @@ var _ = Describe("WebhookRHOBSReceiverHandler HTTP Tests", func() {
 						mockStatusWriter.EXPECT().Update(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil),
 					)
 
-					err := testHandler.processAlert(testAlertFiring, &testMFN)
+					err := testHandler.processAlert(context.TODO(), testAlertFiring, &testMFN)
 					Expect(err).ShouldNot(HaveOccurred())
 				})
 			})
@@ -239,7 +382,7 @@ var _ = Describe("WebhookRHOBSReceiverHandler HTTP Tests", func() {
 						mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).SetArg(2, testMFNR),
 
 						// Send the SL
-						mockOCMClient.EXPECT().SendServiceLog(serviceLog).Return(nil),
+						mockOCMClient.EXPECT().SendServiceLog(gomock.Any(), serviceLog).Return("", nil),
 
 						// Update status (create the record item)
 						mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).SetArg(2, testMFNR),
@@ -253,7 +396,7 @@ var _ = Describe("WebhookRHOBSReceiverHandler HTTP Tests", func() {
 								return nil
 							}),
 					)
-					err := testHandler.processAlert(testAlertFiring, &testMFN)
+					err := testHandler.processAlert(context.TODO(), testAlertFiring, &testMFN)
 					Expect(err).ShouldNot(HaveOccurred())
 				})
 			})
@@ -277,7 +420,7 @@ var _ = Describe("WebhookRHOBSReceiverHandler HTTP Tests", func() {
 						// Fetch the MFNR
 						mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).SetArg(2, testMFNR),
 						// Send the SL
-						mockOCMClient.EXPECT().SendServiceLog(serviceLog).Return(nil),
+						mockOCMClient.EXPECT().SendServiceLog(gomock.Any(), serviceLog).Return("", nil),
 						// Update existing MFNR item
 						mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).SetArg(2, testMFNR),
 						mockClient.EXPECT().Status().Return(mockStatusWriter),
@@ -289,7 +432,7 @@ var _ = Describe("WebhookRHOBSReceiverHandler HTTP Tests", func() {
 								return nil
 							}),
 					)
-					err := testHandler.processAlert(testAlertFiring, &testMFN)
+					err := testHandler.processAlert(context.TODO(), testAlertFiring, &testMFN)
 					Expect(err).ShouldNot(HaveOccurred())
 				})
 			})
@@ -308,7 +451,7 @@ var _ = Describe("WebhookRHOBSReceiverHandler HTTP Tests", func() {
 						// Fetch the MFNR
 						mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).SetArg(2, testMFNR),
 						// Send the SL
-						mockOCMClient.EXPECT().SendServiceLog(serviceLog).Return(nil),
+						mockOCMClient.EXPECT().SendServiceLog(gomock.Any(), serviceLog).Return("", nil),
 
 						// Re-fetch the MFNR for the status update
 						mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).SetArg(2, testMFNR),
@@ -320,7 +463,7 @@ var _ = Describe("WebhookRHOBSReceiverHandler HTTP Tests", func() {
 								return nil
 							}),
 					)
-					err := testHandler.processAlert(testAlertFiring, &testMFN)
+					err := testHandler.processAlert(context.TODO(), testAlertFiring, &testMFN)
 					Expect(err).ShouldNot(HaveOccurred())
 				})
 			})
@@ -346,7 +489,7 @@ var _ = Describe("WebhookRHOBSReceiverHandler HTTP Tests", func() {
 						mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).SetArg(2, testMFNR),
 					)
 
-					err := testHandler.processAlert(testAlertFiring, &testMFN)
+					err := testHandler.processAlert(context.TODO(), testAlertFiring, &testMFN)
 					Expect(err).ShouldNot(HaveOccurred())
 				})
 			})
@@ -474,7 +617,7 @@ var _ = Describe("WebhookRHOBSReceiverHandler Additional Tests", func() {
 
 			mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).SetArg(2, validMFN)
 			mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).SetArg(2, testconst.NewManagedFleetNotificationRecordWithStatus())
-			mockOCMClient.EXPECT().SendServiceLog(gomock.Any()).Return(nil)
+			mockOCMClient.EXPECT().SendServiceLog(gomock.Any(), gomock.Any()).Return("", nil)
 			mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).SetArg(2, testconst.NewManagedFleetNotificationRecordWithStatus())
 			mockClient.EXPECT().Status().Return(mockStatusWriter)
 			mockStatusWriter.EXPECT().Update(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
@@ -504,9 +647,236 @@ var _ = Describe("WebhookRHOBSReceiverHandler Additional Tests", func() {
 
 			response := testHandler.processAMReceiver(alertData, context.Background())
 
-			Expect(response.Status).To(ContainSubstring("unable to find ManagedFleetNotification"))
-			Expect(response.Code).To(Equal(http.StatusInternalServerError))
+			Expect(response.Status).To(Equal("some alerts could not be processed"))
+			Expect(response.Code).To(Equal(http.StatusOK))
 			Expect(response.Error).ToNot(BeNil())
+			Expect(response.Alerts).To(HaveLen(1))
+			Expect(response.Alerts[0].Outcome).To(Equal(AlertOutcomeFailed))
+			Expect(response.Alerts[0].Error).To(ContainSubstring("unable to find ManagedFleetNotification"))
+			Expect(response.Alerts[0].Retriable).To(BeFalse())
+		})
+
+		It("should ask for a redelivery when ManagedFleetNotification can't be fetched temporarily", func() {
+			alert := testconst.NewTestAlert(false, true)
+			alertData := AMReceiverData{Alerts: []template.Alert{alert}}
+
+			mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(kerrors.NewServiceUnavailable("unavailable"))
+
+			response := testHandler.processAMReceiver(alertData, context.Background())
+
+			Expect(response.Code).To(Equal(http.StatusServiceUnavailable))
+			Expect(response.Alerts).To(HaveLen(1))
+			Expect(response.Alerts[0].Retriable).To(BeTrue())
+		})
+
+		It("should ask for a redelivery when the service log can't be sent", func() {
+			alert := testconst.NewTestAlert(false, true)
+			alertData := AMReceiverData{Alerts: []template.Alert{alert}}
+			validMFN := testconst.NewManagedFleetNotification(false)
+
+			gomock.InOrder(
+				mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).SetArg(2, validMFN),
+				mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(kerrors.NewNotFound(schema.GroupResource{}, "not-found")),
+				mockOCMClient.EXPECT().SendServiceLog(gomock.Any(), gomock.Any()).Return("", errors.New("OCM unavailable")),
+			)
+
+			response := testHandler.processAMReceiver(alertData, context.Background())
+
+			Expect(response.Code).To(Equal(http.StatusServiceUnavailable))
+			Expect(response.Alerts).To(HaveLen(1))
+			Expect(response.Alerts[0].Template).To(Equal(alert.Labels[AMLabelTemplateName]))
+			Expect(response.Alerts[0].Outcome).To(Equal(AlertOutcomeFailed))
+			Expect(response.Alerts[0].Error).To(ContainSubstring("OCM unavailable"))
+			Expect(response.Alerts[0].Retriable).To(BeTrue())
+		})
+
+		It("should not ask for a redelivery when OCM rejects the service log", func() {
+			alert := testconst.NewTestAlert(false, true)
+			alertData := AMReceiverData{Alerts: []template.Alert{alert}}
+			validMFN := testconst.NewManagedFleetNotification(false)
+
+			gomock.InOrder(
+				mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).SetArg(2, validMFN),
+				mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(kerrors.NewNotFound(schema.GroupResource{}, "not-found")),
+				mockOCMClient.EXPECT().SendServiceLog(gomock.Any(), gomock.Any()).Return("", &ocm.ResponseError{Operation: ocm.OperationSendServiceLog, Status: http.StatusBadRequest}),
+			)
+
+			response := testHandler.processAMReceiver(alertData, context.Background())
+
+			Expect(response.Code).To(Equal(http.StatusOK))
+			Expect(response.Alerts).To(HaveLen(1))
+			Expect(response.Alerts[0].Outcome).To(Equal(AlertOutcomeFailed))
+			Expect(response.Alerts[0].Retriable).To(BeFalse())
+		})
+
+		It("should emit an event on the notification for the service log sent", func() {
+			alert := testconst.NewTestAlert(false, true)
+			alertData := AMReceiverData{Alerts: []template.Alert{alert}}
+			mfn := testconst.NewManagedFleetNotification(false)
+			mfnr := testconst.NewManagedFleetNotificationRecordWithStatus()
+			fakeRecorder := record.NewFakeRecorder(10)
+			testHandler.WithEvents(events.NewRecorder(fakeRecorder))
+
+			gomock.InOrder(
+				mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).SetArg(2, mfn),
+				mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(kerrors.NewNotFound(schema.GroupResource{}, "not-found")),
+				mockOCMClient.EXPECT().SendServiceLog(gomock.Any(), gomock.Any()).Return("operation-id", nil),
+				mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).SetArg(2, mfnr),
+				mockClient.EXPECT().Status().Return(mockStatusWriter),
+				mockStatusWriter.EXPECT().Update(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil),
+			)
+
+			response := testHandler.processAMReceiver(alertData, context.Background())
+
+			Expect(response.Code).To(Equal(http.StatusOK))
+			Expect(fakeRecorder.Events).To(Receive(And(
+				HavePrefix("Normal "+events.ReasonServiceLogSent),
+				ContainSubstring(events.ClusterAnnotation+":"+testconst.TestHostedClusterID),
+				ContainSubstring(events.OperationIDAnnotation+":operation-id"),
+			)))
+		})
+
+		It("should emit a warning on the notification when the service log can't be sent", func() {
+			alert := testconst.NewTestAlert(false, true)
+			alertData := AMReceiverData{Alerts: []template.Alert{alert}}
+			fakeRecorder := record.NewFakeRecorder(10)
+			testHandler.WithEvents(events.NewRecorder(fakeRecorder))
+
+			gomock.InOrder(
+				mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).SetArg(2, testconst.NewManagedFleetNotification(false)),
+				mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(kerrors.NewNotFound(schema.GroupResource{}, "not-found")),
+				mockOCMClient.EXPECT().SendServiceLog(gomock.Any(), gomock.Any()).Return("", errors.New("OCM unavailable")),
+			)
+
+			testHandler.processAMReceiver(alertData, context.Background())
+
+			Expect(fakeRecorder.Events).To(Receive(And(
+				HavePrefix("Warning "+events.ReasonSendFailed),
+				ContainSubstring("OCM unavailable"),
+			)))
+		})
+
+		It("should resolve the cluster place holders with the cluster metadata", func() {
+			alert := testconst.NewTestAlert(false, true)
+			alertData := AMReceiverData{Alerts: []template.Alert{alert}}
+			mfn := testconst.NewManagedFleetNotification(false)
+			mfn.Spec.FleetNotification.Summary = "Issue on ${cluster.name}"
+			cluster, _ := cmv1.NewCluster().Name("my-cluster").ExternalID(testconst.TestHostedClusterID).Build()
+			mfnr := testconst.NewManagedFleetNotificationRecordWithStatus()
+
+			var sentSummary string
+			gomock.InOrder(
+				mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).SetArg(2, mfn),
+				mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(kerrors.NewNotFound(schema.GroupResource{}, "not-found")),
+				mockOCMClient.EXPECT().GetClusterByExternalID(gomock.Any(), testconst.TestHostedClusterID).Return(cluster, nil),
+				mockOCMClient.EXPECT().SendServiceLog(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, sl *ocm.ServiceLog) (string, error) {
+					sentSummary = sl.Summary()
+					return "", nil
+				}),
+				mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).SetArg(2, mfnr),
+				mockClient.EXPECT().Status().Return(mockStatusWriter),
+				mockStatusWriter.EXPECT().Update(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil),
+			)
+
+			response := testHandler.processAMReceiver(alertData, context.Background())
+
+			Expect(response.Code).To(Equal(http.StatusOK))
+			Expect(sentSummary).To(Equal(ocm.ServiceLogActivePrefix + ": Issue on my-cluster"))
+		})
+
+		It("should read the notification from the cache and its record from the API server", func() {
+			alert := testconst.NewTestAlert(false, true)
+			alertData := AMReceiverData{Alerts: []template.Alert{alert}}
+			mfn := testconst.NewManagedFleetNotification(false)
+			mfnr := testconst.NewManagedFleetNotificationRecordWithStatus()
+			mockCache := clientmocks.NewMockClient(mockCtrl)
+			testHandler.WithCache(mockCache)
+
+			// The record deciding whether the notification was already sent is read from the API server, as is the
+			// record updated
+			gomock.InOrder(
+				mockCache.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).SetArg(2, mfn),
+				mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).SetArg(2, mfnr),
+				mockOCMClient.EXPECT().SendServiceLog(gomock.Any(), gomock.Any()).Return("", nil),
+				mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).SetArg(2, mfnr),
+				mockClient.EXPECT().Status().Return(mockStatusWriter),
+				mockStatusWriter.EXPECT().Update(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil),
+			)
+
+			response := testHandler.processAMReceiver(alertData, context.Background())
+
+			Expect(response.Code).To(Equal(http.StatusOK))
+		})
+
+		It("should record the notification instead of sending it in dry-run mode", func() {
+			alert := testconst.NewTestAlert(false, true)
+			alertData := AMReceiverData{Alerts: []template.Alert{alert}}
+			mfn := testconst.NewManagedFleetNotification(false)
+			mfn.Annotations = map[string]string{consts.DryRunAnnotation: "true"}
+			recorder := ocm.NewDryRunRecorder(10)
+			testHandler.WithDryRun(recorder, false)
+
+			// Neither the service log is sent nor the notification record is updated
+			gomock.InOrder(
+				mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).SetArg(2, mfn),
+				mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(kerrors.NewNotFound(schema.GroupResource{}, "not-found")),
+			)
+
+			response := testHandler.processAMReceiver(alertData, context.Background())
+
+			Expect(response.Code).To(Equal(http.StatusOK))
+			Expect(recorder.Records()).To(HaveLen(1))
+			Expect(recorder.Records()[0].Operation).To(Equal(ocm.OperationSendServiceLog))
+			Expect(recorder.Records()[0].ClusterID).To(Equal(testconst.TestHostedClusterID))
+		})
+
+		It("should drop the notification throttled by the rate limits with the drop policy", func() {
+			alert := testconst.NewTestAlert(false, true)
+			alertData := AMReceiverData{Alerts: []template.Alert{alert}}
+			mfn := testconst.NewManagedFleetNotification(false)
+			limiter, err := ratelimit.NewLimiter(ratelimit.Config{PerCluster: 1, Policy: ratelimit.PolicyDrop})
+			Expect(err).ShouldNot(HaveOccurred())
+			// Use up the cluster's budget
+			Expect(limiter.Wait(context.Background(), ocm.OperationSendServiceLog, testconst.TestHostedClusterID, "other-template")).To(Succeed())
+			testHandler.WithRateLimiter(limiter)
+
+			// The service log isn't sent
+			gomock.InOrder(
+				mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).SetArg(2, mfn),
+				mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(kerrors.NewNotFound(schema.GroupResource{}, "not-found")),
+			)
+
+			response := testHandler.processAMReceiver(alertData, context.Background())
+
+			Expect(response.Code).To(Equal(http.StatusOK))
+			Expect(response.Status).To(Equal("some alerts were dropped by the rate limits"))
+			Expect(response.Error).To(HaveOccurred())
+			Expect(response.Alerts).To(HaveLen(1))
+			Expect(response.Alerts[0].Outcome).To(Equal(AlertOutcomeDropped))
+			Expect(response.Alerts[0].Error).To(ContainSubstring(ratelimit.ErrDropped.Error()))
+			Expect(response.Alerts[0].Retriable).To(BeFalse())
+		})
+
+		It("should ask for a redelivery when the rate limits defer the notification", func() {
+			alert := testconst.NewTestAlert(false, true)
+			alertData := AMReceiverData{Alerts: []template.Alert{alert}}
+			mfn := testconst.NewManagedFleetNotification(false)
+			limiter, err := ratelimit.NewLimiter(ratelimit.Config{PerTemplate: 1, Policy: ratelimit.PolicyWait, MaxDelay: time.Second})
+			Expect(err).ShouldNot(HaveOccurred())
+			// Use up the template's budget
+			Expect(limiter.Wait(context.Background(), ocm.OperationSendServiceLog, "other-cluster", mfn.Spec.FleetNotification.Name)).To(Succeed())
+			testHandler.WithRateLimiter(limiter)
+
+			gomock.InOrder(
+				mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).SetArg(2, mfn),
+				mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(kerrors.NewNotFound(schema.GroupResource{}, "not-found")),
+			)
+
+			response := testHandler.processAMReceiver(alertData, context.Background())
+
+			Expect(response.Code).To(Equal(http.StatusServiceUnavailable))
+			Expect(response.Alerts).To(HaveLen(1))
+			Expect(response.Alerts[0].Retriable).To(BeTrue())
 		})
 
 		It("should skip invalid alerts", func() {
@@ -520,14 +890,13 @@ var _ = Describe("WebhookRHOBSReceiverHandler Additional Tests", func() {
 			}
 			alertData := AMReceiverData{Alerts: []template.Alert{invalidAlert}}
 
-			// Even invalid alerts trigger client.Get() with empty templateName before validation
-			mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(kerrors.NewNotFound(schema.GroupResource{}, "not-found"))
-
 			response := testHandler.processAMReceiver(alertData, context.Background())
 
-			Expect(response.Status).To(ContainSubstring("unable to find ManagedFleetNotification"))
-			Expect(response.Code).To(Equal(http.StatusInternalServerError))
-			Expect(response.Error).ToNot(BeNil())
+			Expect(response.Status).To(Equal("ok"))
+			Expect(response.Code).To(Equal(http.StatusOK))
+			Expect(response.Error).To(BeNil())
+			Expect(response.Alerts).To(HaveLen(1))
+			Expect(response.Alerts[0].Outcome).To(Equal(AlertOutcomeSkipped))
 		})
 	})
 
@@ -546,7 +915,7 @@ var _ = Describe("WebhookRHOBSReceiverHandler Additional Tests", func() {
 			unknownAlert := firingAlert
 			unknownAlert.Status = "unknown"
 
-			err := testHandler.processAlert(unknownAlert, &validMFN)
+			err := testHandler.processAlert(context.TODO(), unknownAlert, &validMFN)
 
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("unexpected status unknown"))
@@ -556,7 +925,7 @@ var _ = Describe("WebhookRHOBSReceiverHandler Additional Tests", func() {
 			emptyAlert := firingAlert
 			emptyAlert.Status = ""
 
-			err := testHandler.processAlert(emptyAlert, &validMFN)
+			err := testHandler.processAlert(context.TODO(), emptyAlert, &validMFN)
 
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("unexpected status"))
@@ -569,9 +938,9 @@ var _ = Describe("WebhookRHOBSReceiverHandler Additional Tests", func() {
 			limitedSupportMFN := testconst.NewManagedFleetNotification(true)
 
 			mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).SetArg(2, testconst.NewManagedFleetNotificationRecordWithStatus())
-			mockOCMClient.EXPECT().SendLimitedSupport(gomock.Any(), gomock.Any()).Return(errors.New("OCM API error"))
+			mockOCMClient.EXPECT().SendLimitedSupport(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, "", errors.New("OCM API error"))
 
-			err := testHandler.processFiringAlert(alert, &limitedSupportMFN)
+			err := testHandler.processFiringAlert(context.TODO(), alert, &limitedSupportMFN)
 
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("OCM API error"))
@@ -583,7 +952,7 @@ var _ = Describe("WebhookRHOBSReceiverHandler Additional Tests", func() {
 
 			mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(errors.New("k8s client error"))
 
-			err := testHandler.updateManagedFleetNotificationRecord(alert, &mfn)
+			err := testHandler.updateManagedFleetNotificationRecord(context.TODO(), alert, &mfn)
 
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("k8s client error"))
@@ -598,7 +967,7 @@ var _ = Describe("WebhookRHOBSReceiverHandler Additional Tests", func() {
 			mockClient.EXPECT().Status().Return(mockStatusWriter)
 			mockStatusWriter.EXPECT().Update(gomock.Any(), gomock.Any(), gomock.Any()).Return(errors.New("status update error"))
 
-			err := testHandler.updateManagedFleetNotificationRecord(alert, &mfn)
+			err := testHandler.updateManagedFleetNotificationRecord(context.TODO(), alert, &mfn)
 
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("status update error"))
@@ -615,13 +984,11 @@ var _ = Describe("WebhookRHOBSReceiverHandler Additional Tests", func() {
 				Status: "firing",
 			}
 
-			// Even invalid alerts trigger client.Get() with empty templateName before validation
-			mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(kerrors.NewNotFound(schema.GroupResource{}, "not-found"))
-
 			response := testHandler.processAMReceiver(AMReceiverData{Alerts: []template.Alert{alert}}, context.Background())
 
-			Expect(response.Status).To(ContainSubstring("unable to find ManagedFleetNotification"))
-			Expect(response.Code).To(Equal(http.StatusInternalServerError))
+			Expect(response.Code).To(Equal(http.StatusOK))
+			Expect(response.Alerts).To(HaveLen(1))
+			Expect(response.Alerts[0].Outcome).To(Equal(AlertOutcomeSkipped))
 		})
 
 		It("should handle nil ManagedFleetNotification", func() {
@@ -634,7 +1001,7 @@ var _ = Describe("WebhookRHOBSReceiverHandler Additional Tests", func() {
 				Expect(fmt.Sprintf("%v", r)).To(ContainSubstring("runtime error: invalid memory address or nil pointer dereference"))
 			}()
 
-			_ = testHandler.processAlert(alert, nil)
+			_ = testHandler.processAlert(context.TODO(), alert, nil)
 
 			// This line should not be reached due to panic
 			Fail("Expected panic for nil ManagedFleetNotification")
@@ -657,7 +1024,7 @@ var _ = Describe("WebhookRHOBSReceiverHandler Additional Tests", func() {
 				// Then check if firing can be sent
 				mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).SetArg(2, mfnr),
 				// Send service log
-				mockOCMClient.EXPECT().SendServiceLog(gomock.Any()).Return(nil),
+				mockOCMClient.EXPECT().SendServiceLog(gomock.Any(), gomock.Any()).Return("", nil),
 				// Update status
 				mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).SetArg(2, mfnr),
 				mockClient.EXPECT().Status().Return(mockStatusWriter),
@@ -694,13 +1061,11 @@ var _ = Describe("WebhookRHOBSReceiverHandler Additional Tests", func() {
 			responseRecorder := httptest.NewRecorder()
 
 			// The JSON parses successfully but creates an invalid alert (missing required labels)
-			// Even invalid alerts trigger client.Get() with empty templateName before validation
-			mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(kerrors.NewNotFound(schema.GroupResource{}, "not-found"))
-
 			testHandler.ServeHTTP(responseRecorder, req)
 
-			// The alert fails to find ManagedFleetNotification due to missing template name
-			Expect(responseRecorder.Code).To(Equal(http.StatusInternalServerError))
+			// The invalid alert is skipped, redelivering it would not help
+			Expect(responseRecorder.Code).To(Equal(http.StatusOK))
+			Expect(responseRecorder.Body.String()).To(ContainSubstring(AlertOutcomeSkipped))
 		})
 
 		It("should handle JSON with unexpected structure", func() {
@@ -733,7 +1098,7 @@ var _ = Describe("WebhookRHOBSReceiverHandler Additional Tests", func() {
 				mockStatusWriter.EXPECT().Update(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil),
 			)
 
-			err := testHandler.updateManagedFleetNotificationRecord(alert, &mfn)
+			err := testHandler.updateManagedFleetNotificationRecord(context.TODO(), alert, &mfn)
 
 			Expect(err).ToNot(HaveOccurred())
 		})
@@ -753,7 +1118,7 @@ var _ = Describe("WebhookRHOBSReceiverHandler Additional Tests", func() {
 				mockStatusWriter.EXPECT().Update(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil),
 			)
 
-			err := testHandler.updateManagedFleetNotificationRecord(alert, &mfn)
+			err := testHandler.updateManagedFleetNotificationRecord(context.TODO(), alert, &mfn)
 
 			Expect(err).ToNot(HaveOccurred())
 		})
@@ -773,7 +1138,7 @@ var _ = Describe("WebhookRHOBSReceiverHandler Additional Tests", func() {
 		It("should return true when no ManagedFleetNotificationRecord exists", func() {
 			mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(kerrors.NewNotFound(schema.GroupResource{}, "not-found"))
 
-			result := testHandler.firingCanBeSent(alert, &mfn)
+			result := testHandler.firingCanBeSent(context.TODO(), alert, &mfn)
 
 			Expect(result).To(BeTrue())
 		})
@@ -782,7 +1147,7 @@ var _ = Describe("WebhookRHOBSReceiverHandler Additional Tests", func() {
 			mfnr := testconst.NewManagedFleetNotificationRecord()
 			mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).SetArg(2, mfnr)
 
-			result := testHandler.firingCanBeSent(alert, &mfn)
+			result := testHandler.firingCanBeSent(context.TODO(), alert, &mfn)
 
 			Expect(result).To(BeTrue())
 		})
@@ -792,7 +1157,7 @@ var _ = Describe("WebhookRHOBSReceiverHandler Additional Tests", func() {
 			mfnr.Status.NotificationRecordByName[0].NotificationRecordItems[0].LastTransitionTime = nil
 			mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).SetArg(2, mfnr)
 
-			result := testHandler.firingCanBeSent(alert, &mfn)
+			result := testHandler.firingCanBeSent(context.TODO(), alert, &mfn)
 
 			Expect(result).To(BeTrue())
 		})
@@ -806,7 +1171,7 @@ var _ = Describe("WebhookRHOBSReceiverHandler Additional Tests", func() {
 
 			mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).SetArg(2, mfnr)
 
-			result := testHandler.firingCanBeSent(alert, &limitedSupportMFN)
+			result := testHandler.firingCanBeSent(context.TODO(), alert, &limitedSupportMFN)
 
 			Expect(result).To(BeFalse())
 		})
@@ -818,7 +1183,7 @@ var _ = Describe("WebhookRHOBSReceiverHandler Additional Tests", func() {
 
 			mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).SetArg(2, mfnr)
 
-			result := testHandler.firingCanBeSent(alert, &mfn)
+			result := testHandler.firingCanBeSent(context.TODO(), alert, &mfn)
 
 			Expect(result).To(BeFalse())
 		})
@@ -830,7 +1195,7 @@ var _ = Describe("WebhookRHOBSReceiverHandler Additional Tests", func() {
 
 			mockClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).SetArg(2, mfnr)
 
-			result := testHandler.firingCanBeSent(alert, &mfn)
+			result := testHandler.firingCanBeSent(context.TODO(), alert, &mfn)
 
 			Expect(result).To(BeTrue())
 		})
@@ -0,0 +1,113 @@
+package httpchecker
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/openshift/ocm-agent/pkg/metrics"
+)
+
+const (
+	// DefaultHealthTTL is how long the availability of OCM is trusted before the URL is probed again
+	DefaultHealthTTL = 30 * time.Second
+	// HealthFailureThreshold is the number of consecutive failures after which OCM is considered unavailable
+	HealthFailureThreshold = 3
+)
+
+// HealthMonitor tracks the availability of OCM so that the handlers don't have to probe it before each request.
+// The availability is refreshed by the outcome of the requests sent to OCM and, when it is older than the TTL,
+// by probing the URL with the checker. OCM is considered unavailable after HealthFailureThreshold consecutive
+// failures, and available again after a success.
+type HealthMonitor struct {
+	checker   HTTPChecker
+	url       string
+	ttl       time.Duration
+	mu        sync.Mutex
+	err       error
+	failures  int
+	checkedAt time.Time
+	probing   bool
+}
+
+// NewHealthMonitor returns a monitor probing the URL with the checker, OCM is considered available until checked
+func NewHealthMonitor(checker HTTPChecker, url string, ttl time.Duration) *HealthMonitor {
+	if ttl <= 0 {
+		ttl = DefaultHealthTTL
+	}
+	metrics.SetOCMAvailable(true)
+	return &HealthMonitor{checker: checker, url: url, ttl: ttl}
+}
+
+// Available returns nil if OCM is available or the error of the last failure otherwise. The URL is only probed
+// when the availability expired, the callers don't wait for a probe in progress and get the current availability.
+// A nil monitor reports OCM as available.
+func (m *HealthMonitor) Available() error {
+	if m == nil {
+		return nil
+	}
+	m.mu.Lock()
+	if m.probing || time.Since(m.checkedAt) < m.ttl {
+		defer m.mu.Unlock()
+		return m.err
+	}
+	m.probing = true
+	m.mu.Unlock()
+
+	probeErr := m.checker.UrlAvailabilityCheck(m.url)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.probing = false
+	m.set(probeErr)
+	return m.err
+}
+
+// Observe records the outcome of a request sent to OCM, err is nil if OCM handled the request
+func (m *HealthMonitor) Observe(err error) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.set(err)
+}
+
+// Run keeps the availability fresh in the background until ctx is done, so that the handlers rarely wait for a probe
+func (m *HealthMonitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.ttl)
+	defer ticker.Stop()
+	for {
+		_ = m.Available()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// set updates the availability with the outcome of a request or probe, callers must hold the lock
+func (m *HealthMonitor) set(err error) {
+	m.checkedAt = time.Now()
+	if err == nil {
+		m.failures = 0
+	} else {
+		m.failures++
+		if m.failures < HealthFailureThreshold {
+			// A single failure could be a glitch, OCM keeps its current availability
+			return
+		}
+	}
+	if (err == nil) != (m.err == nil) {
+		if err != nil {
+			log.WithError(err).WithField("failures", m.failures).Warning("OCM is unavailable")
+		} else {
+			log.Info("OCM is available again")
+		}
+	}
+	m.err = err
+	metrics.SetOCMAvailable(err == nil)
+}
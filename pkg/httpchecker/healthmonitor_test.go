@@ -0,0 +1,130 @@
+package httpchecker_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/openshift/ocm-agent/pkg/httpchecker"
+	"github.com/openshift/ocm-agent/pkg/httpchecker/mocks"
+)
+
+var _ = Describe("HealthMonitor", func() {
+	const testURL = "https://api.openshift.com"
+
+	var (
+		mockCtrl    *gomock.Controller
+		mockChecker *mocks.MockHTTPChecker
+		monitor     *httpchecker.HealthMonitor
+		errProbe    = errors.New("failed to connect")
+	)
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		mockChecker = mocks.NewMockHTTPChecker(mockCtrl)
+		monitor = httpchecker.NewHealthMonitor(mockChecker, testURL, time.Hour)
+	})
+
+	It("probes the URL once while the availability is fresh", func() {
+		mockChecker.EXPECT().UrlAvailabilityCheck(testURL).Return(nil).Times(1)
+		Expect(monitor.Available()).To(Succeed())
+		Expect(monitor.Available()).To(Succeed())
+	})
+
+	It("reports OCM as unavailable after consecutive failed probes", func() {
+		monitor = httpchecker.NewHealthMonitor(mockChecker, testURL, time.Millisecond)
+		mockChecker.EXPECT().UrlAvailabilityCheck(testURL).Return(errProbe).Times(httpchecker.HealthFailureThreshold)
+		for i := 1; i < httpchecker.HealthFailureThreshold; i++ {
+			Expect(monitor.Available()).To(Succeed())
+			time.Sleep(2 * time.Millisecond)
+		}
+		Expect(monitor.Available()).To(MatchError(errProbe))
+	})
+
+	It("probes the URL again once the availability expired", func() {
+		for i := 0; i < httpchecker.HealthFailureThreshold; i++ {
+			monitor.Observe(errProbe)
+		}
+		Expect(monitor.Available()).To(MatchError(errProbe))
+		monitor = httpchecker.NewHealthMonitor(mockChecker, testURL, time.Millisecond)
+		for i := 0; i < httpchecker.HealthFailureThreshold; i++ {
+			monitor.Observe(errProbe)
+		}
+		mockChecker.EXPECT().UrlAvailabilityCheck(testURL).Return(nil)
+		time.Sleep(2 * time.Millisecond)
+		Expect(monitor.Available()).To(Succeed())
+	})
+
+	It("doesn't probe the URL after observing the outcome of a request", func() {
+		for i := 0; i < httpchecker.HealthFailureThreshold; i++ {
+			monitor.Observe(errProbe)
+		}
+		Expect(monitor.Available()).To(MatchError(errProbe))
+		monitor.Observe(nil)
+		Expect(monitor.Available()).To(Succeed())
+	})
+
+	It("keeps OCM available after a single failed request", func() {
+		monitor.Observe(errProbe)
+		Expect(monitor.Available()).To(Succeed())
+		monitor.Observe(nil)
+		for i := 1; i < httpchecker.HealthFailureThreshold; i++ {
+			monitor.Observe(errProbe)
+		}
+		Expect(monitor.Available()).To(Succeed())
+	})
+
+	It("doesn't make the callers wait for a probe in progress", func() {
+		monitor = httpchecker.NewHealthMonitor(mockChecker, testURL, time.Millisecond)
+		started := make(chan struct{})
+		release := make(chan struct{})
+		mockChecker.EXPECT().UrlAvailabilityCheck(testURL).DoAndReturn(func(string) error {
+			close(started)
+			<-release
+			return nil
+		})
+		time.Sleep(2 * time.Millisecond)
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			Expect(monitor.Available()).To(Succeed())
+			close(done)
+		}()
+		Eventually(started).Should(BeClosed())
+		Expect(monitor.Available()).To(Succeed())
+		close(release)
+		Eventually(done).Should(BeClosed())
+	})
+
+	It("refreshes the availability in the background", func() {
+		probed := make(chan struct{}, 10)
+		mockChecker.EXPECT().UrlAvailabilityCheck(testURL).DoAndReturn(func(string) error {
+			select {
+			case probed <- struct{}{}:
+			default:
+			}
+			return errProbe
+		}).MinTimes(2)
+		monitor = httpchecker.NewHealthMonitor(mockChecker, testURL, 10*time.Millisecond)
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			monitor.Run(ctx)
+			close(done)
+		}()
+		Eventually(probed).Should(Receive())
+		Eventually(probed).Should(Receive())
+		cancel()
+		Eventually(done).Should(BeClosed())
+	})
+
+	It("reports OCM as available with a nil monitor", func() {
+		var m *httpchecker.HealthMonitor
+		m.Observe(errProbe)
+		Expect(m.Available()).To(Succeed())
+	})
+})
@@ -0,0 +1,52 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// CacheSyncTimeout is the time the informers of the cache have to list the resources when it starts
+const CacheSyncTimeout = 2 * time.Minute
+
+// StartCache starts the informers caching the given resources of the namespace, kept up to date by watching them,
+// and returns the cache once they are synced. The informers stop with ctx. Reading other resources from the cache
+// fails, they have to be read from the API server.
+func StartCache(ctx context.Context, namespace string, objects ...client.Object) (client.Reader, error) {
+	cfg, err := ctrl.GetConfig()
+	if err != nil {
+		return nil, err
+	}
+	c, err := cache.New(cfg, cache.Options{
+		Scheme:                      newScheme(),
+		DefaultNamespaces:           map[string]cache.Config{namespace: {}},
+		ReaderFailOnMissingInformer: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The informers are registered before the cache starts, so that they are all synced when it is returned
+	for _, obj := range objects {
+		if _, err := c.GetInformer(ctx, obj); err != nil {
+			return nil, err
+		}
+	}
+	go func() {
+		if err := c.Start(ctx); err != nil {
+			log.WithError(err).Error("The informer cache stopped")
+		}
+	}()
+
+	syncCtx, cancel := context.WithTimeout(ctx, CacheSyncTimeout)
+	defer cancel()
+	if !c.WaitForCacheSync(syncCtx) {
+		return nil, errors.New("the informer cache didn't sync, ensure the resources can be listed and watched")
+	}
+	return c, nil
+}
@@ -3,9 +3,11 @@ package k8s
 import (
 	oav1alpha1 "github.com/openshift/ocm-agent-operator/api/v1alpha1"
 
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	coordinationv1client "k8s.io/client-go/kubernetes/typed/coordination/v1"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -21,14 +23,30 @@ func NewClient() (client.Client, error) {
 	if err != nil {
 		return nil, err
 	}
-	scheme := runtime.NewScheme()
-	_ = addKnownTypes(scheme)
 	c, err := client.New(cfg, client.Options{
-		Scheme: scheme,
+		Scheme: newScheme(),
 	})
 	return c, err
 }
 
+// newScheme returns the scheme of the agent's custom resources and of the core resources it uses, e.g. the
+// ConfigMap of the outbox
+func newScheme() *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	_ = addKnownTypes(scheme)
+	_ = corev1.AddToScheme(scheme)
+	return scheme
+}
+
+// NewCoordinationClient builds and returns a client for the coordination API holding the leader election Leases
+func NewCoordinationClient() (coordinationv1client.CoordinationV1Interface, error) {
+	cfg, err := ctrl.GetConfig()
+	if err != nil {
+		return nil, err
+	}
+	return coordinationv1client.NewForConfig(cfg)
+}
+
 func addKnownTypes(scheme *runtime.Scheme) error {
 	scheme.AddKnownTypes(SchemeGroupVersion,
 		&oav1alpha1.ManagedNotification{},
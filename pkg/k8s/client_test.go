@@ -0,0 +1,26 @@
+package k8s
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/openshift/ocm-agent/pkg/outbox"
+)
+
+var _ = Describe("Client scheme", func() {
+	It("persists the outbox in a ConfigMap", func() {
+		c := fake.NewClientBuilder().WithScheme(newScheme()).Build()
+		store := outbox.NewConfigMapStore(c, "openshift-ocm-agent-operator", "ocm-agent-outbox")
+		entries := []outbox.Entry{{Cluster: "cluster-1", Operation: "send_service_log", Notification: "test-notification"}}
+
+		// The ConfigMap is created on the first save and updated on the next ones
+		Expect(store.Save(context.TODO(), entries)).To(Succeed())
+		Expect(store.Save(context.TODO(), append(entries, entries[0]))).To(Succeed())
+		loaded, err := store.Load(context.TODO())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(loaded).To(HaveLen(2))
+	})
+})
@@ -0,0 +1,27 @@
+package k8s
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// StartEventRecorder starts sending the events of the component to the API server until ctx is cancelled, and returns
+// the recorder of the events. Similar events are aggregated and rate limited as set by the correlator options, see
+// record.CorrelatorOptions for the defaults.
+func StartEventRecorder(ctx context.Context, component string, options record.CorrelatorOptions) (record.EventRecorder, error) {
+	cfg, err := ctrl.GetConfig()
+	if err != nil {
+		return nil, err
+	}
+	c, err := typedcorev1.NewForConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	broadcaster := record.NewBroadcaster(record.WithContext(ctx), record.WithCorrelatorOptions(options))
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: c.Events("")})
+	return broadcaster.NewRecorder(newScheme(), corev1.EventSource{Component: component}), nil
+}
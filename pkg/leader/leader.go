@@ -0,0 +1,128 @@
+package leader
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	coordinationv1client "k8s.io/client-go/kubernetes/typed/coordination/v1"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+
+	"github.com/openshift/ocm-agent/pkg/metrics"
+)
+
+const (
+	// LeaseName is the name of the Lease held by the leader
+	LeaseName = "ocm-agent-leader"
+
+	DefaultLeaseDuration = 15 * time.Second
+	DefaultRenewDeadline = 10 * time.Second
+	DefaultRetryPeriod   = 2 * time.Second
+)
+
+// Elector elects, through a Kubernetes Lease, the single replica of the agent which sends the notifications.
+// The functions registered with OnStartedLeading only run on the leader.
+type Elector struct {
+	config  *leaderelection.LeaderElectionConfig
+	leading atomic.Bool
+	mu      sync.Mutex
+	tasks   []func(ctx context.Context)
+}
+
+// NewElector returns an elector competing for the Lease in the namespace under the given identity
+func NewElector(leases coordinationv1client.LeasesGetter, namespace, identity string) *Elector {
+	return &Elector{
+		config: &leaderelection.LeaderElectionConfig{
+			Lock: &resourcelock.LeaseLock{
+				LeaseMeta:  metav1.ObjectMeta{Namespace: namespace, Name: LeaseName},
+				Client:     leases,
+				LockConfig: resourcelock.ResourceLockConfig{Identity: identity},
+			},
+			LeaseDuration:   DefaultLeaseDuration,
+			RenewDeadline:   DefaultRenewDeadline,
+			RetryPeriod:     DefaultRetryPeriod,
+			ReleaseOnCancel: true,
+			Name:            LeaseName,
+		},
+	}
+}
+
+// NewSingleReplicaElector returns an elector which is always the leader, for deployments without leader election
+func NewSingleReplicaElector() *Elector {
+	return &Elector{}
+}
+
+// OnStartedLeading registers a function run once the replica becomes the leader, it must be called before Run.
+// The context passed to the function is cancelled when the leadership is lost.
+func (e *Elector) OnStartedLeading(fn func(ctx context.Context)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.tasks = append(e.tasks, fn)
+}
+
+// IsLeader indicates whether this replica currently holds the leadership
+func (e *Elector) IsLeader() bool {
+	return e.leading.Load()
+}
+
+// Run competes for the leadership until ctx is cancelled, the Lease is then released.
+// Losing the leadership otherwise is fatal, so that the work started by the leader can't go on concurrently
+// with the new leader's.
+func (e *Elector) Run(ctx context.Context) {
+	if e.config == nil {
+		e.startLeading(ctx)
+		<-ctx.Done()
+		e.setLeading(false)
+		return
+	}
+
+	config := *e.config
+	config.Callbacks = leaderelection.LeaderCallbacks{
+		OnStartedLeading: e.startLeading,
+		OnStoppedLeading: func() {
+			e.setLeading(false)
+			if ctx.Err() == nil {
+				log.Fatal("Leadership lost, exiting")
+			}
+			log.Info("Leadership released")
+		},
+		OnNewLeader: func(identity string) {
+			log.WithField("leader", identity).Info("New leader elected")
+		},
+	}
+	leaderelection.RunOrDie(ctx, config)
+}
+
+// Middleware answers the requests with 503 Service Unavailable when this replica isn't the leader, so that
+// Alertmanager delivers them again, possibly to the leader
+func (e *Elector) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !e.IsLeader() {
+			log.WithField("path", r.URL.Path).Debug("rejecting request as this replica is not the leader")
+			http.Error(w, "Not the leader, retry later", http.StatusServiceUnavailable)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (e *Elector) startLeading(ctx context.Context) {
+	log.Info("Started leading")
+	e.setLeading(true)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, fn := range e.tasks {
+		go fn(ctx)
+	}
+}
+
+func (e *Elector) setLeading(leading bool) {
+	e.leading.Store(leading)
+	metrics.SetLeader(leading)
+}
@@ -0,0 +1,13 @@
+package leader_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestLeaderSuite(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Leader Suite")
+}
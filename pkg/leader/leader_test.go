@@ -0,0 +1,98 @@
+package leader_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/openshift/ocm-agent/pkg/leader"
+)
+
+const testNamespace = "openshift-ocm-agent-operator"
+
+var _ = Describe("Leader election", func() {
+
+	var (
+		clientset *fake.Clientset
+		ctx       context.Context
+		cancel    context.CancelFunc
+		next      http.Handler
+	)
+
+	serve := func(e *leader.Elector) int {
+		rr := httptest.NewRecorder()
+		e.Middleware(next).ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/alertmanager-receiver", nil))
+		return rr.Code
+	}
+
+	BeforeEach(func() {
+		clientset = fake.NewSimpleClientset()
+		ctx, cancel = context.WithCancel(context.Background())
+		next = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		})
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	Context("When leader election is enabled", func() {
+		It("elects a single leader running the registered functions", func() {
+			started := make(chan struct{})
+			first := leader.NewElector(clientset.CoordinationV1(), testNamespace, "replica-1")
+			first.OnStartedLeading(func(ctx context.Context) { close(started) })
+			go first.Run(ctx)
+			Eventually(first.IsLeader, 5*time.Second).Should(BeTrue())
+			Eventually(started).Should(BeClosed())
+			Expect(serve(first)).To(Equal(http.StatusOK))
+
+			second := leader.NewElector(clientset.CoordinationV1(), testNamespace, "replica-2")
+			second.OnStartedLeading(func(ctx context.Context) { Fail("the second replica should not lead") })
+			go second.Run(ctx)
+			Consistently(second.IsLeader, 3*time.Second).Should(BeFalse())
+			Expect(serve(second)).To(Equal(http.StatusServiceUnavailable))
+
+			lease, err := clientset.CoordinationV1().Leases(testNamespace).Get(context.Background(), leader.LeaseName, metav1.GetOptions{})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(*lease.Spec.HolderIdentity).To(Equal("replica-1"))
+		})
+
+		It("rejects the requests before being elected", func() {
+			e := leader.NewElector(clientset.CoordinationV1(), testNamespace, "replica-1")
+			Expect(e.IsLeader()).To(BeFalse())
+			Expect(serve(e)).To(Equal(http.StatusServiceUnavailable))
+		})
+
+		It("releases the leadership when stopped", func() {
+			e := leader.NewElector(clientset.CoordinationV1(), testNamespace, "replica-1")
+			done := make(chan struct{})
+			go func() {
+				e.Run(ctx)
+				close(done)
+			}()
+			Eventually(e.IsLeader, 5*time.Second).Should(BeTrue())
+			cancel()
+			Eventually(done, 5*time.Second).Should(BeClosed())
+			Expect(e.IsLeader()).To(BeFalse())
+		})
+	})
+
+	Context("When leader election is disabled", func() {
+		It("always leads", func() {
+			started := make(chan struct{})
+			e := leader.NewSingleReplicaElector()
+			e.OnStartedLeading(func(ctx context.Context) { close(started) })
+			go e.Run(ctx)
+			Eventually(started).Should(BeClosed())
+			Expect(e.IsLeader()).To(BeTrue())
+			Expect(serve(e)).To(Equal(http.StatusOK))
+		})
+	})
+})
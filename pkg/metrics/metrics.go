@@ -2,6 +2,7 @@ package metrics
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/openshift/ocm-agent/pkg/consts"
@@ -87,6 +88,128 @@ var (
 			Help: "Pull Secret auth token is not valid",
 		}, []string{})
 
+	metricQueueDepth = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "ocm_agent_queue_depth",
+			Help: "The number of alerts waiting in the processing queue",
+		}, []string{})
+
+	metricQueueLatency = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "ocm_agent_queue_latency_seconds",
+			Help:    "The time between an alert being queued and its processing being completed",
+			Buckets: []float64{0.1, 0.5, 1, 5, 10, 30, 60, 120, 300, 600},
+		}, []string{})
+
+	metricQueueProcessingFailuresTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ocm_agent_queue_processing_failures_total",
+			Help: "A count of queued alerts which could not be processed successfully",
+		}, []string{})
+
+	metricDryRunNotificationsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ocm_agent_dry_run_notifications_total",
+			Help: "A count of OCM writes which were recorded instead of being sent because of the dry-run mode",
+		}, []string{"operation"})
+
+	metricAuthenticationFailuresTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ocm_agent_authentication_failures_total",
+			Help: "A count of requests rejected because they could not be authenticated",
+		}, []string{"path", "reason"})
+
+	metricLeader = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "ocm_agent_leader",
+			Help: "Whether this replica is the leader sending the notifications",
+		}, []string{})
+
+	metricRateLimitedWritesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ocm_agent_rate_limited_writes_total",
+			Help: "A count of OCM writes throttled by the rate limits",
+		}, []string{"operation", "scope", "outcome"})
+
+	metricDroppedAlertsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ocm_agent_dropped_alerts_total",
+			Help: "A count of alerts whose notification was dropped by the rate limits",
+		}, []string{"template"})
+
+	metricRateLimitDelay = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "ocm_agent_rate_limit_delay_seconds",
+			Help:    "The time OCM writes were delayed by the rate limits",
+			Buckets: []float64{0.1, 0.5, 1, 5, 10, 30, 60},
+		}, []string{"operation"})
+
+	metricCircuitBreakerState = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "ocm_agent_ocm_circuit_breaker_state",
+			Help: "The state of the circuit breaker of the OCM requests, 1 for the current state",
+		}, []string{"state"})
+
+	metricOCMAvailable = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "ocm_agent_ocm_available",
+			Help: "Whether OCM is available according to the health monitor",
+		}, []string{})
+
+	metricOCMRequestAttemptsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ocm_agent_ocm_request_attempts_total",
+			Help: "A count of the attempts of the OCM requests, retries included, by operation",
+		}, []string{"operation"})
+
+	metricOCMRequestGiveUpsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ocm_agent_ocm_request_give_ups_total",
+			Help: "A count of the OCM requests still failing transiently once their retries were exhausted, by operation",
+		}, []string{"operation"})
+
+	metricOutboxSize = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "ocm_agent_outbox_size",
+			Help: "The number of undelivered OCM writes waiting in the outbox to be replayed",
+		}, []string{})
+
+	metricOutboxDeadLettersTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ocm_agent_outbox_dead_letters_total",
+			Help: "A count of the OCM writes given up from the outbox without being delivered, by operation and reason",
+		}, []string{"operation", "reason"})
+
+	metricLimitedSupportDrift = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "ocm_agent_limited_support_drift",
+			Help: "The number of hosted clusters whose limited support differs from their notification record, found by the last reconciliation",
+		}, []string{"template", "drift"})
+
+	metricLimitedSupportDriftRepairedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ocm_agent_limited_support_drift_repaired_total",
+			Help: "A count of the stale limited support reasons removed by the reconciliation",
+		}, []string{"template"})
+
+	metricNotificationRecordItems = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "ocm_agent_notification_record_items",
+			Help: "The number of hosted cluster items of the ManagedFleetNotificationRecords of a management cluster",
+		}, []string{"management_cluster"})
+
+	metricNotificationRecordSize = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "ocm_agent_notification_record_size_bytes",
+			Help: "The size of the ManagedFleetNotificationRecords of a management cluster",
+		}, []string{"management_cluster"})
+
+	metricNotificationRecordItemsPrunedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ocm_agent_notification_record_items_pruned_total",
+			Help: "A count of the hosted cluster items pruned from the ManagedFleetNotificationRecords",
+		}, []string{"reason"})
+
 	metricsList = []prometheus.Collector{
 		metricRequestsTotal,
 		metricFailedRequestsTotal,
@@ -101,6 +224,26 @@ var (
 		metricLimitedSupportRemovedTotal,
 		metricFailedLimitedSupportSendsTotal,
 		metricFailedLimitedSupportRemovalsTotal,
+		metricQueueDepth,
+		metricQueueLatency,
+		metricQueueProcessingFailuresTotal,
+		metricDryRunNotificationsTotal,
+		metricAuthenticationFailuresTotal,
+		metricLeader,
+		metricRateLimitedWritesTotal,
+		metricDroppedAlertsTotal,
+		metricRateLimitDelay,
+		metricCircuitBreakerState,
+		metricOCMAvailable,
+		metricOCMRequestAttemptsTotal,
+		metricOCMRequestGiveUpsTotal,
+		metricOutboxSize,
+		metricOutboxDeadLettersTotal,
+		metricLimitedSupportDrift,
+		metricLimitedSupportDriftRepairedTotal,
+		metricNotificationRecordItems,
+		metricNotificationRecordSize,
+		metricNotificationRecordItemsPrunedTotal,
 	}
 )
 
@@ -240,6 +383,141 @@ func SetPullSecretInvalidMetricFailure() {
 	metricPullSecretInvalid.WithLabelValues().Set(float64(1))
 }
 
+// SetQueueDepth sets the number of alerts waiting in the processing queue
+func SetQueueDepth(depth int) {
+	metricQueueDepth.WithLabelValues().Set(float64(depth))
+}
+
+// ObserveQueueLatency records the time a queued alert took from being queued to being processed
+func ObserveQueueLatency(d time.Duration) {
+	metricQueueLatency.WithLabelValues().Observe(d.Seconds())
+}
+
+// CountQueueProcessingFailure counts the queued alerts which failed to be processed
+func CountQueueProcessingFailure() {
+	metricQueueProcessingFailuresTotal.WithLabelValues().Inc()
+}
+
+// CountDryRunNotification counts the OCM writes recorded by the dry-run mode by operation
+func CountDryRunNotification(operation string) {
+	metricDryRunNotificationsTotal.With(prometheus.Labels{
+		"operation": operation,
+	}).Inc()
+}
+
+// CountAuthenticationFailure counts the requests rejected by the authentication by path and reason
+func CountAuthenticationFailure(path, reason string) {
+	metricAuthenticationFailuresTotal.With(prometheus.Labels{
+		"path":   path,
+		"reason": reason,
+	}).Inc()
+}
+
+// SetLeader sets whether this replica is the leader
+func SetLeader(leader bool) {
+	value := float64(0)
+	if leader {
+		value = 1
+	}
+	metricLeader.WithLabelValues().Set(value)
+}
+
+// CountRateLimitThrottled counts the OCM writes throttled by the rate limits by operation, limit scope and outcome
+func CountRateLimitThrottled(operation, scope, outcome string) {
+	metricRateLimitedWritesTotal.With(prometheus.Labels{
+		"operation": operation,
+		"scope":     scope,
+		"outcome":   outcome,
+	}).Inc()
+}
+
+// CountDroppedAlert counts the alerts whose notification was dropped by the rate limits by notification template
+func CountDroppedAlert(template string) {
+	metricDroppedAlertsTotal.WithLabelValues(template).Inc()
+}
+
+// ObserveRateLimitDelay records the time an OCM write was delayed by the rate limits
+func ObserveRateLimitDelay(operation string, d time.Duration) {
+	metricRateLimitDelay.WithLabelValues(operation).Observe(d.Seconds())
+}
+
+// SetCircuitBreakerState sets the current state of the OCM circuit breaker
+func SetCircuitBreakerState(state string) {
+	metricCircuitBreakerState.Reset()
+	metricCircuitBreakerState.With(prometheus.Labels{
+		"state": state,
+	}).Set(1)
+}
+
+// SetOCMAvailable sets whether OCM is available
+func SetOCMAvailable(available bool) {
+	value := float64(0)
+	if available {
+		value = 1
+	}
+	metricOCMAvailable.WithLabelValues().Set(value)
+}
+
+// CountOCMRequestAttempt counts an attempt of an OCM request of the operation
+func CountOCMRequestAttempt(operation string) {
+	metricOCMRequestAttemptsTotal.WithLabelValues(operation).Inc()
+}
+
+// CountOCMRequestGiveUp counts an OCM request of the operation given up after its retries
+func CountOCMRequestGiveUp(operation string) {
+	metricOCMRequestGiveUpsTotal.WithLabelValues(operation).Inc()
+}
+
+// SetOutboxSize sets the number of entries of the outbox
+func SetOutboxSize(size int) {
+	metricOutboxSize.WithLabelValues().Set(float64(size))
+}
+
+// CountOutboxDeadLetter counts an OCM write of the operation given up from the outbox for the reason
+func CountOutboxDeadLetter(operation, reason string) {
+	metricOutboxDeadLettersTotal.With(prometheus.Labels{
+		"operation": operation,
+		"reason":    reason,
+	}).Inc()
+}
+
+// SetLimitedSupportDrift replaces the number of hosted clusters with a limited support drift by notification
+// template and drift
+func SetLimitedSupportDrift(drift map[string]map[string]int) {
+	metricLimitedSupportDrift.Reset()
+	for template, counts := range drift {
+		for kind, count := range counts {
+			metricLimitedSupportDrift.With(prometheus.Labels{
+				"template": template,
+				"drift":    kind,
+			}).Set(float64(count))
+		}
+	}
+}
+
+// CountLimitedSupportDriftRepaired counts a stale limited support reason removed by the reconciliation
+func CountLimitedSupportDriftRepaired(template string) {
+	metricLimitedSupportDriftRepairedTotal.WithLabelValues(template).Inc()
+}
+
+// SetNotificationRecordSize replaces the number of items and the size in bytes of the ManagedFleetNotificationRecords
+// by management cluster
+func SetNotificationRecordSize(items, size map[string]int) {
+	metricNotificationRecordItems.Reset()
+	for mc, count := range items {
+		metricNotificationRecordItems.WithLabelValues(mc).Set(float64(count))
+	}
+	metricNotificationRecordSize.Reset()
+	for mc, bytes := range size {
+		metricNotificationRecordSize.WithLabelValues(mc).Set(float64(bytes))
+	}
+}
+
+// CountNotificationRecordItemsPruned counts the items pruned from a ManagedFleetNotificationRecord by reason
+func CountNotificationRecordItemsPruned(reason string, count int) {
+	metricNotificationRecordItemsPrunedTotal.WithLabelValues(reason).Add(float64(count))
+}
+
 // ResetMetric reset the metric with Gauge values
 func ResetMetric(m *prometheus.GaugeVec) {
 	m.Reset()
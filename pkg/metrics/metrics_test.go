@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus/testutil"
 
@@ -263,6 +264,283 @@ var _ = Describe("Webhook Handlers", func() {
 			})
 		})
 	})
+
+	Context("Dry-run notifications metric", func() {
+		var (
+			metricHelpHeader = `
+# HELP ocm_agent_dry_run_notifications_total A count of OCM writes which were recorded instead of being sent because of the dry-run mode
+# TYPE ocm_agent_dry_run_notifications_total counter
+`
+			metricValueHeader = `ocm_agent_dry_run_notifications_total{operation="send_service_log"} `
+		)
+		When("the metric is incremented", func() {
+			It("counts the recorded operations", func() {
+				CountDryRunNotification("send_service_log")
+				CountDryRunNotification("send_service_log")
+				expectedMetric := fmt.Sprintf("%s%s%d\n", metricHelpHeader, metricValueHeader, 2)
+				err := testutil.CollectAndCompare(metricDryRunNotificationsTotal, strings.NewReader(expectedMetric))
+				Expect(err).To(BeNil())
+			})
+		})
+	})
+
+	Context("Authentication failures metric", func() {
+		var (
+			metricHelpHeader = `
+# HELP ocm_agent_authentication_failures_total A count of requests rejected because they could not be authenticated
+# TYPE ocm_agent_authentication_failures_total counter
+`
+			metricValueHeader = fmt.Sprintf(`ocm_agent_authentication_failures_total{path="%s",reason="invalid_token"} `, testPath)
+		)
+		When("the metric is incremented", func() {
+			It("counts the rejected requests", func() {
+				CountAuthenticationFailure(testPath, "invalid_token")
+				expectedMetric := fmt.Sprintf("%s%s%d\n", metricHelpHeader, metricValueHeader, 1)
+				err := testutil.CollectAndCompare(metricAuthenticationFailuresTotal, strings.NewReader(expectedMetric))
+				Expect(err).To(BeNil())
+			})
+		})
+	})
+
+	Context("Rate limited writes metric", func() {
+		var (
+			metricHelpHeader = `
+# HELP ocm_agent_rate_limited_writes_total A count of OCM writes throttled by the rate limits
+# TYPE ocm_agent_rate_limited_writes_total counter
+`
+			metricValueHeader = `ocm_agent_rate_limited_writes_total{operation="send_service_log",outcome="dropped",scope="cluster"} `
+		)
+		When("a write is throttled", func() {
+			It("counts the throttled writes", func() {
+				CountRateLimitThrottled("send_service_log", "cluster", "dropped")
+				CountRateLimitThrottled("send_service_log", "cluster", "dropped")
+				expectedMetric := fmt.Sprintf("%s%s%d\n", metricHelpHeader, metricValueHeader, 2)
+				err := testutil.CollectAndCompare(metricRateLimitedWritesTotal, strings.NewReader(expectedMetric))
+				Expect(err).To(BeNil())
+			})
+		})
+	})
+
+	Context("Dropped alerts metric", func() {
+		var (
+			metricHelpHeader = `
+# HELP ocm_agent_dropped_alerts_total A count of alerts whose notification was dropped by the rate limits
+# TYPE ocm_agent_dropped_alerts_total counter
+`
+			metricValueHeader = fmt.Sprintf(`ocm_agent_dropped_alerts_total{template="%s"} `, testTemplate)
+		)
+		When("an alert is dropped", func() {
+			It("counts the dropped alerts", func() {
+				CountDroppedAlert(testTemplate)
+				expectedMetric := fmt.Sprintf("%s%s%d\n", metricHelpHeader, metricValueHeader, 1)
+				err := testutil.CollectAndCompare(metricDroppedAlertsTotal, strings.NewReader(expectedMetric))
+				Expect(err).To(BeNil())
+			})
+		})
+	})
+
+	Context("Rate limit delay metric", func() {
+		When("a write is delayed", func() {
+			It("records the delay", func() {
+				ObserveRateLimitDelay("send_service_log", 2*time.Second)
+				Expect(testutil.CollectAndCount(metricRateLimitDelay)).To(Equal(1))
+			})
+		})
+	})
+
+	Context("Circuit breaker state metric", func() {
+		var (
+			metricHelpHeader = `
+# HELP ocm_agent_ocm_circuit_breaker_state The state of the circuit breaker of the OCM requests, 1 for the current state
+# TYPE ocm_agent_ocm_circuit_breaker_state gauge
+`
+		)
+		When("the state changes", func() {
+			It("only reports the current state", func() {
+				SetCircuitBreakerState("closed")
+				SetCircuitBreakerState("open")
+				expectedMetric := fmt.Sprintf("%s%s%d\n", metricHelpHeader, `ocm_agent_ocm_circuit_breaker_state{state="open"} `, 1)
+				err := testutil.CollectAndCompare(metricCircuitBreakerState, strings.NewReader(expectedMetric))
+				Expect(err).To(BeNil())
+			})
+		})
+	})
+
+	Context("OCM available metric", func() {
+		var (
+			metricHelpHeader = `
+# HELP ocm_agent_ocm_available Whether OCM is available according to the health monitor
+# TYPE ocm_agent_ocm_available gauge
+`
+		)
+		When("OCM becomes unavailable", func() {
+			It("sets the metric to 0", func() {
+				SetOCMAvailable(true)
+				SetOCMAvailable(false)
+				expectedMetric := fmt.Sprintf("%s%s%d\n", metricHelpHeader, `ocm_agent_ocm_available `, 0)
+				err := testutil.CollectAndCompare(metricOCMAvailable, strings.NewReader(expectedMetric))
+				Expect(err).To(BeNil())
+			})
+		})
+	})
+
+	Context("OCM request attempts metric", func() {
+		var (
+			metricHelpHeader = `
+# HELP ocm_agent_ocm_request_attempts_total A count of the attempts of the OCM requests, retries included, by operation
+# TYPE ocm_agent_ocm_request_attempts_total counter
+`
+		)
+		When("a request is retried", func() {
+			It("counts every attempt", func() {
+				CountOCMRequestAttempt("get_cluster")
+				CountOCMRequestAttempt("get_cluster")
+				expectedMetric := fmt.Sprintf("%s%s%d\n", metricHelpHeader, `ocm_agent_ocm_request_attempts_total{operation="get_cluster"} `, 2)
+				err := testutil.CollectAndCompare(metricOCMRequestAttemptsTotal, strings.NewReader(expectedMetric))
+				Expect(err).To(BeNil())
+			})
+		})
+	})
+
+	Context("OCM request give-ups metric", func() {
+		var (
+			metricHelpHeader = `
+# HELP ocm_agent_ocm_request_give_ups_total A count of the OCM requests still failing transiently once their retries were exhausted, by operation
+# TYPE ocm_agent_ocm_request_give_ups_total counter
+`
+		)
+		When("a request is given up", func() {
+			It("counts it", func() {
+				CountOCMRequestGiveUp("send_service_log")
+				expectedMetric := fmt.Sprintf("%s%s%d\n", metricHelpHeader, `ocm_agent_ocm_request_give_ups_total{operation="send_service_log"} `, 1)
+				err := testutil.CollectAndCompare(metricOCMRequestGiveUpsTotal, strings.NewReader(expectedMetric))
+				Expect(err).To(BeNil())
+			})
+		})
+	})
+
+	Context("Outbox size metric", func() {
+		var (
+			metricHelpHeader = `
+# HELP ocm_agent_outbox_size The number of undelivered OCM writes waiting in the outbox to be replayed
+# TYPE ocm_agent_outbox_size gauge
+`
+		)
+		When("the outbox changes", func() {
+			It("reports its current size", func() {
+				SetOutboxSize(3)
+				SetOutboxSize(2)
+				expectedMetric := fmt.Sprintf("%s%s%d\n", metricHelpHeader, `ocm_agent_outbox_size `, 2)
+				err := testutil.CollectAndCompare(metricOutboxSize, strings.NewReader(expectedMetric))
+				Expect(err).To(BeNil())
+			})
+		})
+	})
+
+	Context("Outbox dead letters metric", func() {
+		var (
+			metricHelpHeader = `
+# HELP ocm_agent_outbox_dead_letters_total A count of the OCM writes given up from the outbox without being delivered, by operation and reason
+# TYPE ocm_agent_outbox_dead_letters_total counter
+`
+		)
+		When("an OCM write is given up", func() {
+			It("counts it by operation and reason", func() {
+				CountOutboxDeadLetter("send_service_log", "expired")
+				expectedMetric := fmt.Sprintf("%s%s%d\n", metricHelpHeader, `ocm_agent_outbox_dead_letters_total{operation="send_service_log",reason="expired"} `, 1)
+				err := testutil.CollectAndCompare(metricOutboxDeadLettersTotal, strings.NewReader(expectedMetric))
+				Expect(err).To(BeNil())
+			})
+		})
+	})
+
+	Context("Limited support drift metrics", func() {
+		When("a reconciliation finds drifts", func() {
+			It("replaces the drifts of the previous reconciliation", func() {
+				SetLimitedSupportDrift(map[string]map[string]int{"old-template": {"missing": 1}})
+				SetLimitedSupportDrift(map[string]map[string]int{"test-template": {"stale": 2}})
+				expectedMetric := `
+# HELP ocm_agent_limited_support_drift The number of hosted clusters whose limited support differs from their notification record, found by the last reconciliation
+# TYPE ocm_agent_limited_support_drift gauge
+ocm_agent_limited_support_drift{drift="stale",template="test-template"} 2
+`
+				err := testutil.CollectAndCompare(metricLimitedSupportDrift, strings.NewReader(expectedMetric))
+				Expect(err).To(BeNil())
+			})
+		})
+		When("a stale limited support reason is removed", func() {
+			It("counts it", func() {
+				CountLimitedSupportDriftRepaired("test-template")
+				expectedMetric := `
+# HELP ocm_agent_limited_support_drift_repaired_total A count of the stale limited support reasons removed by the reconciliation
+# TYPE ocm_agent_limited_support_drift_repaired_total counter
+ocm_agent_limited_support_drift_repaired_total{template="test-template"} 1
+`
+				err := testutil.CollectAndCompare(metricLimitedSupportDriftRepairedTotal, strings.NewReader(expectedMetric))
+				Expect(err).To(BeNil())
+			})
+		})
+	})
+
+	Context("Notification record metrics", func() {
+		When("the records are measured", func() {
+			It("replaces the sizes of the previous measure", func() {
+				SetNotificationRecordSize(map[string]int{"old-mc": 1}, map[string]int{"old-mc": 100})
+				SetNotificationRecordSize(map[string]int{"test-mc": 3}, map[string]int{"test-mc": 512})
+				expectedMetric := `
+# HELP ocm_agent_notification_record_items The number of hosted cluster items of the ManagedFleetNotificationRecords of a management cluster
+# TYPE ocm_agent_notification_record_items gauge
+ocm_agent_notification_record_items{management_cluster="test-mc"} 3
+# HELP ocm_agent_notification_record_size_bytes The size of the ManagedFleetNotificationRecords of a management cluster
+# TYPE ocm_agent_notification_record_size_bytes gauge
+ocm_agent_notification_record_size_bytes{management_cluster="test-mc"} 512
+`
+				err := testutil.CollectAndCompare(metricNotificationRecordItems, strings.NewReader(expectedMetric), "ocm_agent_notification_record_items")
+				Expect(err).To(BeNil())
+				err = testutil.CollectAndCompare(metricNotificationRecordSize, strings.NewReader(expectedMetric), "ocm_agent_notification_record_size_bytes")
+				Expect(err).To(BeNil())
+			})
+		})
+		When("items are pruned", func() {
+			It("counts them by reason", func() {
+				CountNotificationRecordItemsPruned("idle", 2)
+				expectedMetric := `
+# HELP ocm_agent_notification_record_items_pruned_total A count of the hosted cluster items pruned from the ManagedFleetNotificationRecords
+# TYPE ocm_agent_notification_record_items_pruned_total counter
+ocm_agent_notification_record_items_pruned_total{reason="idle"} 2
+`
+				err := testutil.CollectAndCompare(metricNotificationRecordItemsPrunedTotal, strings.NewReader(expectedMetric))
+				Expect(err).To(BeNil())
+			})
+		})
+	})
+
+	Context("Leader metric", func() {
+		var (
+			metricHelpHeader = `
+# HELP ocm_agent_leader Whether this replica is the leader sending the notifications
+# TYPE ocm_agent_leader gauge
+`
+			metricValueHeader = `ocm_agent_leader `
+		)
+		When("the replica becomes the leader", func() {
+			It("sets the metric", func() {
+				SetLeader(true)
+				expectedMetric := fmt.Sprintf("%s%s%d\n", metricHelpHeader, metricValueHeader, 1)
+				err := testutil.CollectAndCompare(metricLeader, strings.NewReader(expectedMetric))
+				Expect(err).To(BeNil())
+			})
+		})
+		When("the replica loses the leadership", func() {
+			It("resets the metric", func() {
+				SetLeader(true)
+				SetLeader(false)
+				expectedMetric := fmt.Sprintf("%s%s%d\n", metricHelpHeader, metricValueHeader, 0)
+				err := testutil.CollectAndCompare(metricLeader, strings.NewReader(expectedMetric))
+				Expect(err).To(BeNil())
+			})
+		})
+	})
 })
 
 func resetMetrics() {
@@ -278,4 +556,21 @@ func resetMetrics() {
 	metricFailedLimitedSupportSendsTotal.Reset()
 	metricLimitedSupportRemovedTotal.Reset()
 	metricLimitedSupportSentTotal.Reset()
+	metricDryRunNotificationsTotal.Reset()
+	metricAuthenticationFailuresTotal.Reset()
+	metricLeader.Reset()
+	metricRateLimitedWritesTotal.Reset()
+	metricDroppedAlertsTotal.Reset()
+	metricRateLimitDelay.Reset()
+	metricCircuitBreakerState.Reset()
+	metricOCMAvailable.Reset()
+	metricOCMRequestAttemptsTotal.Reset()
+	metricOCMRequestGiveUpsTotal.Reset()
+	metricOutboxSize.Reset()
+	metricOutboxDeadLettersTotal.Reset()
+	metricLimitedSupportDrift.Reset()
+	metricLimitedSupportDriftRepairedTotal.Reset()
+	metricNotificationRecordItems.Reset()
+	metricNotificationRecordSize.Reset()
+	metricNotificationRecordItemsPrunedTotal.Reset()
 }
@@ -0,0 +1,178 @@
+package ocm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/openshift/ocm-agent/pkg/metrics"
+)
+
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half_open"
+
+	DefaultCircuitBreakerFailureRatio = 0.5
+	DefaultCircuitBreakerMinRequests  = 5
+	DefaultCircuitBreakerOpenTimeout  = 30 * time.Second
+	// DefaultCircuitBreakerWindow is the period the failure ratio is computed over
+	DefaultCircuitBreakerWindow = time.Minute
+)
+
+// ErrCircuitOpen is returned for the OCM requests which are not sent because OCM is failing
+var ErrCircuitOpen = errors.New("OCM circuit breaker is open, not sending the request")
+
+// CircuitBreakerConfig defines when the circuit breaker opens and for how long
+type CircuitBreakerConfig struct {
+	// FailureRatio is the ratio of failed requests over the window opening the circuit
+	FailureRatio float64
+	// MinRequests is the number of requests over the window needed to open the circuit
+	MinRequests int
+	// Window is the period the failure ratio is computed over
+	Window time.Duration
+	// OpenTimeout is the time the circuit stays open before letting a probe request through
+	OpenTimeout time.Duration
+}
+
+// CircuitBreaker tracks the failures of the OCM requests. It opens when too many of them fail, failing the next
+// requests fast, and half opens after a while to probe OCM with a single request which closes it again on success.
+type CircuitBreaker struct {
+	config      CircuitBreakerConfig
+	mu          sync.Mutex
+	state       string
+	windowStart time.Time
+	requests    int
+	failures    int
+	openedAt    time.Time
+	probing     bool
+}
+
+// NewCircuitBreaker returns a closed circuit breaker
+func NewCircuitBreaker(c CircuitBreakerConfig) *CircuitBreaker {
+	if c.Window <= 0 {
+		c.Window = DefaultCircuitBreakerWindow
+	}
+	if c.MinRequests < 1 {
+		c.MinRequests = 1
+	}
+	cb := &CircuitBreaker{config: c, windowStart: time.Now()}
+	cb.setState(CircuitClosed)
+	return cb
+}
+
+// State returns the current state of the circuit breaker
+func (cb *CircuitBreaker) State() string {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	return cb.currentState()
+}
+
+// Ready returns ErrCircuitOpen while the circuit is open
+func (cb *CircuitBreaker) Ready() error {
+	if cb.State() == CircuitOpen {
+		return ErrCircuitOpen
+	}
+	return nil
+}
+
+// Execute sends the request unless the circuit is open, send returns the status of the response
+func (cb *CircuitBreaker) Execute(send func() (int, error)) error {
+	probe, err := cb.allow()
+	if err != nil {
+		return err
+	}
+	status, err := send()
+	cb.record(probe, isOCMFailure(status, err))
+	return err
+}
+
+// allow returns whether the request can be sent and if it is the half open circuit's probe
+func (cb *CircuitBreaker) allow() (bool, error) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	switch cb.currentState() {
+	case CircuitOpen:
+		return false, ErrCircuitOpen
+	case CircuitHalfOpen:
+		if cb.probing {
+			return false, ErrCircuitOpen
+		}
+		cb.probing = true
+		return true, nil
+	}
+	return false, nil
+}
+
+// record updates the state with the outcome of a request
+func (cb *CircuitBreaker) record(probe, failed bool) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if probe {
+		cb.probing = false
+		if failed {
+			cb.open()
+		} else {
+			cb.resetWindow()
+			cb.setState(CircuitClosed)
+		}
+		return
+	}
+	// The requests sent before the circuit opened don't change its state any more
+	if cb.state != CircuitClosed {
+		return
+	}
+
+	if time.Since(cb.windowStart) > cb.config.Window {
+		cb.resetWindow()
+	}
+	cb.requests++
+	if failed {
+		cb.failures++
+	}
+	if cb.requests >= cb.config.MinRequests && float64(cb.failures)/float64(cb.requests) >= cb.config.FailureRatio {
+		cb.open()
+	}
+}
+
+// currentState half opens the circuit once the open timeout passed, callers must hold the lock
+func (cb *CircuitBreaker) currentState() string {
+	if cb.state == CircuitOpen && time.Since(cb.openedAt) >= cb.config.OpenTimeout {
+		cb.setState(CircuitHalfOpen)
+	}
+	return cb.state
+}
+
+func (cb *CircuitBreaker) open() {
+	cb.openedAt = time.Now()
+	cb.setState(CircuitOpen)
+}
+
+func (cb *CircuitBreaker) resetWindow() {
+	cb.windowStart = time.Now()
+	cb.requests = 0
+	cb.failures = 0
+}
+
+func (cb *CircuitBreaker) setState(state string) {
+	if cb.state != state {
+		log.WithFields(log.Fields{"from": cb.state, "to": state}).Info("OCM circuit breaker state changed")
+	}
+	cb.state = state
+	metrics.SetCircuitBreakerState(state)
+}
+
+// isOCMFailure tells whether a request failed because of OCM, i.e. it couldn't be reached or it failed to handle
+// the request, as opposed to the request being rejected
+func isOCMFailure(status int, err error) bool {
+	// A request cancelled by the caller doesn't tell anything about OCM
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	return status == 0 || status == http.StatusTooManyRequests || status >= http.StatusInternalServerError
+}
@@ -0,0 +1,157 @@
+package ocm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/ghttp"
+	sdk "github.com/openshift-online/ocm-sdk-go"
+	. "github.com/openshift-online/ocm-sdk-go/testing"
+
+	"github.com/openshift/ocm-agent/pkg/httpchecker"
+)
+
+var _ = Describe("OCM circuit breaker", func() {
+	var (
+		cb      *CircuitBreaker
+		errOCM  = errors.New("OCM failure")
+		fail    = func() (int, error) { return http.StatusServiceUnavailable, errOCM }
+		reject  = func() (int, error) { return http.StatusNotFound, errors.New("not found") }
+		succeed = func() (int, error) { return http.StatusOK, nil }
+	)
+
+	BeforeEach(func() {
+		cb = NewCircuitBreaker(CircuitBreakerConfig{
+			FailureRatio: 0.5,
+			MinRequests:  4,
+			Window:       time.Minute,
+			OpenTimeout:  50 * time.Millisecond,
+		})
+	})
+
+	It("stays closed until enough requests failed", func() {
+		Expect(cb.Execute(fail)).To(MatchError(errOCM))
+		Expect(cb.Execute(succeed)).To(Succeed())
+		Expect(cb.Execute(succeed)).To(Succeed())
+		Expect(cb.State()).To(Equal(CircuitClosed))
+		Expect(cb.Ready()).To(Succeed())
+	})
+
+	It("doesn't count the requests rejected by OCM as failures", func() {
+		for i := 0; i < 4; i++ {
+			Expect(cb.Execute(reject)).To(HaveOccurred())
+		}
+		Expect(cb.State()).To(Equal(CircuitClosed))
+	})
+
+	It("opens when the failure ratio is reached and fails fast", func() {
+		Expect(cb.Execute(succeed)).To(Succeed())
+		Expect(cb.Execute(fail)).To(MatchError(errOCM))
+		Expect(cb.Execute(succeed)).To(Succeed())
+		Expect(cb.Execute(fail)).To(MatchError(errOCM))
+		Expect(cb.State()).To(Equal(CircuitOpen))
+		Expect(cb.Ready()).To(MatchError(ErrCircuitOpen))
+
+		sent := false
+		err := cb.Execute(func() (int, error) {
+			sent = true
+			return http.StatusOK, nil
+		})
+		Expect(err).To(MatchError(ErrCircuitOpen))
+		Expect(sent).To(BeFalse())
+	})
+
+	Context("When the open timeout passed", func() {
+		BeforeEach(func() {
+			for i := 0; i < 4; i++ {
+				_ = cb.Execute(fail)
+			}
+			Expect(cb.State()).To(Equal(CircuitOpen))
+			Eventually(cb.State).Should(Equal(CircuitHalfOpen))
+		})
+
+		It("lets a single probe through", func() {
+			probing := make(chan struct{})
+			release := make(chan struct{})
+			done := make(chan error)
+			go func() {
+				done <- cb.Execute(func() (int, error) {
+					close(probing)
+					<-release
+					return http.StatusOK, nil
+				})
+			}()
+			<-probing
+			Expect(cb.Execute(succeed)).To(MatchError(ErrCircuitOpen))
+			close(release)
+			Expect(<-done).To(Succeed())
+		})
+
+		It("closes when the probe succeeds", func() {
+			Expect(cb.Execute(succeed)).To(Succeed())
+			Expect(cb.State()).To(Equal(CircuitClosed))
+			Expect(cb.Execute(fail)).To(MatchError(errOCM))
+			Expect(cb.State()).To(Equal(CircuitClosed))
+		})
+
+		It("opens again when the probe fails", func() {
+			Expect(cb.Execute(fail)).To(MatchError(errOCM))
+			Expect(cb.State()).To(Equal(CircuitOpen))
+		})
+	})
+
+	Context("When used by the OCM client", func() {
+		var (
+			mockServer *Server
+			ocmClient  OCMClient
+			health     *httpchecker.HealthMonitor
+		)
+
+		BeforeEach(func() {
+			mockServer = NewServer()
+			mockServer.SetAllowUnhandledRequests(true)
+			mockServer.SetUnhandledRequestStatusCode(http.StatusBadGateway)
+			ocmConnection, err := sdk.NewConnectionBuilder().
+				URL(mockServer.URL()).
+				Tokens(MakeTokenString("Bearer", 15*time.Minute)).
+				RetryLimit(0).
+				Build()
+			Expect(err).NotTo(HaveOccurred())
+			health = httpchecker.NewHealthMonitor(httpchecker.NewHTTPChecker(nil), mockServer.URL(), time.Hour)
+			ocmClient = NewOcmClient(ocmConnection, WithCircuitBreaker(cb), WithHealthMonitor(health))
+		})
+
+		AfterEach(func() {
+			mockServer.Close()
+		})
+
+		It("stops sending requests to a failing OCM", func() {
+			for i := 0; i < 4; i++ {
+				_, _, err := ocmClient.GetCluster(context.TODO(), "cluster-id")
+				Expect(err).Should(HaveOccurred())
+			}
+			Expect(mockServer.ReceivedRequests()).To(HaveLen(4))
+
+			_, err := ocmClient.SendServiceLog(context.TODO(), &ServiceLog{})
+			Expect(errors.Is(err, ErrCircuitOpen)).To(BeTrue())
+			Expect(mockServer.ReceivedRequests()).To(HaveLen(4))
+		})
+
+		It("reports the outcome of the requests to the health monitor", func() {
+			for i := 1; i <= httpchecker.HealthFailureThreshold; i++ {
+				_, _, err := ocmClient.GetCluster(context.TODO(), "cluster-id")
+				Expect(err).Should(HaveOccurred())
+				if i < httpchecker.HealthFailureThreshold {
+					Expect(health.Available()).Should(Succeed())
+				}
+			}
+			Expect(health.Available()).ShouldNot(Succeed())
+			// Only the requests were received, the health monitor didn't probe OCM
+			Expect(mockServer.ReceivedRequests()).To(HaveLen(httpchecker.HealthFailureThreshold))
+		})
+	})
+})
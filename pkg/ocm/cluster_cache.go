@@ -0,0 +1,107 @@
+package ocm
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+)
+
+const (
+	// DefaultClusterCacheTTL is how long the cluster metadata is reused before being fetched again from OCM
+	DefaultClusterCacheTTL = 10 * time.Minute
+	// MaxClusterCacheEntries bounds the number of clusters kept, the ones expiring first are evicted beyond it
+	MaxClusterCacheEntries = 1000
+
+	// ClusterPlaceHolderPrefix prefixes the place holders resolved with the cluster metadata, e.g. ${cluster.name}
+	ClusterPlaceHolderPrefix = "cluster."
+)
+
+type clusterCacheEntry struct {
+	cluster   *cmv1.Cluster
+	expiresAt time.Time
+}
+
+// ClusterCache keeps the clusters fetched from OCM by external ID so that
+// notifications for the same cluster don't query OCM every time. The expired clusters are evicted when a
+// cluster is added, and at most MaxClusterCacheEntries clusters are kept.
+type ClusterCache struct {
+	ocm     OCMClient
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]clusterCacheEntry
+}
+
+// NewClusterCache returns a cache fetching clusters with the given client and keeping them for ttl
+func NewClusterCache(ocm OCMClient, ttl time.Duration) *ClusterCache {
+	return &ClusterCache{
+		ocm:     ocm,
+		ttl:     ttl,
+		entries: map[string]clusterCacheEntry{},
+	}
+}
+
+// Get returns the cluster with the given external ID, from the cache if it has not expired yet
+func (c *ClusterCache) Get(ctx context.Context, externalID string) (*cmv1.Cluster, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[externalID]
+	c.mu.Unlock()
+	if ok && time.Now().Before(entry.expiresAt) {
+		return entry.cluster, nil
+	}
+
+	cluster, err := c.ocm.GetClusterByExternalID(ctx, externalID)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	c.evict(now)
+	c.entries[externalID] = clusterCacheEntry{cluster: cluster, expiresAt: now.Add(c.ttl)}
+	return cluster, nil
+}
+
+// Len returns the number of clusters in the cache
+func (c *ClusterCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// evict removes the expired clusters, then the ones expiring first until there is room for a new one.
+// It must be called with the lock held.
+func (c *ClusterCache) evict(now time.Time) {
+	for externalID, entry := range c.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(c.entries, externalID)
+		}
+	}
+	for len(c.entries) >= MaxClusterCacheEntries {
+		var oldest string
+		for externalID, entry := range c.entries {
+			if oldest == "" || entry.expiresAt.Before(c.entries[oldest].expiresAt) {
+				oldest = externalID
+			}
+		}
+		delete(c.entries, oldest)
+	}
+}
+
+// ClusterData returns the cluster metadata available to the notification templates, keyed without the "cluster." prefix
+func ClusterData(cluster *cmv1.Cluster) map[string]string {
+	version := cluster.OpenshiftVersion()
+	if version == "" {
+		version = cluster.Version().RawID()
+	}
+	return map[string]string{
+		"name":           cluster.Name(),
+		"version":        version,
+		"region":         cluster.Region().ID(),
+		"cloud_provider": cluster.CloudProvider().ID(),
+		"id":             cluster.ID(),
+		"external_id":    cluster.ExternalID(),
+	}
+}
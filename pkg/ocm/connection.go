@@ -29,6 +29,9 @@ func (b *ConnectionBuilder) Build(baseUrl string, clusterId string, accessToken
 	authToken := fmt.Sprintf("%v:%v", clusterId, accessToken)
 	builder.Tokens(authToken)
 
+	// The requests are retried by the OCM client, see WithRetries
+	builder.RetryLimit(0)
+
 	if b.logger != nil {
 		builder.Logger(*b.logger)
 	}
@@ -58,7 +61,10 @@ func (b *ConnectionBuilder) TransportWrapper(wrapper sdk.TransportWrapper) *Conn
 // Adapted from https://github.com/gdbranco/rosa/blob/9c5d9a00eef233a7989aca5ddca6762dc0f4d01d/pkg/ocm/clusters.go#L371
 func GetInternalIDByExternalID(externalID string, ocm *sdk.Connection) (string, error) {
 	log.Debugf("Getting internal ID from external ID %s", externalID)
-	query := fmt.Sprintf("external_id = '%s'", externalID)
+	query, err := externalIDSearch(externalID)
+	if err != nil {
+		return "", err
+	}
 
 	response, err := ocm.ClustersMgmt().V1().Clusters().List().
 		Search(query).
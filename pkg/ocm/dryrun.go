@@ -0,0 +1,106 @@
+package ocm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+	slv1 "github.com/openshift-online/ocm-sdk-go/servicelogs/v1"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/openshift/ocm-agent/pkg/metrics"
+)
+
+// DefaultDryRunRecords is the number of dry-run records kept in memory
+const DefaultDryRunRecords = 100
+
+// DryRunRecord is an OCM write which was recorded instead of being sent
+type DryRunRecord struct {
+	Time      time.Time       `json:"time"`
+	Operation string          `json:"operation"`
+	ClusterID string          `json:"clusterId"`
+	Payload   json.RawMessage `json:"payload,omitempty"`
+}
+
+// DryRunRecorder keeps the last dry-run records in a ring buffer
+type DryRunRecorder struct {
+	mu      sync.Mutex
+	records []DryRunRecord
+	next    int
+	full    bool
+}
+
+// NewDryRunRecorder returns a recorder keeping at most size records
+func NewDryRunRecorder(size int) *DryRunRecorder {
+	if size < 1 {
+		size = 1
+	}
+	return &DryRunRecorder{records: make([]DryRunRecord, size)}
+}
+
+// Record logs the operation, counts it and keeps it in the ring buffer
+func (r *DryRunRecorder) Record(operation, clusterID string, payload json.RawMessage) {
+	log.WithFields(log.Fields{"operation": operation, "cluster_id": clusterID, "payload": string(payload)}).Info("dry-run: not sending to OCM")
+	metrics.CountDryRunNotification(operation)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.records[r.next] = DryRunRecord{Time: time.Now(), Operation: operation, ClusterID: clusterID, Payload: payload}
+	r.next = (r.next + 1) % len(r.records)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// Records returns the recorded operations, oldest first
+func (r *DryRunRecorder) Records() []DryRunRecord {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.full {
+		return append([]DryRunRecord{}, r.records[:r.next]...)
+	}
+	return append(append([]DryRunRecord{}, r.records[r.next:]...), r.records[:r.next]...)
+}
+
+// dryRunClient records the OCM writes instead of sending them, reads are delegated to the wrapped client
+type dryRunClient struct {
+	OCMClient
+	recorder *DryRunRecorder
+}
+
+// NewDryRunClient wraps the client so that service logs and limited support changes are only recorded
+func NewDryRunClient(client OCMClient, recorder *DryRunRecorder) OCMClient {
+	return &dryRunClient{OCMClient: client, recorder: recorder}
+}
+
+// SendServiceLog records the service log, there's no OCM operation ID as it wasn't sent
+func (d *dryRunClient) SendServiceLog(_ context.Context, logEntry *slv1.LogEntry) (string, error) {
+	var b bytes.Buffer
+	if err := slv1.MarshalLogEntry(logEntry, &b); err != nil {
+		return "", err
+	}
+	d.recorder.Record(OperationSendServiceLog, logEntry.ClusterUUID(), b.Bytes())
+	return "", nil
+}
+
+// SendLimitedSupport records the limited support reason, the returned reason has no ID as it wasn't created
+func (d *dryRunClient) SendLimitedSupport(_ context.Context, clusterUUID string, lsReason *cmv1.LimitedSupportReason) (*cmv1.LimitedSupportReason, string, error) {
+	var b bytes.Buffer
+	if err := cmv1.MarshalLimitedSupportReason(lsReason, &b); err != nil {
+		return nil, "", err
+	}
+	d.recorder.Record(OperationSendLimitedSupport, clusterUUID, b.Bytes())
+	return lsReason, "", nil
+}
+
+func (d *dryRunClient) RemoveLimitedSupport(_ context.Context, clusterUUID string, lsReasonID string) (string, error) {
+	payload, err := json.Marshal(map[string]string{"id": lsReasonID})
+	if err != nil {
+		return "", err
+	}
+	d.recorder.Record(OperationRemoveLimitedSupport, clusterUUID, payload)
+	return "", nil
+}
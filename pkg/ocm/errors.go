@@ -0,0 +1,165 @@
+package ocm
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	sdkerrors "github.com/openshift-online/ocm-sdk-go/errors"
+)
+
+// ocmResponse is implemented by the responses of the OCM SDK, whose methods handle nil receivers
+type ocmResponse interface {
+	Status() int
+	Header() http.Header
+}
+
+// ResponseError is returned when OCM answers a request with an error status. Depending on the status, it is
+// wrapped by one of NotFoundError, UnauthorizedError, ForbiddenError, ConflictError, RateLimitedError or
+// ServerError, which callers can match with errors.As.
+type ResponseError struct {
+	// Operation is the name of the OCM operation, e.g. OperationSendServiceLog
+	Operation string
+	// Status is the HTTP status of the response
+	Status int
+	// OperationID identifies the request in OCM
+	OperationID string
+	// Body is the error returned by OCM, nil if the response has no error body, e.g. an unexpected redirect
+	Body *sdkerrors.Error
+	// RetryAfter is the delay requested by the Retry-After header of the response, 0 if there is none
+	RetryAfter time.Duration
+	err        error
+}
+
+func (e *ResponseError) Error() string {
+	msg := fmt.Sprintf("OCM %s request failed with status %d", e.Operation, e.Status)
+	if e.Body != nil && e.Body.Reason() != "" {
+		msg += ": " + e.Body.Reason()
+	}
+	if e.OperationID != "" {
+		msg += fmt.Sprintf(" (operation ID %s)", e.OperationID)
+	}
+	return msg
+}
+
+func (e *ResponseError) Unwrap() error {
+	return e.err
+}
+
+// ErrClusterNotFound is returned when OCM has no cluster with the requested external ID, e.g. once it is deleted
+var ErrClusterNotFound = errors.New("not found in OCM database")
+
+// ErrInvalidExternalID is returned for an external ID which can't be a cluster's, it is never sent to OCM
+var ErrInvalidExternalID = errors.New("invalid cluster external id")
+
+// NotFoundError is returned when OCM answers 404
+type NotFoundError struct{ *ResponseError }
+
+func (e *NotFoundError) Unwrap() error { return e.ResponseError }
+
+// UnauthorizedError is returned when OCM answers 401
+type UnauthorizedError struct{ *ResponseError }
+
+func (e *UnauthorizedError) Unwrap() error { return e.ResponseError }
+
+// ForbiddenError is returned when OCM answers 403
+type ForbiddenError struct{ *ResponseError }
+
+func (e *ForbiddenError) Unwrap() error { return e.ResponseError }
+
+// ConflictError is returned when OCM answers 409
+type ConflictError struct{ *ResponseError }
+
+func (e *ConflictError) Unwrap() error { return e.ResponseError }
+
+// RateLimitedError is returned when OCM answers 429
+type RateLimitedError struct{ *ResponseError }
+
+func (e *RateLimitedError) Unwrap() error { return e.ResponseError }
+
+// ServerError is returned when OCM answers a 5xx status
+type ServerError struct{ *ResponseError }
+
+func (e *ServerError) Unwrap() error { return e.ResponseError }
+
+// OperationID returns the OCM operation ID of the request that failed with err, empty if OCM didn't answer
+func OperationID(err error) string {
+	var re *ResponseError
+	if errors.As(err, &re) {
+		return re.OperationID
+	}
+	return ""
+}
+
+// IsTransient indicates whether the request failing with err could succeed when sent again later, i.e. unless
+// OCM rejected it with a 4xx status other than 429 or the cluster is unknown to OCM
+func IsTransient(err error) bool {
+	if errors.Is(err, ErrClusterNotFound) || errors.Is(err, ErrInvalidExternalID) {
+		return false
+	}
+	var responseErr *ResponseError
+	if !errors.As(err, &responseErr) {
+		return true
+	}
+	return responseErr.Status == http.StatusTooManyRequests || responseErr.Status >= http.StatusInternalServerError
+}
+
+// responseError returns the typed error of the operation's response, or err as is if OCM didn't answer or
+// answered successfully
+func responseError(operation string, resp ocmResponse, err error) error {
+	status := statusOf(resp)
+	if status < http.StatusMultipleChoices {
+		return err
+	}
+
+	re := &ResponseError{
+		Operation:   operation,
+		Status:      status,
+		OperationID: resp.Header().Get(OcmOperationIdHeader),
+		RetryAfter:  parseRetryAfter(resp.Header().Get("Retry-After")),
+		err:         err,
+	}
+	if errors.As(err, &re.Body) && re.OperationID == "" {
+		re.OperationID = re.Body.OperationID()
+	}
+
+	switch {
+	case status == http.StatusNotFound:
+		return &NotFoundError{re}
+	case status == http.StatusUnauthorized:
+		return &UnauthorizedError{re}
+	case status == http.StatusForbidden:
+		return &ForbiddenError{re}
+	case status == http.StatusConflict:
+		return &ConflictError{re}
+	case status == http.StatusTooManyRequests:
+		return &RateLimitedError{re}
+	case status >= http.StatusInternalServerError:
+		return &ServerError{re}
+	}
+	return re
+}
+
+// statusOf returns the status of the response, 0 if there is none
+func statusOf(resp ocmResponse) int {
+	if resp == nil {
+		return 0
+	}
+	return resp.Status()
+}
+
+// parseRetryAfter returns the delay of a Retry-After header, given either in seconds or as an HTTP date
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return time.Until(date)
+	}
+	return 0
+}
@@ -0,0 +1,126 @@
+package ocm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/ghttp"
+	sdk "github.com/openshift-online/ocm-sdk-go"
+	. "github.com/openshift-online/ocm-sdk-go/testing"
+)
+
+var _ = Describe("OCM errors", func() {
+	var (
+		mockServer *Server
+		ocmClient  OCMClient
+	)
+
+	BeforeEach(func() {
+		mockServer = NewServer()
+		ocmConnection, err := sdk.NewConnectionBuilder().
+			URL(mockServer.URL()).
+			Tokens(MakeTokenString("Bearer", 15*time.Minute)).
+			RetryLimit(0).
+			Build()
+		Expect(err).NotTo(HaveOccurred())
+		ocmClient = NewOcmClient(ocmConnection)
+	})
+
+	AfterEach(func() {
+		mockServer.Close()
+	})
+
+	respondWith := func(status int, header http.Header) {
+		header.Set("Content-Type", "application/json")
+		mockServer.AppendHandlers(CombineHandlers(
+			VerifyRequest("GET", "/api/clusters_mgmt/v1/clusters/cluster-id"),
+			RespondWith(
+				status,
+				`{"kind": "Error", "id": "42", "code": "CLUSTERS-MGMT-42", "reason": "Something went wrong", "operation_id": "body-operation-id"}`,
+				header,
+			),
+		))
+	}
+
+	DescribeTable("returns the typed error of the response status",
+		func(status int, target interface{}) {
+			respondWith(status, http.Header{})
+			_, _, err := ocmClient.GetCluster(context.TODO(), "cluster-id")
+			Expect(errors.As(err, target)).To(BeTrue())
+
+			var responseErr *ResponseError
+			Expect(errors.As(err, &responseErr)).To(BeTrue())
+			Expect(responseErr.Operation).To(Equal(OperationGetCluster))
+			Expect(responseErr.Status).To(Equal(status))
+			Expect(responseErr.Body.Code()).To(Equal("CLUSTERS-MGMT-42"))
+		},
+		Entry("not found", http.StatusNotFound, new(*NotFoundError)),
+		Entry("unauthorized", http.StatusUnauthorized, new(*UnauthorizedError)),
+		Entry("forbidden", http.StatusForbidden, new(*ForbiddenError)),
+		Entry("conflict", http.StatusConflict, new(*ConflictError)),
+		Entry("rate limited", http.StatusTooManyRequests, new(*RateLimitedError)),
+		Entry("server error", http.StatusServiceUnavailable, new(*ServerError)),
+	)
+
+	It("returns a generic response error for the other statuses", func() {
+		respondWith(http.StatusBadRequest, http.Header{})
+		_, _, err := ocmClient.GetCluster(context.TODO(), "cluster-id")
+		var responseErr *ResponseError
+		Expect(errors.As(err, &responseErr)).To(BeTrue())
+		Expect(responseErr.Status).To(Equal(http.StatusBadRequest))
+		Expect(errors.As(err, new(*NotFoundError))).To(BeFalse())
+		Expect(err.Error()).To(Equal("OCM get_cluster request failed with status 400: Something went wrong (operation ID body-operation-id)"))
+	})
+
+	It("takes the operation ID from the response header", func() {
+		respondWith(http.StatusNotFound, http.Header{OcmOperationIdHeader: []string{"header-operation-id"}})
+		_, operationID, err := ocmClient.GetCluster(context.TODO(), "cluster-id")
+		Expect(err).To(HaveOccurred())
+		Expect(operationID).To(Equal("header-operation-id"))
+		Expect(OperationID(err)).To(Equal("header-operation-id"))
+	})
+
+	It("falls back to the operation ID of the error body", func() {
+		respondWith(http.StatusNotFound, http.Header{})
+		_, operationID, err := ocmClient.GetCluster(context.TODO(), "cluster-id")
+		Expect(err).To(HaveOccurred())
+		Expect(operationID).To(Equal("body-operation-id"))
+	})
+
+	It("parses the delay requested by the Retry-After header", func() {
+		respondWith(http.StatusTooManyRequests, http.Header{"Retry-After": []string{"120"}})
+		_, _, err := ocmClient.GetCluster(context.TODO(), "cluster-id")
+		var rateLimitedErr *RateLimitedError
+		Expect(errors.As(err, &rateLimitedErr)).To(BeTrue())
+		Expect(rateLimitedErr.RetryAfter).To(Equal(2 * time.Minute))
+
+		Expect(parseRetryAfter(time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))).To(BeNumerically("~", time.Hour, time.Second))
+		Expect(parseRetryAfter("soon")).To(BeZero())
+	})
+
+	It("returns the error as is when OCM didn't answer", func() {
+		mockServer.Close()
+		_, operationID, err := ocmClient.GetCluster(context.TODO(), "cluster-id")
+		Expect(err).To(HaveOccurred())
+		Expect(operationID).To(BeEmpty())
+		Expect(errors.As(err, new(*ResponseError))).To(BeFalse())
+	})
+
+	DescribeTable("tells which failures are transient",
+		func(err error, expected bool) {
+			Expect(IsTransient(err)).To(Equal(expected))
+		},
+		Entry("a network error", errors.New("connection refused"), true),
+		Entry("a server error", &ServerError{&ResponseError{Status: http.StatusBadGateway}}, true),
+		Entry("a rate limited request", &RateLimitedError{&ResponseError{Status: http.StatusTooManyRequests}}, true),
+		Entry("a rejected request", &ResponseError{Status: http.StatusBadRequest}, false),
+		Entry("a missing cluster", &NotFoundError{&ResponseError{Status: http.StatusNotFound}}, false),
+		Entry("a cluster unknown to OCM", fmt.Errorf("cluster with external id cluster-id %w", ErrClusterNotFound), false),
+		Entry("an invalid external ID", ErrInvalidExternalID, false),
+	)
+})
@@ -5,6 +5,7 @@
 package mock_ocm
 
 import (
+	context "context"
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
@@ -36,9 +37,9 @@ func (m *MockOCMClient) EXPECT() *MockOCMClientMockRecorder {
 }
 
 // GetCluster mocks base method.
-func (m *MockOCMClient) GetCluster(clusterID string) (*v1.Cluster, string, error) {
+func (m *MockOCMClient) GetCluster(ctx context.Context, clusterID string) (*v1.Cluster, string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetCluster", clusterID)
+	ret := m.ctrl.Call(m, "GetCluster", ctx, clusterID)
 	ret0, _ := ret[0].(*v1.Cluster)
 	ret1, _ := ret[1].(string)
 	ret2, _ := ret[2].(error)
@@ -46,30 +47,45 @@ func (m *MockOCMClient) GetCluster(clusterID string) (*v1.Cluster, string, error
 }
 
 // GetCluster indicates an expected call of GetCluster.
-func (mr *MockOCMClientMockRecorder) GetCluster(clusterID interface{}) *gomock.Call {
+func (mr *MockOCMClientMockRecorder) GetCluster(ctx, clusterID interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCluster", reflect.TypeOf((*MockOCMClient)(nil).GetCluster), clusterID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCluster", reflect.TypeOf((*MockOCMClient)(nil).GetCluster), ctx, clusterID)
+}
+
+// GetClusterByExternalID mocks base method.
+func (m *MockOCMClient) GetClusterByExternalID(ctx context.Context, externalID string) (*v1.Cluster, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetClusterByExternalID", ctx, externalID)
+	ret0, _ := ret[0].(*v1.Cluster)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetClusterByExternalID indicates an expected call of GetClusterByExternalID.
+func (mr *MockOCMClientMockRecorder) GetClusterByExternalID(ctx, externalID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetClusterByExternalID", reflect.TypeOf((*MockOCMClient)(nil).GetClusterByExternalID), ctx, externalID)
 }
 
 // GetLimitedSupportReasons mocks base method.
-func (m *MockOCMClient) GetLimitedSupportReasons(clusterUUID string) ([]*v1.LimitedSupportReason, error) {
+func (m *MockOCMClient) GetLimitedSupportReasons(ctx context.Context, clusterUUID string) ([]*v1.LimitedSupportReason, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetLimitedSupportReasons", clusterUUID)
+	ret := m.ctrl.Call(m, "GetLimitedSupportReasons", ctx, clusterUUID)
 	ret0, _ := ret[0].([]*v1.LimitedSupportReason)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetLimitedSupportReasons indicates an expected call of GetLimitedSupportReasons.
-func (mr *MockOCMClientMockRecorder) GetLimitedSupportReasons(clusterUUID interface{}) *gomock.Call {
+func (mr *MockOCMClientMockRecorder) GetLimitedSupportReasons(ctx, clusterUUID interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLimitedSupportReasons", reflect.TypeOf((*MockOCMClient)(nil).GetLimitedSupportReasons), clusterUUID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLimitedSupportReasons", reflect.TypeOf((*MockOCMClient)(nil).GetLimitedSupportReasons), ctx, clusterUUID)
 }
 
 // GetUpgradePolicies mocks base method.
-func (m *MockOCMClient) GetUpgradePolicies(clusterID string) ([]*v1.UpgradePolicy, string, error) {
+func (m *MockOCMClient) GetUpgradePolicies(ctx context.Context, clusterID string) ([]*v1.UpgradePolicy, string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetUpgradePolicies", clusterID)
+	ret := m.ctrl.Call(m, "GetUpgradePolicies", ctx, clusterID)
 	ret0, _ := ret[0].([]*v1.UpgradePolicy)
 	ret1, _ := ret[1].(string)
 	ret2, _ := ret[2].(error)
@@ -77,15 +93,15 @@ func (m *MockOCMClient) GetUpgradePolicies(clusterID string) ([]*v1.UpgradePolic
 }
 
 // GetUpgradePolicies indicates an expected call of GetUpgradePolicies.
-func (mr *MockOCMClientMockRecorder) GetUpgradePolicies(clusterID interface{}) *gomock.Call {
+func (mr *MockOCMClientMockRecorder) GetUpgradePolicies(ctx, clusterID interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUpgradePolicies", reflect.TypeOf((*MockOCMClient)(nil).GetUpgradePolicies), clusterID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUpgradePolicies", reflect.TypeOf((*MockOCMClient)(nil).GetUpgradePolicies), ctx, clusterID)
 }
 
 // GetUpgradePolicy mocks base method.
-func (m *MockOCMClient) GetUpgradePolicy(clusterID, upgradePolicyID string) (*v1.UpgradePolicy, string, error) {
+func (m *MockOCMClient) GetUpgradePolicy(ctx context.Context, clusterID, upgradePolicyID string) (*v1.UpgradePolicy, string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetUpgradePolicy", clusterID, upgradePolicyID)
+	ret := m.ctrl.Call(m, "GetUpgradePolicy", ctx, clusterID, upgradePolicyID)
 	ret0, _ := ret[0].(*v1.UpgradePolicy)
 	ret1, _ := ret[1].(string)
 	ret2, _ := ret[2].(error)
@@ -93,15 +109,15 @@ func (m *MockOCMClient) GetUpgradePolicy(clusterID, upgradePolicyID string) (*v1
 }
 
 // GetUpgradePolicy indicates an expected call of GetUpgradePolicy.
-func (mr *MockOCMClientMockRecorder) GetUpgradePolicy(clusterID, upgradePolicyID interface{}) *gomock.Call {
+func (mr *MockOCMClientMockRecorder) GetUpgradePolicy(ctx, clusterID, upgradePolicyID interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUpgradePolicy", reflect.TypeOf((*MockOCMClient)(nil).GetUpgradePolicy), clusterID, upgradePolicyID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUpgradePolicy", reflect.TypeOf((*MockOCMClient)(nil).GetUpgradePolicy), ctx, clusterID, upgradePolicyID)
 }
 
 // GetUpgradePolicyState mocks base method.
-func (m *MockOCMClient) GetUpgradePolicyState(clusterID, upgradePolicyID string) (*v1.UpgradePolicyState, string, error) {
+func (m *MockOCMClient) GetUpgradePolicyState(ctx context.Context, clusterID, upgradePolicyID string) (*v1.UpgradePolicyState, string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetUpgradePolicyState", clusterID, upgradePolicyID)
+	ret := m.ctrl.Call(m, "GetUpgradePolicyState", ctx, clusterID, upgradePolicyID)
 	ret0, _ := ret[0].(*v1.UpgradePolicyState)
 	ret1, _ := ret[1].(string)
 	ret2, _ := ret[2].(error)
@@ -109,57 +125,61 @@ func (m *MockOCMClient) GetUpgradePolicyState(clusterID, upgradePolicyID string)
 }
 
 // GetUpgradePolicyState indicates an expected call of GetUpgradePolicyState.
-func (mr *MockOCMClientMockRecorder) GetUpgradePolicyState(clusterID, upgradePolicyID interface{}) *gomock.Call {
+func (mr *MockOCMClientMockRecorder) GetUpgradePolicyState(ctx, clusterID, upgradePolicyID interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUpgradePolicyState", reflect.TypeOf((*MockOCMClient)(nil).GetUpgradePolicyState), clusterID, upgradePolicyID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUpgradePolicyState", reflect.TypeOf((*MockOCMClient)(nil).GetUpgradePolicyState), ctx, clusterID, upgradePolicyID)
 }
 
 // RemoveLimitedSupport mocks base method.
-func (m *MockOCMClient) RemoveLimitedSupport(clusterUUID, lsReasonID string) error {
+func (m *MockOCMClient) RemoveLimitedSupport(ctx context.Context, clusterUUID, lsReasonID string) (string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "RemoveLimitedSupport", clusterUUID, lsReasonID)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret := m.ctrl.Call(m, "RemoveLimitedSupport", ctx, clusterUUID, lsReasonID)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // RemoveLimitedSupport indicates an expected call of RemoveLimitedSupport.
-func (mr *MockOCMClientMockRecorder) RemoveLimitedSupport(clusterUUID, lsReasonID interface{}) *gomock.Call {
+func (mr *MockOCMClientMockRecorder) RemoveLimitedSupport(ctx, clusterUUID, lsReasonID interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveLimitedSupport", reflect.TypeOf((*MockOCMClient)(nil).RemoveLimitedSupport), clusterUUID, lsReasonID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveLimitedSupport", reflect.TypeOf((*MockOCMClient)(nil).RemoveLimitedSupport), ctx, clusterUUID, lsReasonID)
 }
 
 // SendLimitedSupport mocks base method.
-func (m *MockOCMClient) SendLimitedSupport(clusterUUID string, lsReason *v1.LimitedSupportReason) error {
+func (m *MockOCMClient) SendLimitedSupport(ctx context.Context, clusterUUID string, lsReason *v1.LimitedSupportReason) (*v1.LimitedSupportReason, string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SendLimitedSupport", clusterUUID, lsReason)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret := m.ctrl.Call(m, "SendLimitedSupport", ctx, clusterUUID, lsReason)
+	ret0, _ := ret[0].(*v1.LimitedSupportReason)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
 }
 
 // SendLimitedSupport indicates an expected call of SendLimitedSupport.
-func (mr *MockOCMClientMockRecorder) SendLimitedSupport(clusterUUID, lsReason interface{}) *gomock.Call {
+func (mr *MockOCMClientMockRecorder) SendLimitedSupport(ctx, clusterUUID, lsReason interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendLimitedSupport", reflect.TypeOf((*MockOCMClient)(nil).SendLimitedSupport), clusterUUID, lsReason)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendLimitedSupport", reflect.TypeOf((*MockOCMClient)(nil).SendLimitedSupport), ctx, clusterUUID, lsReason)
 }
 
 // SendServiceLog mocks base method.
-func (m *MockOCMClient) SendServiceLog(logEntry *v10.LogEntry) error {
+func (m *MockOCMClient) SendServiceLog(ctx context.Context, logEntry *v10.LogEntry) (string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SendServiceLog", logEntry)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret := m.ctrl.Call(m, "SendServiceLog", ctx, logEntry)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // SendServiceLog indicates an expected call of SendServiceLog.
-func (mr *MockOCMClientMockRecorder) SendServiceLog(logEntry interface{}) *gomock.Call {
+func (mr *MockOCMClientMockRecorder) SendServiceLog(ctx, logEntry interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendServiceLog", reflect.TypeOf((*MockOCMClient)(nil).SendServiceLog), logEntry)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendServiceLog", reflect.TypeOf((*MockOCMClient)(nil).SendServiceLog), ctx, logEntry)
 }
 
 // UpdateUpgradePolicyState mocks base method.
-func (m *MockOCMClient) UpdateUpgradePolicyState(clusterID, upgradePolicyID string, policyState *v1.UpgradePolicyState) (*v1.UpgradePolicyState, string, error) {
+func (m *MockOCMClient) UpdateUpgradePolicyState(ctx context.Context, clusterID, upgradePolicyID string, policyState *v1.UpgradePolicyState) (*v1.UpgradePolicyState, string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateUpgradePolicyState", clusterID, upgradePolicyID, policyState)
+	ret := m.ctrl.Call(m, "UpdateUpgradePolicyState", ctx, clusterID, upgradePolicyID, policyState)
 	ret0, _ := ret[0].(*v1.UpgradePolicyState)
 	ret1, _ := ret[1].(string)
 	ret2, _ := ret[2].(error)
@@ -167,7 +187,7 @@ func (m *MockOCMClient) UpdateUpgradePolicyState(clusterID, upgradePolicyID stri
 }
 
 // UpdateUpgradePolicyState indicates an expected call of UpdateUpgradePolicyState.
-func (mr *MockOCMClientMockRecorder) UpdateUpgradePolicyState(clusterID, upgradePolicyID, policyState interface{}) *gomock.Call {
+func (mr *MockOCMClientMockRecorder) UpdateUpgradePolicyState(ctx, clusterID, upgradePolicyID, policyState interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUpgradePolicyState", reflect.TypeOf((*MockOCMClient)(nil).UpdateUpgradePolicyState), clusterID, upgradePolicyID, policyState)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUpgradePolicyState", reflect.TypeOf((*MockOCMClient)(nil).UpdateUpgradePolicyState), ctx, clusterID, upgradePolicyID, policyState)
 }
@@ -1,15 +1,21 @@
 package ocm
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"net/http"
 	"regexp"
+	"strings"
+	"time"
 
 	sdk "github.com/openshift-online/ocm-sdk-go"
 	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
 	slv1 "github.com/openshift-online/ocm-sdk-go/servicelogs/v1"
 	"github.com/openshift/ocm-agent-operator/api/v1alpha1"
+	"github.com/openshift/ocm-agent/pkg/backoff"
 	"github.com/openshift/ocm-agent/pkg/consts"
+	"github.com/openshift/ocm-agent/pkg/httpchecker"
+	"github.com/openshift/ocm-agent/pkg/metrics"
 	"github.com/prometheus/alertmanager/template"
 	log "github.com/sirupsen/logrus"
 )
@@ -20,12 +26,44 @@ const (
 	ServiceLogResolvePrefix = "Issue Resolution"
 )
 
+const (
+	// Names of the OCM write operations
+	OperationSendServiceLog           = "send_service_log"
+	OperationSendLimitedSupport       = "send_limited_support"
+	OperationRemoveLimitedSupport     = "remove_limited_support"
+	OperationUpdateUpgradePolicyState = "update_upgrade_policy_state"
+
+	// Names of the OCM read operations
+	OperationGetCluster               = "get_cluster"
+	OperationGetClusterByExternalID   = "get_cluster_by_external_id"
+	OperationGetUpgradePolicy         = "get_upgrade_policy"
+	OperationGetUpgradePolicyState    = "get_upgrade_policy_state"
+	OperationGetUpgradePolicies       = "get_upgrade_policies"
+	OperationGetLimitedSupportReasons = "get_limited_support_reasons"
+)
+
+// Operations lists the names of all the OCM operations
+var Operations = []string{
+	OperationSendServiceLog,
+	OperationSendLimitedSupport,
+	OperationRemoveLimitedSupport,
+	OperationUpdateUpgradePolicyState,
+	OperationGetCluster,
+	OperationGetClusterByExternalID,
+	OperationGetUpgradePolicy,
+	OperationGetUpgradePolicyState,
+	OperationGetUpgradePolicies,
+	OperationGetLimitedSupportReasons,
+}
+
 type ServiceLogBuilder struct {
 	wrappedBuilder *slv1.LogEntryBuilder
 	summary        string
 	firingDesc     string
 	resolveDesc    string
 	references     []v1alpha1.NotificationReferenceType
+	templateEngine string
+	clusterData    map[string]string
 }
 
 type ServiceLog = slv1.LogEntry
@@ -45,12 +83,67 @@ func NewServiceLogBuilder(summary, firingDesc, resolveDesc, clusterUUID string,
 	}
 }
 
+// WithTemplateEngine selects how the summary and descriptions are rendered with the alert data,
+// TemplateEnginePlaceHolder is used when the engine is empty
+func (b *ServiceLogBuilder) WithTemplateEngine(engine string) *ServiceLogBuilder {
+	b.templateEngine = engine
+	return b
+}
+
+// WithClusterData makes the cluster metadata returned by ClusterData available to the templates
+func (b *ServiceLogBuilder) WithClusterData(data map[string]string) *ServiceLogBuilder {
+	b.clusterData = data
+	return b
+}
+
+// NeedsClusterData indicates whether the templates reference the cluster metadata, so it only
+// has to be fetched from OCM for the notifications using it
+func (b *ServiceLogBuilder) NeedsClusterData() bool {
+	for _, s := range []string{b.summary, b.firingDesc, b.resolveDesc} {
+		switch b.templateEngine {
+		case "", TemplateEnginePlaceHolder:
+			if strings.Contains(s, "${"+ClusterPlaceHolderPrefix) {
+				return true
+			}
+		case TemplateEngineGoTemplate:
+			if goTemplateUsesCluster(s) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// render replaces the alert data in the given string using the builder's template engine
+func (b *ServiceLogBuilder) render(s string, alert *template.Alert) (string, error) {
+	switch b.templateEngine {
+	case "", TemplateEnginePlaceHolder:
+		return replacePlaceHoldersInString(s, alert, b.clusterData)
+	case TemplateEngineGoTemplate:
+		return renderGoTemplate(s, alert, b.clusterData)
+	default:
+		return "", fmt.Errorf("unknown template engine '%s'", b.templateEngine)
+	}
+}
+
 var (
 	slVarRefRe = regexp.MustCompile(`\${[^{}]*}`)
+	// externalIDRe matches the external IDs which can be quoted in a cluster search, e.g. UUIDs
+	externalIDRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
 )
 
-// Replace place holders in the given string with the alert labels and annotations
-func replacePlaceHoldersInString(s string, alert *template.Alert) (string, error) {
+// externalIDSearch returns the cluster search matching the external ID, which is validated first as it is
+// received in the alert labels
+func externalIDSearch(externalID string) (string, error) {
+	if !externalIDRe.MatchString(externalID) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidExternalID, externalID)
+	}
+	return fmt.Sprintf("external_id = '%s'", externalID), nil
+}
+
+// Replace place holders in the given string with the alert labels and annotations,
+// place holders prefixed with "cluster." are replaced with the given cluster metadata
+func replacePlaceHoldersInString(s string, alert *template.Alert, cluster map[string]string) (string, error) {
 	var err error
 	resolvePlaceHolder := func(placeHolder string) string {
 		if err == nil {
@@ -58,9 +151,11 @@ func replacePlaceHoldersInString(s string, alert *template.Alert) (string, error
 
 			if value, isOk = alert.Labels[key]; !isOk {
 				if value, isOk = alert.Annotations[key]; !isOk {
-					err = fmt.Errorf("alert has no '%s' label or annotation which could be used to replace place holders in the template", key)
+					if value, isOk = clusterValue(key, cluster); !isOk {
+						err = fmt.Errorf("alert has no '%s' label or annotation which could be used to replace place holders in the template", key)
 
-					return placeHolder
+						return placeHolder
+					}
 				}
 			}
 			return value
@@ -72,6 +167,15 @@ func replacePlaceHoldersInString(s string, alert *template.Alert) (string, error
 	return slVarRefRe.ReplaceAllStringFunc(s, resolvePlaceHolder), err
 }
 
+// clusterValue looks up a "cluster." prefixed place holder in the cluster metadata
+func clusterValue(key string, cluster map[string]string) (string, bool) {
+	if !strings.HasPrefix(key, ClusterPlaceHolderPrefix) {
+		return "", false
+	}
+	value, ok := cluster[strings.TrimPrefix(key, ClusterPlaceHolderPrefix)]
+	return value, ok
+}
+
 func (b *ServiceLogBuilder) Build(firing bool, alert *template.Alert) (*ServiceLog, error) {
 	var summary, description string
 	var docReferences []string
@@ -89,10 +193,10 @@ func (b *ServiceLogBuilder) Build(firing bool, alert *template.Alert) (*ServiceL
 	if alert != nil {
 		var err error
 
-		if summary, err = replacePlaceHoldersInString(summary, alert); err != nil {
+		if summary, err = b.render(summary, alert); err != nil {
 			return nil, err
 		}
-		if description, err = replacePlaceHoldersInString(description, alert); err != nil {
+		if description, err = b.render(description, alert); err != nil {
 			return nil, err
 		}
 	}
@@ -115,75 +219,220 @@ func (b *ServiceLogBuilder) Build(firing bool, alert *template.Alert) (*ServiceL
 }
 
 type OCMClient interface {
-	SendServiceLog(logEntry *slv1.LogEntry) error
-	SendLimitedSupport(clusterUUID string, lsReason *cmv1.LimitedSupportReason) error
-	RemoveLimitedSupport(clusterUUID string, lsReasonID string) error
-	GetLimitedSupportReasons(clusterUUID string) ([]*cmv1.LimitedSupportReason, error)
-	GetCluster(clusterID string) (*cmv1.Cluster, string, error)
-	GetUpgradePolicyState(clusterID string, upgradePolicyID string) (*cmv1.UpgradePolicyState, string, error)
-	GetUpgradePolicy(clusterID string, upgradePolicyID string) (*cmv1.UpgradePolicy, string, error)
-	GetUpgradePolicies(clusterID string) ([]*cmv1.UpgradePolicy, string, error)
-	UpdateUpgradePolicyState(clusterID string, upgradePolicyID string, policyState *cmv1.UpgradePolicyState) (*cmv1.UpgradePolicyState, string, error)
+	SendServiceLog(ctx context.Context, logEntry *slv1.LogEntry) (string, error)
+	SendLimitedSupport(ctx context.Context, clusterUUID string, lsReason *cmv1.LimitedSupportReason) (*cmv1.LimitedSupportReason, string, error)
+	RemoveLimitedSupport(ctx context.Context, clusterUUID string, lsReasonID string) (string, error)
+	GetLimitedSupportReasons(ctx context.Context, clusterUUID string) ([]*cmv1.LimitedSupportReason, error)
+	GetCluster(ctx context.Context, clusterID string) (*cmv1.Cluster, string, error)
+	GetClusterByExternalID(ctx context.Context, externalID string) (*cmv1.Cluster, error)
+	GetUpgradePolicyState(ctx context.Context, clusterID string, upgradePolicyID string) (*cmv1.UpgradePolicyState, string, error)
+	GetUpgradePolicy(ctx context.Context, clusterID string, upgradePolicyID string) (*cmv1.UpgradePolicy, string, error)
+	GetUpgradePolicies(ctx context.Context, clusterID string) ([]*cmv1.UpgradePolicy, string, error)
+	UpdateUpgradePolicyState(ctx context.Context, clusterID string, upgradePolicyID string, policyState *cmv1.UpgradePolicyState) (*cmv1.UpgradePolicyState, string, error)
 }
 
 type ocmClientImpl struct {
 	ocmConnection *sdk.Connection
+	breaker       *CircuitBreaker
+	health        *httpchecker.HealthMonitor
+	timeouts      Timeouts
+	retries       *backoff.Config
+}
+
+// ClientOption configures the OCM client
+type ClientOption func(*ocmClientImpl)
+
+// WithCircuitBreaker fails the requests fast while the circuit breaker is open
+func WithCircuitBreaker(breaker *CircuitBreaker) ClientOption {
+	return func(o *ocmClientImpl) {
+		o.breaker = breaker
+	}
 }
 
 //go:generate mockgen -destination=mocks/ocm.go -package=mocks github.com/openshift/ocm-agent/pkg/ocm OCMClient
-func NewOcmClient(ocmConnection *sdk.Connection) OCMClient {
-	return &ocmClientImpl{
+func NewOcmClient(ocmConnection *sdk.Connection, opts ...ClientOption) OCMClient {
+	o := &ocmClientImpl{
 		ocmConnection: ocmConnection,
 	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithHealthMonitor reports the outcome of the requests to the health monitor
+func WithHealthMonitor(health *httpchecker.HealthMonitor) ClientOption {
+	return func(o *ocmClientImpl) {
+		o.health = health
+	}
+}
+
+// WithTimeouts bounds the duration of the requests by operation
+func WithTimeouts(timeouts Timeouts) ClientOption {
+	return func(o *ocmClientImpl) {
+		o.timeouts = timeouts
+	}
+}
+
+// WithRetries retries the requests failing transiently according to the backoff, see isRetriable. The delay
+// requested by OCM with a Retry-After header is honored.
+func WithRetries(config backoff.Config) ClientOption {
+	return func(o *ocmClientImpl) {
+		if config.MaxAttempts > 1 {
+			o.retries = &config
+		}
+	}
+}
+
+// call sends a request of the operation to OCM, retrying it if needed. The error answered by OCM is returned as a
+// typed error, see ResponseError. The outcome of the request, once retried, is reported to the health monitor.
+func (o *ocmClientImpl) call(ctx context.Context, operation string, send func(ctx context.Context) (ocmResponse, error)) error {
+	status := 0
+	attempt := func() error {
+		var err error
+		status, err = o.attempt(ctx, operation, send)
+		return err
+	}
+	err := o.retry(ctx, operation, attempt)
+	// A request rejected by the open circuit wasn't sent, it doesn't tell anything new about OCM
+	if !errors.Is(err, ErrCircuitOpen) {
+		if isOCMFailure(status, err) {
+			o.health.Observe(err)
+		} else {
+			o.health.Observe(nil)
+		}
+	}
+	return err
+}
+
+// retry makes the attempts of a request of the operation according to the retries of the client
+func (o *ocmClientImpl) retry(ctx context.Context, operation string, attempt func() error) error {
+	if o.retries == nil {
+		return attempt()
+	}
+
+	retriable := false
+	config := *o.retries
+	config.Retriable = func(err error) bool {
+		// The attempts are bounded by their own timeout, the request is only aborted with its context
+		retriable = ctx.Err() == nil && isRetriable(operation, err)
+		return retriable
+	}
+	config.RetryAfter = retryAfter
+	config.OnRetry = func(attempt int, err error, delay time.Duration) {
+		log.WithError(err).WithField("operation", operation).Debugf("Retrying the OCM request in %s after %d attempts", delay, attempt)
+	}
+	err := backoff.Retry(ctx, config, attempt)
+	if err != nil && retriable {
+		metrics.CountOCMRequestGiveUp(operation)
+	}
+	return err
+}
+
+// attempt sends a request of the operation to OCM through the circuit breaker, if any, within the timeout of the
+// operation. It returns the status of the response, 0 if there is none.
+func (o *ocmClientImpl) attempt(ctx context.Context, operation string, send func(ctx context.Context) (ocmResponse, error)) (int, error) {
+	if timeout := o.timeouts.For(operation); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	status := 0
+	sent := func() (int, error) {
+		metrics.CountOCMRequestAttempt(operation)
+		resp, err := send(ctx)
+		status = statusOf(resp)
+		return status, responseError(operation, resp, err)
+	}
+	if o.breaker == nil {
+		return sent()
+	}
+	err := o.breaker.Execute(sent)
+	return status, err
 }
 
 // https://pkg.go.dev/github.com/openshift-online/ocm-sdk-go@v0.1.382/clustersmgmt/v1#Cluster
-func (o *ocmClientImpl) GetCluster(clusterID string) (*cmv1.Cluster, string, error) {
+func (o *ocmClientImpl) GetCluster(ctx context.Context, clusterID string) (*cmv1.Cluster, string, error) {
 	log.Debugf("Sending get cluster object request to OCM API: %s", clusterID)
 	request := o.ocmConnection.ClustersMgmt().V1().Clusters().Cluster(clusterID)
-	resp, err := request.Get().Send()
+	var resp *cmv1.ClusterGetResponse
+	err := o.call(ctx, OperationGetCluster, func(ctx context.Context) (ocmResponse, error) {
+		var err error
+		resp, err = request.Get().SendContext(ctx)
+		return resp, err
+	})
 	if err != nil {
-		return nil, resp.Header().Get(OcmOperationIdHeader), err
+		return nil, OperationID(err), err
 	}
 
-	if resp.Status() < 200 || resp.Status() >= 300 {
-		// Extract error details from the resp and return an appropriate error.
-		return nil, resp.Header().Get(OcmOperationIdHeader), fmt.Errorf("unexpected status: %d", resp.Status())
+	return resp.Body(), resp.Header().Get(OcmOperationIdHeader), nil
+}
+
+// GetClusterByExternalID gets the cluster with the given external ID
+func (o *ocmClientImpl) GetClusterByExternalID(ctx context.Context, externalID string) (*cmv1.Cluster, error) {
+	log.Debugf("Sending get cluster by external ID request to OCM API: %s", externalID)
+	search, err := externalIDSearch(externalID)
+	if err != nil {
+		return nil, err
+	}
+	var resp *cmv1.ClustersListResponse
+	err = o.call(ctx, OperationGetClusterByExternalID, func(ctx context.Context) (ocmResponse, error) {
+		var err error
+		resp, err = o.ocmConnection.ClustersMgmt().V1().Clusters().List().
+			Search(search).
+			Page(1).
+			Size(1).
+			SendContext(ctx)
+		return resp, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("can't get cluster with external id %s: %w", externalID, err)
+	}
+	if resp.Total() < 1 {
+		return nil, fmt.Errorf("cluster with external id %s %w", externalID, ErrClusterNotFound)
 	}
+	return resp.Items().Get(0), nil
+}
 
-	return resp.Body(), resp.Header().Get(OcmOperationIdHeader), nil
+// internalID returns the OCM ID of the cluster with the given external ID
+func (o *ocmClientImpl) internalID(ctx context.Context, externalID string) (string, error) {
+	cluster, err := o.GetClusterByExternalID(ctx, externalID)
+	if err != nil {
+		return "", err
+	}
+	return cluster.ID(), nil
 }
 
 // GetUpgradePolicy gets a single upgrade policy from a cluster.
 // Proxies to https://api.openshift.com/#/default/get_api_clusters_mgmt_v1_clusters__cluster_id__upgrade_policies__upgrade_policy_id_
-func (o *ocmClientImpl) GetUpgradePolicy(clusterID string, upgradePolicyID string) (*cmv1.UpgradePolicy, string, error) {
+func (o *ocmClientImpl) GetUpgradePolicy(ctx context.Context, clusterID string, upgradePolicyID string) (*cmv1.UpgradePolicy, string, error) {
 	log.Debugf("Sending get upgrade policy request to OCM API: %s %s", clusterID, upgradePolicyID)
 	request := o.ocmConnection.ClustersMgmt().V1().Clusters().Cluster(clusterID).UpgradePolicies().UpgradePolicy(upgradePolicyID)
-	resp, err := request.Get().Send()
+	var resp *cmv1.UpgradePolicyGetResponse
+	err := o.call(ctx, OperationGetUpgradePolicy, func(ctx context.Context) (ocmResponse, error) {
+		var err error
+		resp, err = request.Get().SendContext(ctx)
+		return resp, err
+	})
 	if err != nil {
-		return nil, resp.Header().Get(OcmOperationIdHeader), err
-	}
-
-	if resp.Status() < 200 || resp.Status() >= 300 {
-		// Extract error details from the resp and return an appropriate error.
-		return nil, resp.Header().Get(OcmOperationIdHeader), fmt.Errorf("unexpected status: %d", resp.Status())
+		return nil, OperationID(err), err
 	}
 	return resp.Body(), resp.Header().Get(OcmOperationIdHeader), nil
 }
 
 // GetUpgradePolicy gets a single upgrade policy's state from a cluster.
 // Proxies to https://api.openshift.com#/default/get_api_clusters_mgmt_v1_clusters__cluster_id__upgrade_policies__upgrade_policy_id__state
-func (o *ocmClientImpl) GetUpgradePolicyState(clusterID string, upgradePolicyID string) (*cmv1.UpgradePolicyState, string, error) {
+func (o *ocmClientImpl) GetUpgradePolicyState(ctx context.Context, clusterID string, upgradePolicyID string) (*cmv1.UpgradePolicyState, string, error) {
 	log.Debugf("Sending get upgrade policy state request to OCM API: %s", clusterID)
 	request := o.ocmConnection.ClustersMgmt().V1().Clusters().Cluster(clusterID).UpgradePolicies().UpgradePolicy(upgradePolicyID).State()
-	resp, err := request.Get().Send()
+	var resp *cmv1.UpgradePolicyStateGetResponse
+	err := o.call(ctx, OperationGetUpgradePolicyState, func(ctx context.Context) (ocmResponse, error) {
+		var err error
+		resp, err = request.Get().SendContext(ctx)
+		return resp, err
+	})
 	if err != nil {
-		return nil, resp.Header().Get(OcmOperationIdHeader), err
-	}
-
-	if resp.Status() < 200 || resp.Status() >= 300 {
-		// Extract error details from the resp and return an appropriate error.
-		return nil, resp.Header().Get(OcmOperationIdHeader), fmt.Errorf("unexpected status: %d", resp.Status())
+		return nil, OperationID(err), err
 	}
 	return resp.Body(), resp.Header().Get(OcmOperationIdHeader), nil
 }
@@ -191,7 +440,7 @@ func (o *ocmClientImpl) GetUpgradePolicyState(clusterID string, upgradePolicyID
 // GetUpgradePolicies gets all of the upgrade policies belonging to a cluster from OCM.
 // It does not paginate, and sends the whole list as a single list.
 // Proxies to https://api.openshift.com/#/default/get_api_clusters_mgmt_v1_clusters__cluster_id__upgrade_policies
-func (o *ocmClientImpl) GetUpgradePolicies(clusterID string) ([]*cmv1.UpgradePolicy, string, error) {
+func (o *ocmClientImpl) GetUpgradePolicies(ctx context.Context, clusterID string) ([]*cmv1.UpgradePolicy, string, error) {
 	var upgradePolicies []*cmv1.UpgradePolicy
 	var operationIdHeader string
 
@@ -201,15 +450,15 @@ func (o *ocmClientImpl) GetUpgradePolicies(clusterID string) ([]*cmv1.UpgradePol
 	size := consts.OCMListRequestMaxPerPage
 
 	for {
-		resp, err := collection.List().Send()
+		var resp *cmv1.UpgradePoliciesListResponse
+		err := o.call(ctx, OperationGetUpgradePolicies, func(ctx context.Context) (ocmResponse, error) {
+			var err error
+			resp, err = collection.List().SendContext(ctx)
+			return resp, err
+		})
 
 		if err != nil {
-			return nil, resp.Header().Get(OcmOperationIdHeader), err
-		}
-
-		if resp.Status() < 200 || resp.Status() >= 300 {
-			// Extract error details from the resp and return an appropriate error.
-			return nil, resp.Header().Get(OcmOperationIdHeader), fmt.Errorf("unexpected status: %d", resp.Status())
+			return nil, OperationID(err), err
 		}
 
 		upgradePolicies = append(upgradePolicies, resp.Items().Slice()...)
@@ -225,100 +474,104 @@ func (o *ocmClientImpl) GetUpgradePolicies(clusterID string) ([]*cmv1.UpgradePol
 
 // UpdateUpgradePolicyState updates a single upgrade policy's state for a given cluster.
 // Proxies to https://api.openshift.com/#/default/patch_api_clusters_mgmt_v1_clusters__cluster_id__upgrade_policies__upgrade_policy_id__state
-func (o *ocmClientImpl) UpdateUpgradePolicyState(clusterID string, upgradePolicyID string, policyState *cmv1.UpgradePolicyState) (*cmv1.UpgradePolicyState, string, error) {
+func (o *ocmClientImpl) UpdateUpgradePolicyState(ctx context.Context, clusterID string, upgradePolicyID string, policyState *cmv1.UpgradePolicyState) (*cmv1.UpgradePolicyState, string, error) {
 	log.Debugf("Sending update upgrade policy state request to OCM API: %s %s", clusterID, upgradePolicyID)
 	request := o.ocmConnection.ClustersMgmt().V1().Clusters().Cluster(clusterID).UpgradePolicies().UpgradePolicy(upgradePolicyID).State().Update().Body(policyState)
-	resp, err := request.Send()
+	var resp *cmv1.UpgradePolicyStateUpdateResponse
+	err := o.call(ctx, OperationUpdateUpgradePolicyState, func(ctx context.Context) (ocmResponse, error) {
+		var err error
+		resp, err = request.SendContext(ctx)
+		return resp, err
+	})
 	if err != nil {
-		return nil, resp.Header().Get(OcmOperationIdHeader), err
+		return nil, OperationID(err), err
 	}
 	return resp.Body(), resp.Header().Get(OcmOperationIdHeader), nil
 }
 
-func (o *ocmClientImpl) SendServiceLog(logEntry *slv1.LogEntry) error {
+// SendServiceLog posts the service log and returns the ID of the OCM operation
+func (o *ocmClientImpl) SendServiceLog(ctx context.Context, logEntry *slv1.LogEntry) (string, error) {
 	// Use the OCM SDK to construct the request for posting a service log for a specific cluster.
 	request := o.ocmConnection.ServiceLogs().V1().ClusterLogs().Add().Body(logEntry)
 
 	// Send the request to the OCM API.
-	response, err := request.Send()
+	var response *slv1.ClusterLogsAddResponse
+	err := o.call(ctx, OperationSendServiceLog, func(ctx context.Context) (ocmResponse, error) {
+		var err error
+		response, err = request.SendContext(ctx)
+		return response, err
+	})
 	if err != nil {
-		return fmt.Errorf("can't post service log: %v", err)
-	}
-
-	// Check the response status code.
-	if response.Status() != http.StatusCreated {
-		// Extract error details from the response and return an appropriate error.
-		return fmt.Errorf("unexpected status: %d", response.Status())
+		return OperationID(err), fmt.Errorf("can't post service log: %w", err)
 	}
 
-	return nil
-}
-
-func BuildAndSendServiceLog(slBuilder *ServiceLogBuilder, firing bool, alert *template.Alert, ocmClient OCMClient) error {
-	logEntry, err := slBuilder.Build(firing, alert)
-	if err != nil {
-		return err
-	}
-	return ocmClient.SendServiceLog(logEntry)
+	return response.Header().Get(OcmOperationIdHeader), nil
 }
 
-func (o *ocmClientImpl) SendLimitedSupport(clusterUUID string, lsReason *cmv1.LimitedSupportReason) error {
-	internalID, err := GetInternalIDByExternalID(clusterUUID, o.ocmConnection)
+// SendLimitedSupport adds the limited support reason to the cluster and returns the reason created by OCM, along
+// with its ID, and the ID of the OCM operation
+func (o *ocmClientImpl) SendLimitedSupport(ctx context.Context, clusterUUID string, lsReason *cmv1.LimitedSupportReason) (*cmv1.LimitedSupportReason, string, error) {
+	internalID, err := o.internalID(ctx, clusterUUID)
 	if err != nil {
-		return fmt.Errorf("can't get internal id: %w", err)
+		return nil, OperationID(err), fmt.Errorf("can't get internal id: %w", err)
 	}
 
-	response, err := o.ocmConnection.ClustersMgmt().V1().Clusters().Cluster(internalID).LimitedSupportReasons().Add().Body(lsReason).Send()
+	var response *cmv1.LimitedSupportReasonsAddResponse
+	err = o.call(ctx, OperationSendLimitedSupport, func(ctx context.Context) (ocmResponse, error) {
+		var err error
+		response, err = o.ocmConnection.ClustersMgmt().V1().Clusters().Cluster(internalID).LimitedSupportReasons().Add().Body(lsReason).SendContext(ctx)
+		return response, err
+	})
 	if err != nil {
-		return fmt.Errorf("can't post limited support: %w", err)
+		return nil, OperationID(err), fmt.Errorf("can't post limited support: %w", err)
 	}
 
-	// Check the response status code
-	if response.Status() < 200 || response.Status() >= 300 {
-		// Extract error details from the response and return an appropriate error.
-		return fmt.Errorf("unexpected status: %d", response.Status())
-	}
-
-	return nil
+	return response.Body(), response.Header().Get(OcmOperationIdHeader), nil
 }
 
-func (o *ocmClientImpl) RemoveLimitedSupport(clusterUUID string, lsReasonID string) error {
-	internalID, err := GetInternalIDByExternalID(clusterUUID, o.ocmConnection)
+// RemoveLimitedSupport removes the limited support reason from the cluster and returns the ID of the OCM operation
+func (o *ocmClientImpl) RemoveLimitedSupport(ctx context.Context, clusterUUID string, lsReasonID string) (string, error) {
+	internalID, err := o.internalID(ctx, clusterUUID)
 	if err != nil {
-		return fmt.Errorf("can't get internal id: %w", err)
+		return OperationID(err), fmt.Errorf("can't get internal id: %w", err)
 	}
 
-	response, err := o.ocmConnection.ClustersMgmt().V1().Clusters().Cluster(internalID).LimitedSupportReasons().LimitedSupportReason(lsReasonID).Delete().Send()
-	if err != nil {
-		return fmt.Errorf("can't delete limited support reason %s from cluster %s: %w", lsReasonID, clusterUUID, err)
+	var response *cmv1.LimitedSupportReasonDeleteResponse
+	attempts := 0
+	err = o.call(ctx, OperationRemoveLimitedSupport, func(ctx context.Context) (ocmResponse, error) {
+		var err error
+		attempts++
+		response, err = o.ocmConnection.ClustersMgmt().V1().Clusters().Cluster(internalID).LimitedSupportReasons().LimitedSupportReason(lsReasonID).Delete().SendContext(ctx)
+		return response, err
+	})
+	if err != nil && attempts > 1 && errors.As(err, new(*NotFoundError)) {
+		// The previous attempt removed the reason although it failed, e.g. its response was lost
+		log.Debugf("Limited support reason %s of cluster %s was removed by a previous attempt", lsReasonID, clusterUUID)
+		return OperationID(err), nil
 	}
-
-	// Check the response status code
-	if response.Status() < 200 || response.Status() >= 300 {
-		// Extract error details from the response and return an appropriate error.
-		return fmt.Errorf("unexpected status: %d", response.Status())
+	if err != nil {
+		return OperationID(err), fmt.Errorf("can't delete limited support reason %s from cluster %s: %w", lsReasonID, clusterUUID, err)
 	}
 
-	return nil
+	return response.Header().Get(OcmOperationIdHeader), nil
 }
 
-func (o *ocmClientImpl) GetLimitedSupportReasons(clusterUUID string) ([]*cmv1.LimitedSupportReason, error) {
+func (o *ocmClientImpl) GetLimitedSupportReasons(ctx context.Context, clusterUUID string) ([]*cmv1.LimitedSupportReason, error) {
 
-	internalID, err := GetInternalIDByExternalID(clusterUUID, o.ocmConnection)
+	internalID, err := o.internalID(ctx, clusterUUID)
 	if err != nil {
 		return nil, fmt.Errorf("can't get internal id: %w", err)
 	}
 
-	response, err := o.ocmConnection.ClustersMgmt().V1().Clusters().Cluster(internalID).LimitedSupportReasons().List().Send()
+	var response *cmv1.LimitedSupportReasonsListResponse
+	err = o.call(ctx, OperationGetLimitedSupportReasons, func(ctx context.Context) (ocmResponse, error) {
+		var err error
+		response, err = o.ocmConnection.ClustersMgmt().V1().Clusters().Cluster(internalID).LimitedSupportReasons().List().SendContext(ctx)
+		return response, err
+	})
 	if err != nil {
 		return nil, fmt.Errorf("can't get limited support reasons: %w", err)
 	}
 
-	// Check the response status code
-	if response.Status() < 200 || response.Status() >= 300 {
-		// Extract error details from the response and return an appropriate error.
-		return nil, fmt.Errorf("unexpected status: %d", response.Status())
-	}
-
 	return response.Items().Slice(), nil
 }
@@ -1,6 +1,8 @@
 package ocm
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"testing"
@@ -142,16 +144,59 @@ var _ = Describe("OCM client Handler", func() {
 
 	Context("Replace place holders in the given string with the alert labels and annotations", func() {
 		It("shoudn't have errors when the replacement label/annotation found in the template", func() {
-			replaceString, err := replacePlaceHoldersInString("failure regarding the alert '${alertname}'. The initial issue ", &testAlert)
+			replaceString, err := replacePlaceHoldersInString("failure regarding the alert '${alertname}'. The initial issue ", &testAlert, nil)
 			Expect(err).ShouldNot(HaveOccurred())
 			Expect(replaceString).To(Equal("failure regarding the alert 'TestAlertName'. The initial issue "))
 		})
 		It("should error when the replacement label/annotation not found in the template", func() {
-			replaceString, err := replacePlaceHoldersInString("failure regarding the alert '${ALERT_NAME}'. The initial issue ", &testAlert)
+			replaceString, err := replacePlaceHoldersInString("failure regarding the alert '${ALERT_NAME}'. The initial issue ", &testAlert, nil)
 			Expect(err).Should(HaveOccurred())
 			Expect(replaceString).To(Equal("failure regarding the alert '${ALERT_NAME}'. The initial issue "))
 		})
 	})
+	Context("Render the notification texts with the Go template engine", func() {
+		It("should render labels, annotations and functions", func() {
+			testAlert.StartsAt = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+			rendered, err := renderGoTemplate(`{{ .Labels.alertname | toUpper }} in {{ .Labels.namespace }} since {{ formatTime "2006-01-02" .StartsAt }}: {{ .Annotations.description }}`, &testAlert, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(rendered).To(Equal("TESTALERTNAME in openshift-monitoring since 2024-01-02: alert-desc"))
+		})
+		It("should handle optional labels with conditionals and default values", func() {
+			rendered, err := renderGoTemplate(`{{ if .Labels.pod }}pod {{ .Labels.pod }}{{ else }}no pod{{ end }} in {{ .Labels.node | default "any node" }}`, &testAlert, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(rendered).To(Equal("no pod in any node"))
+		})
+		It("should humanize durations", func() {
+			rendered, err := renderGoTemplate(`{{ humanizeDuration 3725 }} {{ humanizeDuration "90m" }}`, &testAlert, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(rendered).To(Equal("1h2m5s 1h30m0s"))
+		})
+		It("should error on an invalid template", func() {
+			_, err := renderGoTemplate(`{{ .Labels.alertname `, &testAlert, nil)
+			Expect(err).Should(HaveOccurred())
+		})
+		It("should be used by the service log builder when selected", func() {
+			slbuilder := NewServiceLogBuilder("{{ .Labels.alertname }}", "{{ .Labels.severity | toUpper }}", "", testconst.TestHostedClusterID,
+				testconst.TestNotification.Severity, testconst.TestNotification.LogType, nil).WithTemplateEngine(TemplateEngineGoTemplate)
+			sl, err := slbuilder.Build(true, &testAlert)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(sl.Summary()).To(Equal(ServiceLogActivePrefix + ": TestAlertName"))
+			Expect(sl.Description()).To(Equal("INFO"))
+		})
+		It("should keep the place holder engine by default", func() {
+			slbuilder := NewServiceLogBuilder("{{ .Labels.alertname }} ${alertname}", "", "", testconst.TestHostedClusterID,
+				testconst.TestNotification.Severity, testconst.TestNotification.LogType, nil)
+			sl, err := slbuilder.Build(true, &testAlert)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(sl.Summary()).To(Equal(ServiceLogActivePrefix + ": {{ .Labels.alertname }} TestAlertName"))
+		})
+		It("should error on an unknown template engine", func() {
+			slbuilder := NewServiceLogBuilder("summary", "", "", testconst.TestHostedClusterID,
+				testconst.TestNotification.Severity, testconst.TestNotification.LogType, nil).WithTemplateEngine("jinja")
+			_, err := slbuilder.Build(true, &testAlert)
+			Expect(err).Should(HaveOccurred())
+		})
+	})
 	Context("Get Cluster", func() {
 		It("should return the cluster without an error", func() {
 			mockServer.SetHandler(0, CombineHandlers(
@@ -162,18 +207,108 @@ var _ = Describe("OCM client Handler", func() {
 					http.Header{"Content-Type": []string{"application/json"}},
 				),
 			))
-			cluster, _, err := ocmClient.GetCluster(clusterID)
+			cluster, _, err := ocmClient.GetCluster(context.TODO(), clusterID)
 			Expect(cluster).ShouldNot(BeNil())
 			Expect(cluster.ID()).To(Equal(clusterID))
 			Expect(err).ShouldNot(HaveOccurred())
 		})
 		It("should return an empty cluster object when clusterID doesn't exist", func() {
-			cluster, _, err := ocmClient.GetCluster("a-ghost-cluster-id")
+			cluster, _, err := ocmClient.GetCluster(context.TODO(), "a-ghost-cluster-id")
 			Expect(cluster).ShouldNot(BeNil())
 			Expect(cluster.ID()).Should(BeEmpty())
 			Expect(err).ShouldNot(HaveOccurred())
 		})
 	})
+	Context("Get Cluster by external ID", func() {
+		var clusterWithMetadata string
+		BeforeEach(func() {
+			clusterWithMetadata = `{"kind":"ClusterList","page":1,"size":1,"total":1,"items": [{"kind":"Cluster","id":"` + clusterID + `","external_id":"` + clusterUUID +
+				`","name":"my-cluster","openshift_version":"4.16.1","region":{"id":"us-east-1"},"cloud_provider":{"id":"aws"}}]}`
+		})
+		It("should return the cluster metadata", func() {
+			mockServer.SetHandler(0, CombineHandlers(
+				VerifyRequest("GET", "/api/clusters_mgmt/v1/clusters"),
+				RespondWith(http.StatusOK, clusterWithMetadata, http.Header{"Content-Type": []string{"application/json"}}),
+			))
+			cluster, err := ocmClient.GetClusterByExternalID(context.TODO(), clusterUUID)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(ClusterData(cluster)).To(Equal(map[string]string{
+				"name":           "my-cluster",
+				"version":        "4.16.1",
+				"region":         "us-east-1",
+				"cloud_provider": "aws",
+				"id":             clusterID,
+				"external_id":    clusterUUID,
+			}))
+		})
+		It("should error when the cluster doesn't exist", func() {
+			mockServer.SetHandler(0, RespondWith(http.StatusOK, `{"kind":"ClusterList","page":1,"size":0,"total":0,"items":[]}`, http.Header{"Content-Type": []string{"application/json"}}))
+			_, err := ocmClient.GetClusterByExternalID(context.TODO(), clusterUUID)
+			Expect(err).To(MatchError(ErrClusterNotFound))
+		})
+		It("should refuse an external id which can't be quoted in the search", func() {
+			_, err := ocmClient.GetClusterByExternalID(context.TODO(), "x' or external_id != '")
+			Expect(err).To(MatchError(ErrInvalidExternalID))
+			Expect(mockServer.ReceivedRequests()).To(BeEmpty())
+		})
+		It("should evict the expired clusters from the cache", func() {
+			mockServer.RouteToHandler("GET", "/api/clusters_mgmt/v1/clusters",
+				RespondWith(http.StatusOK, clusterWithMetadata, http.Header{"Content-Type": []string{"application/json"}}))
+			cache := NewClusterCache(ocmClient, time.Millisecond)
+			_, err := cache.Get(context.TODO(), clusterUUID)
+			Expect(err).ShouldNot(HaveOccurred())
+			time.Sleep(2 * time.Millisecond)
+			_, err = cache.Get(context.TODO(), "another-cluster")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(cache.Len()).To(Equal(1))
+		})
+		It("should only fetch the cluster once while it is cached", func() {
+			mockServer.SetHandler(0, RespondWith(http.StatusOK, clusterWithMetadata, http.Header{"Content-Type": []string{"application/json"}}))
+			cache := NewClusterCache(ocmClient, time.Minute)
+			for i := 0; i < 3; i++ {
+				cluster, err := cache.Get(context.TODO(), clusterUUID)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(cluster.Name()).To(Equal("my-cluster"))
+			}
+			Expect(mockServer.ReceivedRequests()).To(HaveLen(1))
+		})
+		It("should replace the cluster place holders", func() {
+			clusterData := map[string]string{"name": "my-cluster", "version": "4.16.1"}
+			slbuilder := NewServiceLogBuilder("${alertname} on ${cluster.name}", "version {{ .Cluster.version }}", "", clusterUUID,
+				testconst.TestNotification.Severity, testconst.TestNotification.LogType, nil)
+			Expect(slbuilder.NeedsClusterData()).To(BeTrue())
+			sl, err := slbuilder.WithClusterData(clusterData).Build(true, &testAlert)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(sl.Summary()).To(Equal(ServiceLogActivePrefix + ": TestAlertName on my-cluster"))
+
+			sl, err = NewServiceLogBuilder("", "version {{ .Cluster.version }}", "", clusterUUID, testconst.TestNotification.Severity, testconst.TestNotification.LogType, nil).
+				WithTemplateEngine(TemplateEngineGoTemplate).WithClusterData(clusterData).Build(true, &testAlert)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(sl.Description()).To(Equal("version 4.16.1"))
+		})
+		It("should error on unknown cluster place holders", func() {
+			_, err := replacePlaceHoldersInString("${cluster.owner}", &testAlert, map[string]string{"name": "my-cluster"})
+			Expect(err).Should(HaveOccurred())
+		})
+		It("should not need the cluster data when the templates don't reference it", func() {
+			slbuilder := NewServiceLogBuilder("${alertname}", "description", "", clusterUUID,
+				testconst.TestNotification.Severity, testconst.TestNotification.LogType, nil)
+			Expect(slbuilder.NeedsClusterData()).To(BeFalse())
+		})
+		It("should only need the cluster data for the fields of the template engine", func() {
+			placeHolders := NewServiceLogBuilder("${alertname}", "{{ .Cluster.name }} ${Cluster}", "", clusterUUID,
+				testconst.TestNotification.Severity, testconst.TestNotification.LogType, nil)
+			Expect(placeHolders.NeedsClusterData()).To(BeFalse())
+
+			goTemplate := NewServiceLogBuilder("{{ .Labels.Cluster }}", "{{ .Annotations.description }} ${cluster.name}", "", clusterUUID,
+				testconst.TestNotification.Severity, testconst.TestNotification.LogType, nil).WithTemplateEngine(TemplateEngineGoTemplate)
+			Expect(goTemplate.NeedsClusterData()).To(BeFalse())
+
+			goTemplate = NewServiceLogBuilder("", "", "{{ with $.Cluster }}{{ .region }}{{ end }}", clusterUUID,
+				testconst.TestNotification.Severity, testconst.TestNotification.LogType, nil).WithTemplateEngine(TemplateEngineGoTemplate)
+			Expect(goTemplate.NeedsClusterData()).To(BeTrue())
+		})
+	})
 	Context("Upgrade policy", func() {
 		It("should not return an error for valid upgrade policy for a given cluster", func() {
 			mockServer.SetHandler(0, CombineHandlers(
@@ -185,7 +320,7 @@ var _ = Describe("OCM client Handler", func() {
 				),
 			))
 
-			upgradePolicy, _, err := ocmClient.GetUpgradePolicy(clusterID, upgradePolicyID)
+			upgradePolicy, _, err := ocmClient.GetUpgradePolicy(context.TODO(), clusterID, upgradePolicyID)
 			Expect(upgradePolicy).ShouldNot(BeNil())
 			Expect(err).ShouldNot(HaveOccurred())
 			Expect(upgradePolicy.ClusterID()).To(Equal(clusterID))
@@ -200,7 +335,7 @@ var _ = Describe("OCM client Handler", func() {
 					http.Header{"Content-Type": []string{"application/json"}},
 				),
 			))
-			upgradePolicy, _, err := ocmClient.GetUpgradePolicy(clusterID, "not_a_policy_id")
+			upgradePolicy, _, err := ocmClient.GetUpgradePolicy(context.TODO(), clusterID, "not_a_policy_id")
 			Expect(upgradePolicy).Should(BeNil())
 			Expect(err).Should(HaveOccurred())
 
@@ -214,7 +349,7 @@ var _ = Describe("OCM client Handler", func() {
 					http.Header{"Content-Type": []string{"application/json"}},
 				),
 			))
-			upgradePolicyState, _, err := ocmClient.GetUpgradePolicyState(clusterID, upgradePolicyID)
+			upgradePolicyState, _, err := ocmClient.GetUpgradePolicyState(context.TODO(), clusterID, upgradePolicyID)
 			Expect(upgradePolicyState).ShouldNot(BeNil())
 			Expect(err).ShouldNot(HaveOccurred())
 		})
@@ -227,7 +362,7 @@ var _ = Describe("OCM client Handler", func() {
 					http.Header{"Content-Type": []string{"application/json"}},
 				),
 			))
-			upgradePolicyState, _, err := ocmClient.GetUpgradePolicyState(clusterID, upgradePolicyID)
+			upgradePolicyState, _, err := ocmClient.GetUpgradePolicyState(context.TODO(), clusterID, upgradePolicyID)
 			Expect(upgradePolicyState).Should(BeNil())
 			Expect(err).Should(HaveOccurred())
 		})
@@ -240,7 +375,7 @@ var _ = Describe("OCM client Handler", func() {
 					http.Header{"Content-Type": []string{"application/json"}},
 				),
 			))
-			upgradePolicyArray, _, err := ocmClient.GetUpgradePolicies(clusterID)
+			upgradePolicyArray, _, err := ocmClient.GetUpgradePolicies(context.TODO(), clusterID)
 			Expect(upgradePolicyArray).ShouldNot(BeNil())
 			Expect(err).ShouldNot(HaveOccurred())
 			Expect(len(upgradePolicyArray)).To(Equal(2))
@@ -254,7 +389,7 @@ var _ = Describe("OCM client Handler", func() {
 					http.Header{"Content-Type": []string{"application/json"}},
 				),
 			))
-			upgradePolicyState, _, err := ocmClient.GetUpgradePolicies(clusterID)
+			upgradePolicyState, _, err := ocmClient.GetUpgradePolicies(context.TODO(), clusterID)
 			Expect(upgradePolicyState).Should(BeNil())
 			Expect(err).Should(HaveOccurred())
 		})
@@ -267,7 +402,7 @@ var _ = Describe("OCM client Handler", func() {
 					http.Header{"Content-Type": []string{"application/json"}},
 				),
 			))
-			upgradePolicyStateObj, _, err := ocmClient.UpdateUpgradePolicyState(clusterID, upgradePolicyID, &cmv1.UpgradePolicyState{})
+			upgradePolicyStateObj, _, err := ocmClient.UpdateUpgradePolicyState(context.TODO(), clusterID, upgradePolicyID, &cmv1.UpgradePolicyState{})
 			Expect(upgradePolicyState).ShouldNot(BeNil())
 			Expect(err).ShouldNot(HaveOccurred())
 			Expect(upgradePolicyStateObj.Value()).To(Equal(cmv1.UpgradePolicyStateValuePending))
@@ -281,7 +416,7 @@ var _ = Describe("OCM client Handler", func() {
 					http.Header{"Content-Type": []string{"application/json"}},
 				),
 			))
-			upgradePolicyState, _, err := ocmClient.UpdateUpgradePolicyState(clusterID, upgradePolicyID, &cmv1.UpgradePolicyState{})
+			upgradePolicyState, _, err := ocmClient.UpdateUpgradePolicyState(context.TODO(), clusterID, upgradePolicyID, &cmv1.UpgradePolicyState{})
 			Expect(upgradePolicyState).Should(BeNil())
 			Expect(err).Should(HaveOccurred())
 		})
@@ -289,8 +424,23 @@ var _ = Describe("OCM client Handler", func() {
 
 	Context("Posting a service log", func() {
 		It("should not return an error on successful post", func() {
-			err := ocmClient.SendServiceLog(serviceLog)
+			_, err := ocmClient.SendServiceLog(context.TODO(), serviceLog)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should return the OCM operation ID of the post", func() {
+			mockServer.SetHandler(0, CombineHandlers(
+				VerifyRequest("POST", "/api/service_logs/v1/cluster_logs"),
+				RespondWith(
+					http.StatusCreated,
+					`{"kind": "ClusterLog"}`,
+					http.Header{"Content-Type": []string{"application/json"}, OcmOperationIdHeader: []string{"post-operation-id"}},
+				),
+			))
+
+			operationID, err := ocmClient.SendServiceLog(context.TODO(), serviceLog)
 			Expect(err).NotTo(HaveOccurred())
+			Expect(operationID).To(Equal("post-operation-id"))
 		})
 
 		It("should return an error on failed post", func() {
@@ -304,11 +454,14 @@ var _ = Describe("OCM client Handler", func() {
 				),
 			))
 
-			err := ocmClient.SendServiceLog(serviceLog)
+			_, err := ocmClient.SendServiceLog(context.TODO(), serviceLog)
 			Expect(err).To(HaveOccurred())
 
-			expectedErrorMessage := "can't post service log: status is 500, identifier is '400' and code is 'SERVICE-LOGS-400': An internal server error occurred"
+			expectedErrorMessage := "can't post service log: OCM send_service_log request failed with status 500: An internal server error occurred"
 			Expect(err.Error()).To(Equal(expectedErrorMessage))
+			var serverErr *ServerError
+			Expect(errors.As(err, &serverErr)).To(BeTrue())
+			Expect(serverErr.Body.Code()).To(Equal("SERVICE-LOGS-400"))
 		})
 
 	})
@@ -326,12 +479,13 @@ var _ = Describe("OCM client Handler", func() {
 				VerifyRequest("POST", "/api/clusters_mgmt/v1/clusters/internal-id/limited_support_reasons"),
 				RespondWith(
 					http.StatusCreated,
-					`{"kind": "LimitedSupportReason", "details": "Limited support due to test","detection_type": "manual","summary": "Test limited support"}`,
+					`{"kind": "LimitedSupportReason", "id": "`+limitedSupportReasonID+`", "details": "Limited support due to test","detection_type": "manual","summary": "Test limited support"}`,
 					http.Header{"Content-Type": []string{"application/json"}},
 				),
 			))
-			err := ocmClient.SendLimitedSupport(clusterUUID, limitedSupportReason)
+			created, _, err := ocmClient.SendLimitedSupport(context.TODO(), clusterUUID, limitedSupportReason)
 			Expect(err).NotTo(HaveOccurred())
+			Expect(created.ID()).To(Equal(limitedSupportReasonID))
 		})
 
 		It("should return an error when no internal id was found", func() {
@@ -344,7 +498,7 @@ var _ = Describe("OCM client Handler", func() {
 				),
 			))
 
-			err := ocmClient.SendLimitedSupport(clusterUUID, limitedSupportReason)
+			_, _, err := ocmClient.SendLimitedSupport(context.TODO(), clusterUUID, limitedSupportReason)
 			Expect(err).To(HaveOccurred())
 
 			expectedErrorMessage := fmt.Sprintf("can't get internal id: cluster with external id %s not found in OCM database", clusterUUID)
@@ -370,7 +524,7 @@ var _ = Describe("OCM client Handler", func() {
 				),
 			))
 
-			err := ocmClient.SendLimitedSupport(clusterUUID, limitedSupportReason)
+			_, _, err := ocmClient.SendLimitedSupport(context.TODO(), clusterUUID, limitedSupportReason)
 			Expect(err).To(HaveOccurred())
 		})
 
@@ -393,7 +547,7 @@ var _ = Describe("OCM client Handler", func() {
 					http.Header{"Content-Type": []string{"application/json"}},
 				),
 			))
-			err := ocmClient.RemoveLimitedSupport(clusterUUID, limitedSupportReasonID)
+			_, err := ocmClient.RemoveLimitedSupport(context.TODO(), clusterUUID, limitedSupportReasonID)
 			Expect(err).NotTo(HaveOccurred())
 		})
 
@@ -416,7 +570,7 @@ var _ = Describe("OCM client Handler", func() {
 					http.Header{"Content-Type": []string{"application/json"}},
 				),
 			))
-			err := ocmClient.RemoveLimitedSupport(clusterUUID, limitedSupportReasonID)
+			_, err := ocmClient.RemoveLimitedSupport(context.TODO(), clusterUUID, limitedSupportReasonID)
 			Expect(err).To(HaveOccurred())
 		})
 
@@ -448,7 +602,7 @@ var _ = Describe("OCM client Handler", func() {
 					http.Header{"Content-Type": []string{"application/json"}},
 				),
 			))
-			limitedSupportReasons, err := ocmClient.GetLimitedSupportReasons(clusterUUID)
+			limitedSupportReasons, err := ocmClient.GetLimitedSupportReasons(context.TODO(), clusterUUID)
 			Expect(err).NotTo(HaveOccurred())
 
 			Expect(len(limitedSupportReasons)).To(Equal(1))
@@ -472,10 +626,11 @@ var _ = Describe("OCM client Handler", func() {
 					http.Header{"Content-Type": []string{"application/json"}},
 				),
 			))
-			limitedSupportReasons, err := ocmClient.GetLimitedSupportReasons(clusterUUID)
+			limitedSupportReasons, err := ocmClient.GetLimitedSupportReasons(context.TODO(), clusterUUID)
 			Expect(err).To(HaveOccurred())
-			expectedErrorMessage := "can't get limited support reasons: status is 404, identifier is '404' and code is 'CLUSTERS-MGMT-404': The requested resource doesn't exist"
+			expectedErrorMessage := "can't get limited support reasons: OCM get_limited_support_reasons request failed with status 404: The requested resource doesn't exist"
 			Expect(err.Error()).To(Equal(expectedErrorMessage))
+			Expect(errors.As(err, new(*NotFoundError))).To(BeTrue())
 
 			Expect(limitedSupportReasons).To(BeNil())
 
@@ -518,7 +673,7 @@ var _ = Describe("OCM client Handler", func() {
 					RespondWith(http.StatusInternalServerError, `{"kind": "Error", "reason": "Internal server error"}`, http.Header{"Content-Type": []string{"application/json"}}),
 				),
 			)
-			cluster, _, err := ocmClient.GetCluster(clusterID)
+			cluster, _, err := ocmClient.GetCluster(context.TODO(), clusterID)
 			Expect(err).Should(HaveOccurred())
 			Expect(cluster).Should(BeNil())
 			Expect(err.Error()).Should(ContainSubstring("500"))
@@ -539,7 +694,7 @@ var _ = Describe("OCM client Handler", func() {
 					RespondWith(http.StatusInternalServerError, `{"kind": "Error", "reason": "Internal server error"}`, http.Header{"Content-Type": []string{"application/json"}}),
 				),
 			)
-			upgradePolicies, _, err := ocmClient.GetUpgradePolicies(clusterID)
+			upgradePolicies, _, err := ocmClient.GetUpgradePolicies(context.TODO(), clusterID)
 			Expect(err).Should(HaveOccurred())
 			Expect(upgradePolicies).Should(BeNil())
 			Expect(err.Error()).Should(ContainSubstring("500"))
@@ -560,7 +715,7 @@ var _ = Describe("OCM client Handler", func() {
 					RespondWith(http.StatusInternalServerError, `{"kind": "Error", "reason": "Internal server error"}`, http.Header{"Content-Type": []string{"application/json"}}),
 				),
 			)
-			upgradePolicy, _, err := ocmClient.GetUpgradePolicy(clusterID, upgradePolicyID)
+			upgradePolicy, _, err := ocmClient.GetUpgradePolicy(context.TODO(), clusterID, upgradePolicyID)
 			Expect(err).Should(HaveOccurred())
 			Expect(upgradePolicy).Should(BeNil())
 			Expect(err.Error()).Should(ContainSubstring("500"))
@@ -581,7 +736,7 @@ var _ = Describe("OCM client Handler", func() {
 					RespondWith(http.StatusInternalServerError, `{"kind": "Error", "reason": "Internal server error"}`, http.Header{"Content-Type": []string{"application/json"}}),
 				),
 			)
-			upgradePolicyState, _, err := ocmClient.GetUpgradePolicyState(clusterID, upgradePolicyID)
+			upgradePolicyState, _, err := ocmClient.GetUpgradePolicyState(context.TODO(), clusterID, upgradePolicyID)
 			Expect(err).Should(HaveOccurred())
 			Expect(upgradePolicyState).Should(BeNil())
 			Expect(err.Error()).Should(ContainSubstring("500"))
@@ -602,7 +757,7 @@ var _ = Describe("OCM client Handler", func() {
 					RespondWith(http.StatusInternalServerError, `{"kind": "Error", "reason": "Internal server error"}`, http.Header{"Content-Type": []string{"application/json"}}),
 				),
 			)
-			upgradePolicyState, _, err := ocmClient.UpdateUpgradePolicyState(clusterID, upgradePolicyID, &cmv1.UpgradePolicyState{})
+			upgradePolicyState, _, err := ocmClient.UpdateUpgradePolicyState(context.TODO(), clusterID, upgradePolicyID, &cmv1.UpgradePolicyState{})
 			Expect(err).Should(HaveOccurred())
 			Expect(upgradePolicyState).Should(BeNil())
 			Expect(err.Error()).Should(ContainSubstring("500"))
@@ -623,7 +778,7 @@ var _ = Describe("OCM client Handler", func() {
 					RespondWith(http.StatusInternalServerError, `{"kind": "Error", "reason": "Internal server error"}`, http.Header{"Content-Type": []string{"application/json"}}),
 				),
 			)
-			err := ocmClient.SendServiceLog(serviceLog)
+			_, err := ocmClient.SendServiceLog(context.TODO(), serviceLog)
 			Expect(err).Should(HaveOccurred())
 			Expect(err.Error()).Should(ContainSubstring("500"))
 		})
@@ -651,7 +806,7 @@ var _ = Describe("OCM client Handler", func() {
 					RespondWith(http.StatusInternalServerError, `{"kind": "Error", "reason": "Internal server error"}`, http.Header{"Content-Type": []string{"application/json"}}),
 				),
 			)
-			err := ocmClient.SendLimitedSupport(clusterUUID, limitedSupportReason)
+			_, _, err := ocmClient.SendLimitedSupport(context.TODO(), clusterUUID, limitedSupportReason)
 			Expect(err).Should(HaveOccurred())
 			Expect(err.Error()).Should(ContainSubstring("500"))
 		})
@@ -679,7 +834,7 @@ var _ = Describe("OCM client Handler", func() {
 					RespondWith(http.StatusInternalServerError, `{"kind": "Error", "reason": "Internal server error"}`, http.Header{"Content-Type": []string{"application/json"}}),
 				),
 			)
-			limitedSupportReasons, err := ocmClient.GetLimitedSupportReasons(clusterUUID)
+			limitedSupportReasons, err := ocmClient.GetLimitedSupportReasons(context.TODO(), clusterUUID)
 			Expect(err).Should(HaveOccurred())
 			Expect(limitedSupportReasons).Should(BeNil())
 			Expect(err.Error()).Should(ContainSubstring("500"))
@@ -708,7 +863,7 @@ var _ = Describe("OCM client Handler", func() {
 					RespondWith(http.StatusInternalServerError, `{"kind": "Error", "reason": "Internal server error"}`, http.Header{"Content-Type": []string{"application/json"}}),
 				),
 			)
-			err := ocmClient.RemoveLimitedSupport(clusterUUID, limitedSupportReasonID)
+			_, err := ocmClient.RemoveLimitedSupport(context.TODO(), clusterUUID, limitedSupportReasonID)
 			Expect(err).Should(HaveOccurred())
 			Expect(err.Error()).Should(ContainSubstring("500"))
 		})
@@ -0,0 +1,76 @@
+package ocm
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/openshift/ocm-agent/pkg/backoff"
+)
+
+const (
+	// DefaultRetryAttempts is the number of attempts of an OCM request failing transiently
+	DefaultRetryAttempts = 3
+
+	retryInitialInterval = time.Second
+	retryMaxInterval     = 10 * time.Second
+	retryMaxElapsedTime  = time.Minute
+)
+
+// idempotentOperations are the operations which can be sent again whatever happened to the previous attempt
+var idempotentOperations = map[string]bool{
+	OperationRemoveLimitedSupport:     true,
+	OperationUpdateUpgradePolicyState: true,
+	OperationGetCluster:               true,
+	OperationGetClusterByExternalID:   true,
+	OperationGetUpgradePolicy:         true,
+	OperationGetUpgradePolicyState:    true,
+	OperationGetUpgradePolicies:       true,
+	OperationGetLimitedSupportReasons: true,
+}
+
+// RetryConfig returns the backoff of the OCM requests making up to attempts attempts, the retries are disabled
+// when attempts is lower than 2
+func RetryConfig(attempts int) backoff.Config {
+	return backoff.Config{
+		InitialInterval: retryInitialInterval,
+		MaxInterval:     retryMaxInterval,
+		MaxAttempts:     attempts,
+		MaxElapsedTime:  retryMaxElapsedTime,
+	}
+}
+
+// isRetriable tells whether a request of the operation failing with err can be sent again. The requests which
+// weren't processed by OCM, being rate limited or not reaching it, are always retried. The other transient failures
+// are only retried for the idempotent operations, as OCM may have processed the failed attempt, e.g. posting a
+// service log twice.
+func isRetriable(operation string, err error) bool {
+	if errors.Is(err, ErrCircuitOpen) || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.As(err, new(*RateLimitedError)) {
+		return true
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+	if !idempotentOperations[operation] {
+		return false
+	}
+	if errors.As(err, new(*ServerError)) {
+		return true
+	}
+	// The other responses of OCM don't change when sent again, unlike the network errors and timeouts
+	return !errors.As(err, new(*ResponseError))
+}
+
+// retryAfter returns the delay requested by OCM in the response failing with err
+func retryAfter(err error) time.Duration {
+	var responseErr *ResponseError
+	if errors.As(err, &responseErr) {
+		return responseErr.RetryAfter
+	}
+	return 0
+}
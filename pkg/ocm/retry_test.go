@@ -0,0 +1,163 @@
+package ocm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/ghttp"
+	sdk "github.com/openshift-online/ocm-sdk-go"
+	. "github.com/openshift-online/ocm-sdk-go/testing"
+
+	"github.com/openshift/ocm-agent/pkg/backoff"
+)
+
+var _ = Describe("OCM retries", func() {
+	var (
+		mockServer *Server
+		ocmClient  OCMClient
+		clusterURL = "/api/clusters_mgmt/v1/clusters/cluster-id"
+		clusterOK  = RespondWith(http.StatusOK, `{"kind": "Cluster", "id": "cluster-id"}`, http.Header{"Content-Type": []string{"application/json"}})
+	)
+
+	respondError := func(status int, header http.Header) http.HandlerFunc {
+		header.Set("Content-Type", "application/json")
+		return RespondWith(status, `{"kind": "Error", "reason": "Try again"}`, header)
+	}
+
+	BeforeEach(func() {
+		mockServer = NewServer()
+		ocmConnection, err := sdk.NewConnectionBuilder().
+			URL(mockServer.URL()).
+			Tokens(MakeTokenString("Bearer", 15*time.Minute)).
+			RetryLimit(0).
+			Build()
+		Expect(err).NotTo(HaveOccurred())
+		ocmClient = NewOcmClient(ocmConnection, WithRetries(backoff.Config{
+			InitialInterval: time.Millisecond,
+			MaxInterval:     time.Millisecond,
+			MaxAttempts:     3,
+		}))
+	})
+
+	AfterEach(func() {
+		mockServer.Close()
+	})
+
+	It("retries the reads failing with a server error", func() {
+		mockServer.AppendHandlers(
+			respondError(http.StatusServiceUnavailable, http.Header{}),
+			respondError(http.StatusBadGateway, http.Header{}),
+			clusterOK,
+		)
+		cluster, _, err := ocmClient.GetCluster(context.TODO(), "cluster-id")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cluster.ID()).To(Equal("cluster-id"))
+		Expect(mockServer.ReceivedRequests()).To(HaveLen(3))
+	})
+
+	It("returns the last error once the attempts are exhausted", func() {
+		mockServer.AppendHandlers(
+			respondError(http.StatusServiceUnavailable, http.Header{}),
+			respondError(http.StatusServiceUnavailable, http.Header{}),
+			respondError(http.StatusInternalServerError, http.Header{}),
+		)
+		_, _, err := ocmClient.GetCluster(context.TODO(), "cluster-id")
+		var serverErr *ServerError
+		Expect(errors.As(err, &serverErr)).To(BeTrue())
+		Expect(serverErr.Status).To(Equal(http.StatusInternalServerError))
+		Expect(mockServer.ReceivedRequests()).To(HaveLen(3))
+	})
+
+	It("doesn't retry the errors which don't change when sent again", func() {
+		mockServer.AppendHandlers(respondError(http.StatusNotFound, http.Header{}))
+		_, _, err := ocmClient.GetCluster(context.TODO(), "cluster-id")
+		Expect(errors.As(err, new(*NotFoundError))).To(BeTrue())
+		Expect(mockServer.ReceivedRequests()).To(HaveLen(1))
+	})
+
+	It("waits for the delay requested by OCM", func() {
+		mockServer.AppendHandlers(
+			CombineHandlers(VerifyRequest("GET", clusterURL), respondError(http.StatusTooManyRequests, http.Header{"Retry-After": []string{"1"}})),
+			clusterOK,
+		)
+		start := time.Now()
+		_, _, err := ocmClient.GetCluster(context.TODO(), "cluster-id")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(time.Since(start)).To(BeNumerically(">=", time.Second))
+	})
+
+	It("stops retrying when the request is cancelled", func() {
+		mockServer.AppendHandlers(respondError(http.StatusTooManyRequests, http.Header{"Retry-After": []string{"60"}}))
+		ctx, cancel := context.WithTimeout(context.TODO(), 50*time.Millisecond)
+		defer cancel()
+		_, _, err := ocmClient.GetCluster(ctx, "cluster-id")
+		Expect(err).To(MatchError(context.DeadlineExceeded))
+		Expect(mockServer.ReceivedRequests()).To(HaveLen(1))
+	})
+
+	Context("When posting a service log", func() {
+		It("doesn't retry a server error as the service log may have been posted", func() {
+			mockServer.AppendHandlers(respondError(http.StatusInternalServerError, http.Header{}))
+			_, err := ocmClient.SendServiceLog(context.TODO(), &ServiceLog{})
+			Expect(errors.As(err, new(*ServerError))).To(BeTrue())
+			Expect(mockServer.ReceivedRequests()).To(HaveLen(1))
+		})
+
+		It("retries a rate limited request", func() {
+			mockServer.AppendHandlers(
+				respondError(http.StatusTooManyRequests, http.Header{}),
+				RespondWith(http.StatusCreated, `{"kind": "ClusterLog"}`, http.Header{"Content-Type": []string{"application/json"}}),
+			)
+			_, err := ocmClient.SendServiceLog(context.TODO(), &ServiceLog{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mockServer.ReceivedRequests()).To(HaveLen(2))
+		})
+	})
+
+	Context("When removing a limited support reason", func() {
+		BeforeEach(func() {
+			mockServer.RouteToHandler("GET", "/api/clusters_mgmt/v1/clusters", RespondWith(http.StatusOK,
+				`{"kind":"ClusterList","page":1,"size":1,"total":1,"items":[{"kind":"Cluster","id":"cluster-id","external_id":"cluster-uuid"}]}`,
+				http.Header{"Content-Type": []string{"application/json"}}))
+		})
+
+		It("succeeds when a retried removal finds the reason already removed", func() {
+			mockServer.AppendHandlers(
+				respondError(http.StatusBadGateway, http.Header{}),
+				respondError(http.StatusNotFound, http.Header{}),
+			)
+			_, err := ocmClient.RemoveLimitedSupport(context.TODO(), "cluster-uuid", "reason-id")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mockServer.ReceivedRequests()).To(HaveLen(3))
+		})
+
+		It("fails when the reason to remove isn't found on the first attempt", func() {
+			mockServer.AppendHandlers(respondError(http.StatusNotFound, http.Header{}))
+			_, err := ocmClient.RemoveLimitedSupport(context.TODO(), "cluster-uuid", "reason-id")
+			Expect(errors.As(err, new(*NotFoundError))).To(BeTrue())
+		})
+	})
+
+	DescribeTable("tells which failures are retried",
+		func(operation string, err error, expected bool) {
+			Expect(isRetriable(operation, err)).To(Equal(expected))
+		},
+		Entry("a read timing out", OperationGetCluster, context.DeadlineExceeded, true),
+		Entry("a read failing to connect", OperationGetCluster, &net.OpError{Op: "dial", Err: errors.New("connection refused")}, true),
+		Entry("a read cancelled", OperationGetCluster, context.Canceled, false),
+		Entry("a read failing fast", OperationGetCluster, ErrCircuitOpen, false),
+		Entry("a read forbidden", OperationGetCluster, &ForbiddenError{&ResponseError{Status: http.StatusForbidden}}, false),
+		Entry("a removal failing with a server error", OperationRemoveLimitedSupport, &ServerError{&ResponseError{Status: http.StatusBadGateway}}, true),
+		Entry("a service log timing out", OperationSendServiceLog, context.DeadlineExceeded, false),
+		Entry("a service log failing to connect", OperationSendServiceLog, fmt.Errorf("can't send: %w", &net.OpError{Op: "dial"}), true),
+		Entry("a service log with a dropped connection", OperationSendServiceLog, &net.OpError{Op: "read"}, false),
+		Entry("a limited support rate limited", OperationSendLimitedSupport, &RateLimitedError{&ResponseError{Status: http.StatusTooManyRequests}}, true),
+		Entry("a limited support failing with a server error", OperationSendLimitedSupport, &ServerError{&ResponseError{Status: http.StatusServiceUnavailable}}, false),
+	)
+})
@@ -0,0 +1,167 @@
+package ocm
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+
+	amtemplate "github.com/prometheus/alertmanager/template"
+)
+
+const (
+	// TemplateEnginePlaceHolder only replaces the ${key} place holders with the alert labels and annotations
+	TemplateEnginePlaceHolder = "placeholder"
+	// TemplateEngineGoTemplate renders the notification texts as Go text/template
+	TemplateEngineGoTemplate = "gotemplate"
+)
+
+// templateData is the data the Go templates are executed against
+type templateData struct {
+	Labels       map[string]string
+	Annotations  map[string]string
+	Status       string
+	StartsAt     time.Time
+	EndsAt       time.Time
+	Fingerprint  string
+	GeneratorURL string
+	// Cluster holds the cluster metadata, keyed by name, version, region, cloud_provider, id and external_id
+	Cluster map[string]string
+}
+
+var templateFuncs = template.FuncMap{
+	"toUpper":          strings.ToUpper,
+	"toLower":          strings.ToLower,
+	"default":          defaultValue,
+	"humanizeDuration": humanizeDuration,
+	"since":            time.Since,
+	"formatTime":       formatTime,
+}
+
+// defaultValue returns def when value is empty, it is meant to be used in pipelines: {{ .Labels.namespace | default "unknown" }}
+func defaultValue(def string, value string) string {
+	if value == "" {
+		return def
+	}
+	return value
+}
+
+// humanizeDuration formats a duration, or a number of seconds, rounded to the second
+func humanizeDuration(d interface{}) (string, error) {
+	var duration time.Duration
+	switch v := d.(type) {
+	case time.Duration:
+		duration = v
+	case int:
+		duration = time.Duration(v) * time.Second
+	case int64:
+		duration = time.Duration(v) * time.Second
+	case float64:
+		duration = time.Duration(v * float64(time.Second))
+	case string:
+		var err error
+		if duration, err = time.ParseDuration(v); err != nil {
+			return "", err
+		}
+	default:
+		return "", fmt.Errorf("humanizeDuration can't format a value of type %T", d)
+	}
+	return duration.Round(time.Second).String(), nil
+}
+
+// formatTime formats the time with the given Go layout, RFC3339 is used if the layout is empty
+func formatTime(layout string, t time.Time) string {
+	if layout == "" {
+		layout = time.RFC3339
+	}
+	return t.UTC().Format(layout)
+}
+
+// goTemplateUsesCluster indicates whether the Go template references the Cluster field of the data, e.g.
+// {{ .Cluster.name }} or {{ with $.Cluster }}. A template which can't be parsed doesn't, as it can't be rendered.
+func goTemplateUsesCluster(s string) bool {
+	tmpl, err := template.New("notification").Funcs(templateFuncs).Parse(s)
+	if err != nil {
+		return false
+	}
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil && nodeUsesCluster(t.Tree.Root) {
+			return true
+		}
+	}
+	return false
+}
+
+// nodeUsesCluster walks the template parse tree looking for a field chain starting with Cluster
+func nodeUsesCluster(node parse.Node) bool {
+	switch n := node.(type) {
+	case *parse.FieldNode:
+		return len(n.Ident) > 0 && n.Ident[0] == "Cluster"
+	case *parse.VariableNode:
+		return len(n.Ident) > 1 && n.Ident[0] == "$" && n.Ident[1] == "Cluster"
+	case *parse.ChainNode:
+		return nodeUsesCluster(n.Node)
+	case *parse.ListNode:
+		if n == nil {
+			return false
+		}
+		for _, child := range n.Nodes {
+			if nodeUsesCluster(child) {
+				return true
+			}
+		}
+	case *parse.ActionNode:
+		return nodeUsesCluster(n.Pipe)
+	case *parse.PipeNode:
+		if n == nil {
+			return false
+		}
+		for _, cmd := range n.Cmds {
+			if nodeUsesCluster(cmd) {
+				return true
+			}
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			if nodeUsesCluster(arg) {
+				return true
+			}
+		}
+	case *parse.IfNode:
+		return nodeUsesCluster(n.Pipe) || nodeUsesCluster(n.List) || nodeUsesCluster(n.ElseList)
+	case *parse.RangeNode:
+		return nodeUsesCluster(n.Pipe) || nodeUsesCluster(n.List) || nodeUsesCluster(n.ElseList)
+	case *parse.WithNode:
+		return nodeUsesCluster(n.Pipe) || nodeUsesCluster(n.List) || nodeUsesCluster(n.ElseList)
+	case *parse.TemplateNode:
+		return nodeUsesCluster(n.Pipe)
+	}
+	return false
+}
+
+// renderGoTemplate executes s as a Go text/template with the alert data and the cluster metadata.
+// Missing labels or annotations render as empty strings so templates can handle optional values.
+func renderGoTemplate(s string, alert *amtemplate.Alert, cluster map[string]string) (string, error) {
+	tmpl, err := template.New("notification").Funcs(templateFuncs).Option("missingkey=zero").Parse(s)
+	if err != nil {
+		return "", fmt.Errorf("unable to parse the notification template: %w", err)
+	}
+
+	data := templateData{
+		Labels:       alert.Labels,
+		Annotations:  alert.Annotations,
+		Status:       alert.Status,
+		StartsAt:     alert.StartsAt,
+		EndsAt:       alert.EndsAt,
+		Fingerprint:  alert.Fingerprint,
+		GeneratorURL: alert.GeneratorURL,
+		Cluster:      cluster,
+	}
+
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("unable to render the notification template: %w", err)
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,43 @@
+package ocm
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// DefaultRequestTimeout bounds the duration of the OCM requests whose operation has no specific timeout
+const DefaultRequestTimeout = 30 * time.Second
+
+// Timeouts bounds the duration of the OCM requests by operation, a zero timeout doesn't bound them
+type Timeouts struct {
+	Default    time.Duration
+	Operations map[string]time.Duration
+}
+
+// NewTimeouts returns the timeouts with the operation specific ones parsed from durations indexed by operation name
+func NewTimeouts(defaultTimeout time.Duration, operations map[string]string) (Timeouts, error) {
+	t := Timeouts{Default: defaultTimeout, Operations: map[string]time.Duration{}}
+	for operation, value := range operations {
+		if !slices.Contains(Operations, operation) {
+			return Timeouts{}, fmt.Errorf("unknown OCM operation %q, expected one of %v", operation, Operations)
+		}
+		timeout, err := time.ParseDuration(value)
+		if err != nil {
+			return Timeouts{}, fmt.Errorf("invalid timeout for OCM operation %s: %w", operation, err)
+		}
+		if timeout < 0 {
+			return Timeouts{}, fmt.Errorf("the timeout for OCM operation %s can't be negative", operation)
+		}
+		t.Operations[operation] = timeout
+	}
+	return t, nil
+}
+
+// For returns the timeout of the operation
+func (t Timeouts) For(operation string) time.Duration {
+	if timeout, ok := t.Operations[operation]; ok {
+		return timeout
+	}
+	return t.Default
+}
@@ -0,0 +1,76 @@
+package ocm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/ghttp"
+	sdk "github.com/openshift-online/ocm-sdk-go"
+	. "github.com/openshift-online/ocm-sdk-go/testing"
+)
+
+var _ = Describe("OCM request timeouts", func() {
+	Context("When parsing the timeouts", func() {
+		It("returns the operation timeout or the default one", func() {
+			timeouts, err := NewTimeouts(time.Minute, map[string]string{OperationSendServiceLog: "10s"})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(timeouts.For(OperationSendServiceLog)).To(Equal(10 * time.Second))
+			Expect(timeouts.For(OperationGetCluster)).To(Equal(time.Minute))
+		})
+		It("rejects an unknown operation", func() {
+			_, err := NewTimeouts(time.Minute, map[string]string{"send_email": "10s"})
+			Expect(err).Should(HaveOccurred())
+		})
+		It("rejects an invalid duration", func() {
+			_, err := NewTimeouts(time.Minute, map[string]string{OperationSendServiceLog: "soon"})
+			Expect(err).Should(HaveOccurred())
+		})
+	})
+
+	Context("When used by the OCM client", func() {
+		var (
+			mockServer *Server
+			ocmClient  OCMClient
+			unblock    chan struct{}
+		)
+
+		BeforeEach(func() {
+			unblock = make(chan struct{})
+			mockServer = NewServer()
+			mockServer.RouteToHandler(http.MethodGet, "/api/clusters_mgmt/v1/clusters/cluster-id", func(w http.ResponseWriter, r *http.Request) {
+				<-unblock
+			})
+			ocmConnection, err := sdk.NewConnectionBuilder().
+				URL(mockServer.URL()).
+				Tokens(MakeTokenString("Bearer", 15*time.Minute)).
+				RetryLimit(0).
+				Build()
+			Expect(err).NotTo(HaveOccurred())
+			ocmClient = NewOcmClient(ocmConnection, WithTimeouts(Timeouts{
+				Default:    time.Minute,
+				Operations: map[string]time.Duration{OperationGetCluster: 50 * time.Millisecond},
+			}))
+		})
+
+		AfterEach(func() {
+			close(unblock)
+			mockServer.Close()
+		})
+
+		It("aborts the requests exceeding the timeout of their operation", func() {
+			_, _, err := ocmClient.GetCluster(context.TODO(), "cluster-id")
+			Expect(errors.Is(err, context.DeadlineExceeded)).To(BeTrue())
+		})
+
+		It("aborts the requests when the context is cancelled", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			time.AfterFunc(10*time.Millisecond, cancel)
+			_, _, err := ocmClient.GetCluster(ctx, "cluster-id")
+			Expect(errors.Is(err, context.Canceled)).To(BeTrue())
+		})
+	})
+})
@@ -0,0 +1,77 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ConfigMapEntriesKey is the key of the ConfigMap data holding the entries
+const ConfigMapEntriesKey = "entries.json"
+
+// ConfigMapStore keeps the entries of the outbox as a JSON document in a ConfigMap, shared by the replicas
+type ConfigMapStore struct {
+	c   client.Client
+	key client.ObjectKey
+}
+
+// NewConfigMapStore returns a Store writing the entries to the ConfigMap, which is created when missing
+func NewConfigMapStore(c client.Client, namespace, name string) *ConfigMapStore {
+	return &ConfigMapStore{c: c, key: client.ObjectKey{Namespace: namespace, Name: name}}
+}
+
+// Load reads the entries from the ConfigMap, a missing ConfigMap is an empty outbox
+func (s *ConfigMapStore) Load(ctx context.Context) ([]Entry, error) {
+	cm := &corev1.ConfigMap{}
+	if err := s.c.Get(ctx, s.key, cm); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var entries []Entry
+	data := cm.Data[ConfigMapEntriesKey]
+	if data == "" {
+		return entries, nil
+	}
+	if err := json.Unmarshal([]byte(data), &entries); err != nil {
+		return nil, fmt.Errorf("malformed outbox ConfigMap %s: %w", s.key, err)
+	}
+	return entries, nil
+}
+
+// Save replaces the entries held by the ConfigMap
+func (s *ConfigMapStore) Save(ctx context.Context, entries []Entry) error {
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cm := &corev1.ConfigMap{}
+		err := s.c.Get(ctx, s.key, cm)
+		if apierrors.IsNotFound(err) {
+			cm = &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Namespace: s.key.Namespace, Name: s.key.Name},
+				Data:       map[string]string{ConfigMapEntriesKey: string(data)},
+			}
+			return s.c.Create(ctx, cm)
+		}
+		if err != nil {
+			return err
+		}
+
+		if cm.Data == nil {
+			cm.Data = map[string]string{}
+		}
+		cm.Data[ConfigMapEntriesKey] = string(data)
+		return s.c.Update(ctx, cm)
+	})
+}
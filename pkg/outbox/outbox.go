@@ -0,0 +1,356 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/common/model"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/openshift/ocm-agent/pkg/metrics"
+)
+
+const (
+	// DefaultReplayInterval is the time between two replays of the outbox
+	DefaultReplayInterval = 30 * time.Second
+	// DefaultMaxAttempts is the number of failed replays after which an entry is given up
+	DefaultMaxAttempts = 240
+	// DefaultMaxAge is the time after which an entry not delivered yet is given up
+	DefaultMaxAge = 24 * time.Hour
+	// DefaultMaxSize is the maximum size of the persisted entries, in bytes. It leaves room under the 1 MiB limit
+	// of the ConfigMap holding them.
+	DefaultMaxSize = 768 * 1024
+
+	// maxLastErrorLength bounds the error recorded on an entry so that it doesn't grow the outbox
+	maxLastErrorLength = 512
+
+	// The reasons an entry is given up for
+	deadLetterMaxAttempts = "max_attempts"
+	deadLetterExpired     = "expired"
+)
+
+// Entry is an OCM write which couldn't be delivered, along with the alert it was made for
+type Entry struct {
+	// Seq is a monotonically increasing number keeping the entries ordered
+	Seq uint64 `json:"seq"`
+	// Cluster is the ID of the cluster the write is about, the writes of a cluster are replayed in order
+	Cluster string `json:"cluster"`
+	// Operation is the name of the OCM operation, e.g. ocm.OperationSendServiceLog
+	Operation string `json:"operation"`
+	// Notification is the name of the notification template of the alert
+	Notification string `json:"notification"`
+	// Alert is the alert the write was made for
+	Alert template.Alert `json:"alert"`
+	// AddedAt is the time the entry was added to the outbox
+	AddedAt time.Time `json:"addedAt"`
+	// Attempts is the number of times the entry was replayed without being delivered
+	Attempts int `json:"attempts"`
+	// LastError is the error the last replay failed with
+	LastError string `json:"lastError,omitempty"`
+}
+
+// Firing indicates whether the entry was made for a firing alert
+func (e Entry) Firing() bool {
+	return e.Alert.Status == string(model.AlertFiring)
+}
+
+// sameWrite indicates whether both entries are the same write for the same transition of an alert
+func (e Entry) sameWrite(other Entry) bool {
+	return e.Cluster == other.Cluster &&
+		e.Operation == other.Operation &&
+		e.Notification == other.Notification &&
+		e.Alert.Fingerprint == other.Alert.Fingerprint &&
+		e.Alert.Status == other.Alert.Status
+}
+
+// DeliverFunc delivers an entry of the outbox, the entry is removed from the outbox unless an error is returned
+type DeliverFunc func(ctx context.Context, entry Entry) error
+
+// errNotLoaded is returned when changing the outbox before its persisted entries were loaded, which would
+// overwrite them
+var errNotLoaded = errors.New("the outbox is not loaded yet")
+
+// ErrFull is returned when adding an entry to an outbox which has no room left for it
+var ErrFull = errors.New("the outbox is full")
+
+// Store persists the entries of the outbox so that they survive restarts of the agent and are replayed by
+// the next leader
+type Store interface {
+	Load(ctx context.Context) ([]Entry, error)
+	Save(ctx context.Context, entries []Entry) error
+}
+
+// Outbox holds the OCM writes which couldn't be delivered, e.g. while OCM is unreachable, until they are
+// replayed. The entries of a cluster are replayed in the order they were added, a failing entry holding back
+// the next ones. An entry failing too many times or for too long is given up, so that it doesn't hold back the
+// cluster forever.
+type Outbox struct {
+	store       Store
+	mu          sync.Mutex
+	entries     []Entry
+	seq         uint64
+	loaded      bool
+	maxAttempts int
+	maxAge      time.Duration
+	maxSize     int
+}
+
+// New returns an empty outbox persisting its entries to the store
+func New(store Store) *Outbox {
+	return &Outbox{store: store, maxAttempts: DefaultMaxAttempts, maxAge: DefaultMaxAge, maxSize: DefaultMaxSize}
+}
+
+// WithLimits sets the number of failed replays and the age after which an entry is given up, and the maximum size
+// of the persisted entries in bytes, DefaultMaxAttempts, DefaultMaxAge and DefaultMaxSize by default
+func (o *Outbox) WithLimits(maxAttempts int, maxAge time.Duration, maxSize int) *Outbox {
+	o.maxAttempts = maxAttempts
+	o.maxAge = maxAge
+	o.maxSize = maxSize
+	return o
+}
+
+// Load replaces the entries of the outbox with the persisted ones, e.g. when the replica becomes the leader
+func (o *Outbox) Load(ctx context.Context) error {
+	entries, err := o.store.Load(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to load the outbox: %w", err)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Seq < entries[j].Seq })
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.entries = entries
+	o.loaded = true
+	for _, e := range entries {
+		if e.Seq > o.seq {
+			o.seq = e.Seq
+		}
+	}
+	metrics.SetOutboxSize(len(o.entries))
+	return nil
+}
+
+// Add appends the entry to the outbox, it is only added once persisted. An entry for the same transition of
+// the same alert already waiting, e.g. added on a previous delivery of the alert, isn't added twice. ErrFull is
+// returned when the persisted entries would exceed the maximum size.
+func (o *Outbox) Add(ctx context.Context, e Entry) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if !o.loaded {
+		return errNotLoaded
+	}
+	for _, existing := range o.entries {
+		if existing.sameWrite(e) {
+			return nil
+		}
+	}
+
+	e.Seq = o.seq + 1
+	e.AddedAt = time.Now()
+	entries := append(append([]Entry(nil), o.entries...), e)
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	if len(data) > o.maxSize {
+		return fmt.Errorf("%w: %d entries take %d bytes", ErrFull, len(entries), len(data))
+	}
+	if err := o.save(ctx, entries); err != nil {
+		return err
+	}
+	o.seq = e.Seq
+	log.WithFields(log.Fields{"cluster": e.Cluster, "operation": e.Operation, "notification": e.Notification}).Info("Added an undelivered OCM write to the outbox")
+	return nil
+}
+
+// Pending indicates whether some entries of the cluster are waiting to be replayed, a nil outbox has none
+func (o *Outbox) Pending(cluster string) bool {
+	if o == nil {
+		return false
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for _, e := range o.entries {
+		if e.Cluster == cluster {
+			return true
+		}
+	}
+	return false
+}
+
+// Drop removes the entries matching the function, e.g. the entries of an alert which has since resolved, and
+// returns how many were removed. Nothing is dropped from a nil outbox.
+func (o *Outbox) Drop(ctx context.Context, match func(Entry) bool) (int, error) {
+	if o == nil {
+		return 0, nil
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if !o.loaded {
+		return 0, errNotLoaded
+	}
+
+	var kept []Entry
+	for _, e := range o.entries {
+		if !match(e) {
+			kept = append(kept, e)
+		}
+	}
+	dropped := len(o.entries) - len(kept)
+	if dropped == 0 {
+		return 0, nil
+	}
+	return dropped, o.save(ctx, kept)
+}
+
+// Len returns the number of entries waiting to be replayed
+func (o *Outbox) Len() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.entries)
+}
+
+// Run loads the persisted entries and replays them every interval with deliver until ctx is cancelled, it is
+// meant to run on the leader
+func (o *Outbox) Run(ctx context.Context, interval time.Duration, deliver DeliverFunc) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	loaded := false
+	for {
+		if !loaded {
+			if err := o.Load(ctx); err != nil {
+				log.WithError(err).Error("Unable to load the outbox, retrying later")
+			} else {
+				loaded = true
+				if n := o.Len(); n > 0 {
+					log.WithField("entries", n).Info("Replaying the persisted outbox")
+				}
+			}
+		}
+		if loaded {
+			o.Replay(ctx, deliver)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Replay delivers the entries of the outbox, the entries of a cluster are delivered in order until one fails.
+// The expired entries are given up first.
+func (o *Outbox) Replay(ctx context.Context, deliver DeliverFunc) {
+	if err := o.expire(ctx); err != nil {
+		log.WithError(err).Error("Unable to persist the outbox")
+	}
+
+	o.mu.Lock()
+	entries := append([]Entry(nil), o.entries...)
+	o.mu.Unlock()
+
+	blocked := map[string]bool{}
+	for _, e := range entries {
+		if ctx.Err() != nil {
+			return
+		}
+		if blocked[e.Cluster] {
+			continue
+		}
+		err := deliver(ctx, e)
+		if err != nil {
+			blocked[e.Cluster] = true
+			log.WithError(err).WithFields(log.Fields{"cluster": e.Cluster, "operation": e.Operation, "notification": e.Notification}).Warning("Unable to replay an OCM write of the outbox")
+		}
+		if err := o.update(ctx, e.Seq, err); err != nil {
+			log.WithError(err).Error("Unable to persist the outbox")
+		}
+	}
+}
+
+// update removes a delivered entry, or records the failure of its replay. An entry whose replay failed too many
+// times is given up. The entry is left alone if it was dropped while being replayed.
+func (o *Outbox) update(ctx context.Context, seq uint64, deliveryErr error) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	entries := make([]Entry, 0, len(o.entries))
+	var given []Entry
+	for _, e := range o.entries {
+		if e.Seq == seq {
+			if deliveryErr == nil {
+				continue
+			}
+			e.Attempts++
+			e.LastError = deliveryErr.Error()
+			if len(e.LastError) > maxLastErrorLength {
+				e.LastError = e.LastError[:maxLastErrorLength]
+			}
+			if e.Attempts >= o.maxAttempts {
+				given = append(given, e)
+				continue
+			}
+		}
+		entries = append(entries, e)
+	}
+	if err := o.save(ctx, entries); err != nil {
+		return err
+	}
+	deadLetter(given, deadLetterMaxAttempts)
+	return nil
+}
+
+// expire gives up the entries waiting for longer than the maximum age, e.g. behind an entry of their cluster
+// which keeps failing
+func (o *Outbox) expire(ctx context.Context) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	var kept, given []Entry
+	for _, e := range o.entries {
+		if time.Since(e.AddedAt) > o.maxAge {
+			given = append(given, e)
+			continue
+		}
+		kept = append(kept, e)
+	}
+	if len(given) == 0 {
+		return nil
+	}
+	if err := o.save(ctx, kept); err != nil {
+		return err
+	}
+	deadLetter(given, deadLetterExpired)
+	return nil
+}
+
+// deadLetter reports the entries given up for the reason
+func deadLetter(entries []Entry, reason string) {
+	for _, e := range entries {
+		metrics.CountOutboxDeadLetter(e.Operation, reason)
+		log.WithFields(log.Fields{
+			"cluster":      e.Cluster,
+			"operation":    e.Operation,
+			"notification": e.Notification,
+			"attempts":     e.Attempts,
+			"reason":       reason,
+			"lastError":    e.LastError,
+		}).Error("Giving up an OCM write of the outbox")
+	}
+}
+
+// save persists the entries and makes them the entries of the outbox, callers must hold the lock
+func (o *Outbox) save(ctx context.Context, entries []Entry) error {
+	if err := o.store.Save(ctx, entries); err != nil {
+		return fmt.Errorf("unable to persist the outbox: %w", err)
+	}
+	o.entries = entries
+	metrics.SetOutboxSize(len(entries))
+	return nil
+}
@@ -0,0 +1,259 @@
+package outbox_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/alertmanager/template"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/openshift/ocm-agent/pkg/outbox"
+	clientmocks "github.com/openshift/ocm-agent/pkg/util/test/generated/mocks/client"
+)
+
+// memoryStore keeps the entries in memory, failing the saves while failing is set
+type memoryStore struct {
+	mu      sync.Mutex
+	entries []outbox.Entry
+	failing bool
+}
+
+func (s *memoryStore) Load(_ context.Context) ([]outbox.Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]outbox.Entry(nil), s.entries...), nil
+}
+
+func (s *memoryStore) Save(_ context.Context, entries []outbox.Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failing {
+		return errors.New("store unavailable")
+	}
+	s.entries = append([]outbox.Entry(nil), entries...)
+	return nil
+}
+
+func newEntry(cluster, notification, fingerprint string) outbox.Entry {
+	return outbox.Entry{
+		Cluster:      cluster,
+		Operation:    "send_service_log",
+		Notification: notification,
+		Alert:        template.Alert{Status: "firing", Fingerprint: fingerprint},
+	}
+}
+
+var _ = Describe("Outbox", func() {
+	var (
+		ctx   context.Context
+		store *memoryStore
+		o     *outbox.Outbox
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		store = &memoryStore{}
+		o = outbox.New(store)
+	})
+
+	It("can't be changed before it is loaded", func() {
+		Expect(o.Add(ctx, newEntry("cluster-a", "notification", "1"))).NotTo(Succeed())
+		_, err := o.Drop(ctx, func(outbox.Entry) bool { return true })
+		Expect(err).To(HaveOccurred())
+	})
+
+	Context("When loaded", func() {
+		BeforeEach(func() {
+			Expect(o.Load(ctx)).To(Succeed())
+		})
+
+		It("persists the added entries", func() {
+			Expect(o.Add(ctx, newEntry("cluster-a", "notification", "1"))).To(Succeed())
+			Expect(o.Add(ctx, newEntry("cluster-a", "notification", "2"))).To(Succeed())
+			Expect(o.Len()).To(Equal(2))
+			Expect(o.Pending("cluster-a")).To(BeTrue())
+			Expect(o.Pending("cluster-b")).To(BeFalse())
+
+			reloaded := outbox.New(store)
+			Expect(reloaded.Load(ctx)).To(Succeed())
+			Expect(reloaded.Len()).To(Equal(2))
+		})
+
+		It("doesn't add an entry which couldn't be persisted", func() {
+			store.failing = true
+			Expect(o.Add(ctx, newEntry("cluster-a", "notification", "1"))).NotTo(Succeed())
+			Expect(o.Len()).To(BeZero())
+		})
+
+		It("doesn't add the same write twice", func() {
+			Expect(o.Add(ctx, newEntry("cluster-a", "notification", "1"))).To(Succeed())
+			Expect(o.Add(ctx, newEntry("cluster-a", "notification", "1"))).To(Succeed())
+			Expect(o.Len()).To(Equal(1))
+		})
+
+		It("drops the matching entries", func() {
+			Expect(o.Add(ctx, newEntry("cluster-a", "notification", "1"))).To(Succeed())
+			Expect(o.Add(ctx, newEntry("cluster-a", "notification", "2"))).To(Succeed())
+			dropped, err := o.Drop(ctx, func(e outbox.Entry) bool { return e.Alert.Fingerprint == "1" })
+			Expect(err).NotTo(HaveOccurred())
+			Expect(dropped).To(Equal(1))
+			Expect(store.entries).To(HaveLen(1))
+			Expect(store.entries[0].Alert.Fingerprint).To(Equal("2"))
+		})
+
+		It("replays the entries of a cluster in order until one fails", func() {
+			Expect(o.Add(ctx, newEntry("cluster-a", "notification", "1"))).To(Succeed())
+			Expect(o.Add(ctx, newEntry("cluster-b", "notification", "2"))).To(Succeed())
+			Expect(o.Add(ctx, newEntry("cluster-a", "notification", "3"))).To(Succeed())
+			Expect(o.Add(ctx, newEntry("cluster-b", "notification", "4"))).To(Succeed())
+
+			var delivered []string
+			o.Replay(ctx, func(_ context.Context, e outbox.Entry) error {
+				if e.Alert.Fingerprint == "2" {
+					return errors.New("OCM is unavailable")
+				}
+				delivered = append(delivered, e.Alert.Fingerprint)
+				return nil
+			})
+			Expect(delivered).To(Equal([]string{"1", "3"}))
+			Expect(store.entries).To(HaveLen(2))
+			Expect(store.entries[0].Alert.Fingerprint).To(Equal("2"))
+			Expect(store.entries[0].Attempts).To(Equal(1))
+			Expect(store.entries[0].LastError).To(Equal("OCM is unavailable"))
+			Expect(store.entries[1].Alert.Fingerprint).To(Equal("4"))
+			Expect(store.entries[1].Attempts).To(BeZero())
+		})
+	})
+
+	Context("When limited", func() {
+		BeforeEach(func() {
+			o.WithLimits(2, time.Hour, 512)
+		})
+
+		It("gives up an entry failing too many times", func() {
+			Expect(o.Load(ctx)).To(Succeed())
+			Expect(o.Add(ctx, newEntry("cluster-a", "notification", "1"))).To(Succeed())
+			failing := func(context.Context, outbox.Entry) error { return errors.New("OCM is unavailable") }
+			o.Replay(ctx, failing)
+			Expect(o.Pending("cluster-a")).To(BeTrue())
+			o.Replay(ctx, failing)
+			Expect(o.Pending("cluster-a")).To(BeFalse())
+			Expect(store.entries).To(BeEmpty())
+		})
+
+		It("gives up the expired entries, even behind a failing one", func() {
+			stale := newEntry("cluster-a", "notification", "2")
+			stale.Seq, stale.AddedAt = 2, time.Now().Add(-2*time.Hour)
+			failing := newEntry("cluster-a", "notification", "1")
+			failing.Seq, failing.AddedAt = 1, time.Now()
+			store.entries = []outbox.Entry{failing, stale}
+			Expect(o.Load(ctx)).To(Succeed())
+
+			o.Replay(ctx, func(context.Context, outbox.Entry) error { return errors.New("OCM is unavailable") })
+			Expect(store.entries).To(HaveLen(1))
+			Expect(store.entries[0].Alert.Fingerprint).To(Equal("1"))
+		})
+
+		It("doesn't add an entry once full", func() {
+			Expect(o.Load(ctx)).To(Succeed())
+			Expect(o.Add(ctx, newEntry("cluster-a", "notification", "1"))).To(Succeed())
+			Expect(o.Add(ctx, newEntry("cluster-a", "notification", "2"))).To(MatchError(outbox.ErrFull))
+			Expect(o.Len()).To(Equal(1))
+		})
+	})
+
+	It("is replayed by Run once loaded", func() {
+		entry := newEntry("cluster-a", "notification", "1")
+		entry.AddedAt = time.Now()
+		store.entries = []outbox.Entry{entry}
+		runCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		delivered := make(chan outbox.Entry, 1)
+		go o.Run(runCtx, time.Hour, func(_ context.Context, e outbox.Entry) error {
+			delivered <- e
+			return nil
+		})
+		Eventually(delivered).Should(Receive())
+		Eventually(o.Len).Should(BeZero())
+	})
+
+	It("is empty and changes nothing when nil", func() {
+		var nilOutbox *outbox.Outbox
+		Expect(nilOutbox.Pending("cluster-a")).To(BeFalse())
+		dropped, err := nilOutbox.Drop(ctx, func(outbox.Entry) bool { return true })
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dropped).To(BeZero())
+	})
+})
+
+var _ = Describe("ConfigMapStore", func() {
+	var (
+		mockCtrl   *gomock.Controller
+		mockClient *clientmocks.MockClient
+		store      *outbox.ConfigMapStore
+		key        = client.ObjectKey{Namespace: "namespace", Name: "outbox"}
+		entries    = []outbox.Entry{newEntry("cluster-a", "notification", "1")}
+	)
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		mockClient = clientmocks.NewMockClient(mockCtrl)
+		store = outbox.NewConfigMapStore(mockClient, key.Namespace, key.Name)
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	It("loads an empty outbox when the ConfigMap is missing", func() {
+		mockClient.EXPECT().Get(gomock.Any(), key, gomock.Any()).Return(kerrors.NewNotFound(schema.GroupResource{}, key.Name))
+		loaded, err := store.Load(context.TODO())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(loaded).To(BeEmpty())
+	})
+
+	It("loads the entries of the ConfigMap", func() {
+		data, err := json.Marshal(entries)
+		Expect(err).NotTo(HaveOccurred())
+		cm := corev1.ConfigMap{Data: map[string]string{outbox.ConfigMapEntriesKey: string(data)}}
+		mockClient.EXPECT().Get(gomock.Any(), key, gomock.Any()).SetArg(2, cm).Return(nil)
+		loaded, err := store.Load(context.TODO())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(loaded).To(HaveLen(1))
+		Expect(loaded[0].Cluster).To(Equal("cluster-a"))
+	})
+
+	It("creates the ConfigMap when missing", func() {
+		gomock.InOrder(
+			mockClient.EXPECT().Get(gomock.Any(), key, gomock.Any()).Return(kerrors.NewNotFound(schema.GroupResource{}, key.Name)),
+			mockClient.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ context.Context, obj client.Object, _ ...client.CreateOption) error {
+					cm := obj.(*corev1.ConfigMap)
+					Expect(cm.Namespace).To(Equal(key.Namespace))
+					Expect(cm.Name).To(Equal(key.Name))
+					Expect(cm.Data).To(HaveKey(outbox.ConfigMapEntriesKey))
+					return nil
+				}),
+		)
+		Expect(store.Save(context.TODO(), entries)).To(Succeed())
+	})
+
+	It("updates the existing ConfigMap, retrying on conflicts", func() {
+		gomock.InOrder(
+			mockClient.EXPECT().Get(gomock.Any(), key, gomock.Any()).Return(nil),
+			mockClient.EXPECT().Update(gomock.Any(), gomock.Any()).Return(kerrors.NewConflict(schema.GroupResource{}, key.Name, errors.New("conflict"))),
+			mockClient.EXPECT().Get(gomock.Any(), key, gomock.Any()).Return(nil),
+			mockClient.EXPECT().Update(gomock.Any(), gomock.Any()).Return(nil),
+		)
+		Expect(store.Save(context.TODO(), entries)).To(Succeed())
+	})
+})
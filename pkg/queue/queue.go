@@ -0,0 +1,378 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/openshift/ocm-agent/pkg/backoff"
+	"github.com/openshift/ocm-agent/pkg/metrics"
+)
+
+// ErrQueueFull is returned by Enqueue when the queue can't accept any more items
+var ErrQueueFull = errors.New("alert processing queue is full")
+
+const (
+	// RetryInitialInterval is the delay before processing again an item which failed transiently
+	RetryInitialInterval = time.Second
+	// RetryMaxInterval bounds the delay between two attempts at processing an item which failed transiently
+	RetryMaxInterval = 5 * time.Minute
+	// RetryMaxAttempts is the number of attempts at processing an item failing transiently before it is put back at
+	// the end of the queue, so that the other items of its worker don't wait for it
+	RetryMaxAttempts = 5
+	// MaxAttempts is the number of attempts at processing an item failing transiently after which it is given up
+	MaxAttempts = 50
+	// backlogFeedInterval is the time the persisted backlog waits for a worker to have capacity again
+	backlogFeedInterval = 100 * time.Millisecond
+)
+
+// retriableError marks the failure of an item which could go away by processing it again
+type retriableError struct {
+	err error
+}
+
+func (e *retriableError) Error() string {
+	return e.err.Error()
+}
+
+func (e *retriableError) Unwrap() error {
+	return e.err
+}
+
+// Retriable wraps the error returned by a ProcessFunc so that the item is processed again, with a backoff, rather
+// than removed from the queue
+func Retriable(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &retriableError{err: err}
+}
+
+// IsRetriable indicates whether the item failed with an error wrapped by Retriable
+func IsRetriable(err error) bool {
+	var re *retriableError
+	return errors.As(err, &re)
+}
+
+// Item is a single unit of work waiting in the queue
+type Item struct {
+	// Seq is a monotonically increasing number used to keep the persisted backlog ordered
+	Seq uint64 `json:"seq"`
+	// Key groups items which must be processed in order, e.g. alerts for the same template and cluster
+	Key string `json:"key"`
+	// Payload is the JSON encoded data to be processed
+	Payload json.RawMessage `json:"payload"`
+	// EnqueuedAt is the time the item was accepted by the queue
+	EnqueuedAt time.Time `json:"enqueuedAt"`
+	// Attempts is the number of times the item failed transiently
+	Attempts int `json:"attempts,omitempty"`
+}
+
+// ProcessFunc handles a single item taken off the queue. An item failing with an error wrapped by Retriable is
+// processed again, the next items of its worker waiting for it.
+type ProcessFunc func(ctx context.Context, item Item) error
+
+// Entry is a payload to add to the queue with the key it is ordered by
+type Entry struct {
+	Key     string
+	Payload interface{}
+}
+
+// Queue is a bounded work queue with a fixed number of workers.
+// Items sharing the same key are always handled by the same worker, so they are processed in the order
+// they were enqueued. Items that have been accepted but not processed yet are persisted to the Store
+// (if any) and are enqueued again when the queue is started.
+type Queue struct {
+	shards  []chan Item
+	store   Store
+	mu      sync.Mutex
+	pending map[uint64]Item
+	seq     uint64
+	// initialInterval and maxInterval are the bounds of the backoff between the attempts at processing an item
+	initialInterval time.Duration
+	maxInterval     time.Duration
+}
+
+// NewQueue creates a queue with the given number of workers which holds at most size pending items.
+// A nil store disables the persistence of the backlog.
+func NewQueue(workers int, size int, store Store) (*Queue, error) {
+	if workers < 1 {
+		return nil, fmt.Errorf("queue needs at least one worker, got %d", workers)
+	}
+	if size < workers {
+		return nil, fmt.Errorf("queue size %d can't be lower than the number of workers %d", size, workers)
+	}
+
+	q := &Queue{
+		shards:          make([]chan Item, workers),
+		store:           store,
+		pending:         map[uint64]Item{},
+		initialInterval: RetryInitialInterval,
+		maxInterval:     RetryMaxInterval,
+	}
+	for i := range q.shards {
+		q.shards[i] = make(chan Item, size/workers)
+	}
+	return q, nil
+}
+
+// WithRetryIntervals sets the bounds of the backoff between the attempts at processing an item failing transiently,
+// RetryInitialInterval and RetryMaxInterval by default
+func (q *Queue) WithRetryIntervals(initial, max time.Duration) *Queue {
+	q.initialInterval = initial
+	q.maxInterval = max
+	return q
+}
+
+// Enqueue adds the payload to the queue, it returns ErrQueueFull if the worker owning the key has no capacity left
+func (q *Queue) Enqueue(key string, payload interface{}) error {
+	return q.EnqueueAll(Entry{Key: key, Payload: payload})
+}
+
+// EnqueueAll adds all the entries to the queue or none of them, it returns ErrQueueFull if the workers owning their
+// keys don't have the capacity left for all of them
+func (q *Queue) EnqueueAll(entries ...Entry) error {
+	data := make([]json.RawMessage, len(entries))
+	for i, entry := range entries {
+		var err error
+		if data[i], err = json.Marshal(entry.Payload); err != nil {
+			return fmt.Errorf("unable to encode queue item: %w", err)
+		}
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	// The items are only handed over to the workers under the lock, so the capacity can't shrink until they are
+	needed := map[chan Item]int{}
+	for _, entry := range entries {
+		needed[q.shard(entry.Key)]++
+	}
+	for shard, n := range needed {
+		if cap(shard)-len(shard) < n {
+			return ErrQueueFull
+		}
+	}
+
+	items := make([]Item, len(entries))
+	for i, entry := range entries {
+		q.seq++
+		items[i] = Item{
+			Seq:        q.seq,
+			Key:        entry.Key,
+			Payload:    data[i],
+			EnqueuedAt: time.Now(),
+		}
+		q.pending[items[i].Seq] = items[i]
+	}
+
+	// The items are persisted before being handed over to the workers, so that they can't be
+	// processed and removed from the backlog before they were added to it.
+	if err := q.persist(); err != nil {
+		for _, item := range items {
+			delete(q.pending, item.Seq)
+		}
+		return err
+	}
+	for _, item := range items {
+		q.shard(item.Key) <- item
+	}
+
+	metrics.SetQueueDepth(len(q.pending))
+	return nil
+}
+
+// Len returns the number of items which have been accepted but not processed yet
+func (q *Queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.pending)
+}
+
+// Start loads the persisted backlog and starts the workers processing items with fn until ctx is cancelled
+func (q *Queue) Start(ctx context.Context, fn ProcessFunc) error {
+	backlog, err := q.load()
+	if err != nil {
+		return err
+	}
+
+	for _, shard := range q.shards {
+		go q.work(ctx, shard, fn)
+	}
+
+	if len(backlog) > 0 {
+		log.WithField("items", len(backlog)).Info("Resuming processing of the persisted queue backlog")
+		// The backlog may be bigger than the shard capacity, so feed it in the background
+		go func() {
+			for _, item := range backlog {
+				if !q.feed(ctx, item) {
+					return
+				}
+			}
+		}()
+	}
+
+	return nil
+}
+
+// feed hands the item over to the worker owning its key once it has capacity left, it returns false if ctx is
+// done first
+func (q *Queue) feed(ctx context.Context, item Item) bool {
+	for !q.offer(item) {
+		select {
+		case <-time.After(backlogFeedInterval):
+		case <-ctx.Done():
+			return false
+		}
+	}
+	return true
+}
+
+// offer hands the item over to the worker owning its key if it has capacity left
+func (q *Queue) offer(item Item) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	select {
+	case q.shard(item.Key) <- item:
+		return true
+	default:
+		return false
+	}
+}
+
+func (q *Queue) work(ctx context.Context, shard chan Item, fn ProcessFunc) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case item := <-shard:
+			// The item failing transiently is processed again a few times, the alert was already acknowledged
+			retry := backoff.Config{
+				InitialInterval: q.initialInterval,
+				MaxInterval:     q.maxInterval,
+				MaxAttempts:     min(RetryMaxAttempts, MaxAttempts-item.Attempts),
+				Retriable:       IsRetriable,
+				OnRetry: func(attempt int, err error, delay time.Duration) {
+					log.WithError(err).WithFields(log.Fields{"attempt": item.Attempts + attempt, "delay": delay}).Warning("retrying queued item")
+					metrics.CountQueueProcessingFailure()
+				},
+			}
+			attempts := 0
+			err := backoff.Retry(ctx, retry, func() error {
+				attempts++
+				return fn(ctx, item)
+			})
+			if ctx.Err() != nil {
+				// The item is kept in the backlog, it is processed again once the queue is started again
+				return
+			}
+			item.Attempts += attempts
+			if IsRetriable(err) && item.Attempts < MaxAttempts {
+				q.requeue(ctx, item, err)
+				continue
+			}
+			if err != nil {
+				log.WithError(err).WithFields(log.Fields{"key": item.Key, "attempts": item.Attempts}).Error("failed processing queued item")
+				metrics.CountQueueProcessingFailure()
+			}
+			metrics.ObserveQueueLatency(time.Since(item.EnqueuedAt))
+			q.done(item)
+		}
+	}
+}
+
+// requeue puts the item failing transiently back at the end of the queue once a delay growing with its attempts
+// has passed, so that the other items of its worker are processed meanwhile. It stays in the backlog with the
+// number of its attempts.
+func (q *Queue) requeue(ctx context.Context, item Item, err error) {
+	q.mu.Lock()
+	q.pending[item.Seq] = item
+	if err := q.persist(); err != nil {
+		log.WithError(err).Error("unable to persist the queue backlog")
+	}
+	q.mu.Unlock()
+
+	delay := q.maxInterval
+	if item.Attempts < 32 && q.initialInterval<<item.Attempts < delay {
+		delay = q.initialInterval << item.Attempts
+	}
+	log.WithError(err).WithFields(log.Fields{"key": item.Key, "attempts": item.Attempts, "delay": delay}).Warning("putting queued item back at the end of the queue")
+	metrics.CountQueueProcessingFailure()
+	go func() {
+		select {
+		case <-time.After(delay):
+			q.feed(ctx, item)
+		case <-ctx.Done():
+		}
+	}()
+}
+
+// done removes a processed item from the backlog
+func (q *Queue) done(item Item) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	delete(q.pending, item.Seq)
+	if err := q.persist(); err != nil {
+		log.WithError(err).Error("unable to persist the queue backlog")
+	}
+	metrics.SetQueueDepth(len(q.pending))
+}
+
+// load reads the persisted backlog and registers it as pending, ordered as it was enqueued
+func (q *Queue) load() ([]Item, error) {
+	if q.store == nil {
+		return nil, nil
+	}
+
+	items, err := q.store.Load()
+	if err != nil {
+		return nil, fmt.Errorf("unable to load the queue backlog: %w", err)
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Seq < items[j].Seq })
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, item := range items {
+		q.pending[item.Seq] = item
+		if item.Seq > q.seq {
+			q.seq = item.Seq
+		}
+	}
+	metrics.SetQueueDepth(len(q.pending))
+
+	return items, nil
+}
+
+// persist writes the pending items to the store, callers must hold the lock
+func (q *Queue) persist() error {
+	if q.store == nil {
+		return nil
+	}
+
+	items := make([]Item, 0, len(q.pending))
+	for _, item := range q.pending {
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Seq < items[j].Seq })
+
+	if err := q.store.Save(items); err != nil {
+		return fmt.Errorf("unable to persist the queue backlog: %w", err)
+	}
+	return nil
+}
+
+// shard returns the worker channel owning the given key
+func (q *Queue) shard(key string) chan Item {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return q.shards[h.Sum32()%uint32(len(q.shards))]
+}
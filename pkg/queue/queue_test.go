@@ -0,0 +1,238 @@
+package queue_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/openshift/ocm-agent/pkg/queue"
+)
+
+var _ = Describe("Queue", func() {
+	var (
+		ctx    context.Context
+		cancel context.CancelFunc
+		mu     sync.Mutex
+		seen   []string
+	)
+
+	record := func(_ context.Context, item queue.Item) error {
+		var payload string
+		if err := json.Unmarshal(item.Payload, &payload); err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		seen = append(seen, payload)
+		return nil
+	}
+
+	processed := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string{}, seen...)
+	}
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithCancel(context.Background())
+		seen = nil
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	Context("NewQueue", func() {
+		It("should refuse a queue without workers", func() {
+			_, err := queue.NewQueue(0, 10, nil)
+			Expect(err).Should(HaveOccurred())
+		})
+		It("should refuse a queue smaller than the number of workers", func() {
+			_, err := queue.NewQueue(4, 2, nil)
+			Expect(err).Should(HaveOccurred())
+		})
+	})
+
+	Context("When processing items", func() {
+		It("should process the items of the same key in order", func() {
+			q, err := queue.NewQueue(4, 100, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(q.Start(ctx, record)).To(Succeed())
+
+			expected := []string{"1", "2", "3", "4", "5"}
+			for _, payload := range expected {
+				Expect(q.Enqueue("same-key", payload)).To(Succeed())
+			}
+
+			Eventually(processed).Should(Equal(expected))
+			Eventually(q.Len).Should(Equal(0))
+		})
+
+		It("should remove items from the queue even when processing fails", func() {
+			q, err := queue.NewQueue(1, 10, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(q.Start(ctx, func(context.Context, queue.Item) error { return errors.New("fake error") })).To(Succeed())
+
+			Expect(q.Enqueue("key", "payload")).To(Succeed())
+			Eventually(q.Len).Should(Equal(0))
+		})
+
+		It("should process again an item failing transiently until it succeeds", func() {
+			q, err := queue.NewQueue(1, 10, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			attempts := 0
+			Expect(q.Start(ctx, func(ctx context.Context, item queue.Item) error {
+				mu.Lock()
+				attempts++
+				failed := attempts == 1
+				mu.Unlock()
+				if failed {
+					return queue.Retriable(errors.New("fake error"))
+				}
+				return record(ctx, item)
+			})).To(Succeed())
+
+			Expect(q.Enqueue("key", "first")).To(Succeed())
+			Expect(q.Enqueue("key", "second")).To(Succeed())
+
+			Eventually(processed).WithTimeout(5 * time.Second).Should(Equal([]string{"first", "second"}))
+			Eventually(q.Len).Should(Equal(0))
+		})
+
+		It("should put an item failing transiently back at the end of the queue after a few attempts", func() {
+			q, err := queue.NewQueue(1, 10, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			attempts := 0
+			Expect(q.WithRetryIntervals(time.Millisecond, 10*time.Millisecond).Start(ctx, func(ctx context.Context, item queue.Item) error {
+				if item.Key == "failing" {
+					mu.Lock()
+					attempts++
+					failed := attempts <= queue.RetryMaxAttempts
+					mu.Unlock()
+					if failed {
+						return queue.Retriable(errors.New("fake error"))
+					}
+				}
+				return record(ctx, item)
+			})).To(Succeed())
+
+			Expect(q.Enqueue("failing", "first")).To(Succeed())
+			Expect(q.Enqueue("other", "second")).To(Succeed())
+
+			Eventually(processed).Should(Equal([]string{"second", "first"}))
+			Eventually(q.Len).Should(Equal(0))
+		})
+
+		It("should give up an item failing transiently after the maximum attempts", func() {
+			q, err := queue.NewQueue(1, 10, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			attempts := 0
+			Expect(q.WithRetryIntervals(time.Millisecond, time.Millisecond).Start(ctx, func(context.Context, queue.Item) error {
+				mu.Lock()
+				defer mu.Unlock()
+				attempts++
+				return queue.Retriable(errors.New("fake error"))
+			})).To(Succeed())
+
+			Expect(q.Enqueue("key", "payload")).To(Succeed())
+			Eventually(q.Len).WithTimeout(5 * time.Second).Should(Equal(0))
+			mu.Lock()
+			defer mu.Unlock()
+			Expect(attempts).To(Equal(queue.MaxAttempts))
+		})
+
+		It("should keep an item failing transiently in the backlog when the queue stops", func() {
+			backlogFile := filepath.Join(GinkgoT().TempDir(), "backlog.json")
+			q, err := queue.NewQueue(1, 10, queue.NewFileStore(backlogFile))
+			Expect(err).ShouldNot(HaveOccurred())
+			stopped, stop := context.WithCancel(ctx)
+			Expect(q.Start(stopped, func(context.Context, queue.Item) error {
+				stop()
+				return queue.Retriable(errors.New("fake error"))
+			})).To(Succeed())
+
+			Expect(q.Enqueue("key", "payload")).To(Succeed())
+			Eventually(stopped.Done()).Should(BeClosed())
+			Consistently(q.Len).Should(Equal(1))
+
+			items, err := queue.NewFileStore(backlogFile).Load()
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(items).To(HaveLen(1))
+		})
+
+		It("should report a full queue", func() {
+			q, err := queue.NewQueue(1, 1, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			// Workers are not started so nothing is consumed
+			Expect(q.Enqueue("key", "first")).To(Succeed())
+			Expect(q.Enqueue("key", "second")).To(MatchError(queue.ErrQueueFull))
+			Expect(q.Len()).To(Equal(1))
+		})
+
+		It("should queue all the items of a batch or none of them", func() {
+			q, err := queue.NewQueue(1, 2, nil)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			// Workers are not started so nothing is consumed
+			Expect(q.Enqueue("key", "first")).To(Succeed())
+			Expect(q.EnqueueAll(
+				queue.Entry{Key: "key", Payload: "second"},
+				queue.Entry{Key: "key", Payload: "third"},
+			)).To(MatchError(queue.ErrQueueFull))
+			Expect(q.Len()).To(Equal(1))
+
+			Expect(q.EnqueueAll(queue.Entry{Key: "key", Payload: "second"})).To(Succeed())
+			Expect(q.Start(ctx, record)).To(Succeed())
+			Eventually(processed).Should(Equal([]string{"first", "second"}))
+		})
+	})
+
+	Context("When persisting the backlog", func() {
+		var backlogFile string
+
+		BeforeEach(func() {
+			backlogFile = filepath.Join(GinkgoT().TempDir(), "backlog.json")
+		})
+
+		It("should resume the items which were not processed before a restart", func() {
+			q, err := queue.NewQueue(1, 10, queue.NewFileStore(backlogFile))
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(q.Enqueue("key", "first")).To(Succeed())
+			Expect(q.Enqueue("key", "second")).To(Succeed())
+
+			items, err := queue.NewFileStore(backlogFile).Load()
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(items).To(HaveLen(2))
+
+			restarted, err := queue.NewQueue(1, 10, queue.NewFileStore(backlogFile))
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(restarted.Start(ctx, record)).To(Succeed())
+
+			Eventually(processed).Should(Equal([]string{"first", "second"}))
+			Eventually(func() ([]queue.Item, error) {
+				return queue.NewFileStore(backlogFile).Load()
+			}).Should(BeEmpty())
+		})
+
+		It("should start with an empty backlog when the file does not exist", func() {
+			items, err := queue.NewFileStore(backlogFile).Load()
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(items).To(BeEmpty())
+		})
+
+		It("should fail to start when the backlog can't be read", func() {
+			Expect(os.WriteFile(backlogFile, []byte("not json"), 0600)).To(Succeed())
+			q, err := queue.NewQueue(1, 10, queue.NewFileStore(backlogFile))
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(q.Start(ctx, record)).ShouldNot(Succeed())
+		})
+	})
+})
@@ -0,0 +1,67 @@
+package queue
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// Store persists the queue backlog so it survives restarts of the agent
+type Store interface {
+	Load() ([]Item, error)
+	Save(items []Item) error
+}
+
+// FileStore keeps the queue backlog as a JSON document on disk
+type FileStore struct {
+	path string
+}
+
+// NewFileStore returns a Store writing the backlog to the given file
+func NewFileStore(path string) *FileStore {
+	return &FileStore{path: path}
+}
+
+// Load reads the backlog from the file, a missing file is an empty backlog
+func (s *FileStore) Load() ([]Item, error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var items []Item
+	if len(data) == 0 {
+		return items, nil
+	}
+	err = json.Unmarshal(data, &items)
+	return items, err
+}
+
+// Save replaces the content of the file with the given backlog.
+// The file is written atomically so a crash never leaves a truncated backlog behind.
+func (s *FileStore) Save(items []Item) error {
+	data, err := json.Marshal(items)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), s.path)
+}
@@ -0,0 +1,190 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
+
+	"github.com/openshift/ocm-agent/pkg/metrics"
+)
+
+const (
+	// PolicyWait delays the throttled writes up to the maximum delay
+	PolicyWait = "wait"
+	// PolicyDrop drops the throttled writes
+	PolicyDrop = "drop"
+
+	// Scopes of the limits, reported by the metrics
+	ScopeGlobal   = "global"
+	ScopeCluster  = "cluster"
+	ScopeTemplate = "template"
+
+	// Outcomes of the throttled writes, reported by the metrics
+	OutcomeDelayed  = "delayed"
+	OutcomeDeferred = "deferred"
+	OutcomeDropped  = "dropped"
+
+	DefaultMaxDelay = 30 * time.Second
+
+	// maxIdleLimiters is the number of per cluster or per template limiters above which the idle ones are forgotten
+	maxIdleLimiters = 1000
+)
+
+var (
+	// ErrDeferred is returned when a write would have to wait longer than the maximum delay, it should be attempted again later
+	ErrDeferred = errors.New("rate limit exceeded, write deferred")
+	// ErrDropped is returned when a write is throttled with the drop policy
+	ErrDropped = errors.New("rate limit exceeded, write dropped")
+)
+
+// Config defines the rates, in writes per minute, allowed globally, per cluster and per template. A zero rate
+// doesn't limit the writes. Each limit allows a burst of one minute worth of writes.
+type Config struct {
+	Global      float64
+	PerCluster  float64
+	PerTemplate float64
+	// Policy is either PolicyWait or PolicyDrop
+	Policy string
+	// MaxDelay is the longest a write waits with PolicyWait
+	MaxDelay time.Duration
+}
+
+// Limiter throttles the writes to OCM
+type Limiter struct {
+	config    Config
+	global    *rate.Limiter
+	mu        sync.Mutex
+	clusters  map[string]*rate.Limiter
+	templates map[string]*rate.Limiter
+}
+
+// NewLimiter returns a limiter enforcing the configuration, or nil if it doesn't limit anything
+func NewLimiter(c Config) (*Limiter, error) {
+	if c.Policy != PolicyWait && c.Policy != PolicyDrop {
+		return nil, fmt.Errorf("unknown rate limit policy %q, expected %q or %q", c.Policy, PolicyWait, PolicyDrop)
+	}
+	if c.Global < 0 || c.PerCluster < 0 || c.PerTemplate < 0 {
+		return nil, fmt.Errorf("rate limits can't be negative")
+	}
+	if c.Global == 0 && c.PerCluster == 0 && c.PerTemplate == 0 {
+		return nil, nil
+	}
+	if c.MaxDelay <= 0 {
+		c.MaxDelay = DefaultMaxDelay
+	}
+
+	return &Limiter{
+		config:    c,
+		global:    newRateLimiter(c.Global),
+		clusters:  map[string]*rate.Limiter{},
+		templates: map[string]*rate.Limiter{},
+	}, nil
+}
+
+// Wait blocks until the write of the operation for the cluster and template is allowed. It returns ErrDropped
+// if the write is throttled with the drop policy, or ErrDeferred if it would wait longer than the maximum delay.
+// A nil limiter allows all the writes.
+func (l *Limiter) Wait(ctx context.Context, operation, clusterID, template string) error {
+	if l == nil {
+		return nil
+	}
+
+	now := time.Now()
+	var reservations []*rate.Reservation
+	var delay time.Duration
+	scope := ""
+	for _, limit := range []struct {
+		scope   string
+		limiter *rate.Limiter
+	}{
+		{ScopeGlobal, l.global},
+		{ScopeCluster, l.limiterFor(l.clusters, clusterID, l.config.PerCluster)},
+		{ScopeTemplate, l.limiterFor(l.templates, template, l.config.PerTemplate)},
+	} {
+		if limit.limiter == nil {
+			continue
+		}
+		r := limit.limiter.ReserveN(now, 1)
+		reservations = append(reservations, r)
+		if d := r.DelayFrom(now); d > delay {
+			delay, scope = d, limit.scope
+		}
+	}
+	if delay == 0 {
+		return nil
+	}
+
+	fields := log.Fields{"operation": operation, "cluster_id": clusterID, "template": template, "scope": scope, "delay": delay}
+	cancel := func(outcome string, err error) error {
+		for _, r := range reservations {
+			r.CancelAt(now)
+		}
+		log.WithFields(fields).WithError(err).Warning("throttled an OCM write")
+		metrics.CountRateLimitThrottled(operation, scope, outcome)
+		return err
+	}
+
+	if l.config.Policy == PolicyDrop {
+		return cancel(OutcomeDropped, ErrDropped)
+	}
+	if delay > l.config.MaxDelay {
+		return cancel(OutcomeDeferred, ErrDeferred)
+	}
+
+	log.WithFields(fields).Info("delaying an OCM write to respect the rate limits")
+	metrics.CountRateLimitThrottled(operation, scope, OutcomeDelayed)
+	metrics.ObserveRateLimitDelay(operation, delay)
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		for _, r := range reservations {
+			r.Cancel()
+		}
+		return ctx.Err()
+	}
+}
+
+// limiterFor returns the limiter of the key, creating it if needed, or nil if the rate is unlimited
+func (l *Limiter) limiterFor(limiters map[string]*rate.Limiter, key string, perMinute float64) *rate.Limiter {
+	if perMinute == 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	limiter, ok := limiters[key]
+	if !ok {
+		if len(limiters) >= maxIdleLimiters {
+			forgetIdle(limiters)
+		}
+		limiter = newRateLimiter(perMinute)
+		limiters[key] = limiter
+	}
+	return limiter
+}
+
+// forgetIdle removes the limiters which have all their tokens back, they behave like new ones
+func forgetIdle(limiters map[string]*rate.Limiter) {
+	for key, limiter := range limiters {
+		if limiter.Tokens() >= float64(limiter.Burst()) {
+			delete(limiters, key)
+		}
+	}
+}
+
+// newRateLimiter returns a limiter allowing perMinute writes per minute, or nil if the rate is unlimited
+func newRateLimiter(perMinute float64) *rate.Limiter {
+	if perMinute == 0 {
+		return nil
+	}
+	return rate.NewLimiter(rate.Limit(perMinute/60), int(math.Max(1, math.Ceil(perMinute))))
+}
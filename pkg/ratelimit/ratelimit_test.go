@@ -0,0 +1,97 @@
+package ratelimit_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/openshift/ocm-agent/pkg/ratelimit"
+)
+
+const testOperation = "send_service_log"
+
+var _ = Describe("Rate limiter", func() {
+
+	var ctx context.Context
+
+	BeforeEach(func() {
+		ctx = context.Background()
+	})
+
+	Context("When creating a limiter", func() {
+		It("rejects an unknown policy", func() {
+			_, err := ratelimit.NewLimiter(ratelimit.Config{Global: 1, Policy: "queue"})
+			Expect(err).Should(HaveOccurred())
+		})
+		It("rejects a negative rate", func() {
+			_, err := ratelimit.NewLimiter(ratelimit.Config{PerCluster: -1, Policy: ratelimit.PolicyDrop})
+			Expect(err).Should(HaveOccurred())
+		})
+		It("returns no limiter when nothing is limited", func() {
+			l, err := ratelimit.NewLimiter(ratelimit.Config{Policy: ratelimit.PolicyWait})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(l).To(BeNil())
+			Expect(l.Wait(ctx, testOperation, "cluster-1", "template-1")).To(Succeed())
+		})
+	})
+
+	Context("When throttling with the drop policy", func() {
+		It("drops the writes exceeding the per cluster limit", func() {
+			l, err := ratelimit.NewLimiter(ratelimit.Config{PerCluster: 1, Policy: ratelimit.PolicyDrop})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(l.Wait(ctx, testOperation, "cluster-1", "template-1")).To(Succeed())
+			Expect(l.Wait(ctx, testOperation, "cluster-1", "template-1")).To(MatchError(ratelimit.ErrDropped))
+			Expect(l.Wait(ctx, testOperation, "cluster-2", "template-1")).To(Succeed())
+		})
+		It("drops the writes exceeding the per template limit", func() {
+			l, err := ratelimit.NewLimiter(ratelimit.Config{PerTemplate: 1, Policy: ratelimit.PolicyDrop})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(l.Wait(ctx, testOperation, "cluster-1", "template-1")).To(Succeed())
+			Expect(l.Wait(ctx, testOperation, "cluster-2", "template-1")).To(MatchError(ratelimit.ErrDropped))
+			Expect(l.Wait(ctx, testOperation, "cluster-2", "template-2")).To(Succeed())
+		})
+		It("drops the writes exceeding the global limit", func() {
+			l, err := ratelimit.NewLimiter(ratelimit.Config{Global: 2, Policy: ratelimit.PolicyDrop})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(l.Wait(ctx, testOperation, "cluster-1", "template-1")).To(Succeed())
+			Expect(l.Wait(ctx, testOperation, "cluster-2", "template-2")).To(Succeed())
+			Expect(l.Wait(ctx, testOperation, "cluster-3", "template-3")).To(MatchError(ratelimit.ErrDropped))
+		})
+		It("doesn't consume the other limits for a dropped write", func() {
+			l, err := ratelimit.NewLimiter(ratelimit.Config{Global: 2, PerCluster: 1, Policy: ratelimit.PolicyDrop})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(l.Wait(ctx, testOperation, "cluster-1", "template-1")).To(Succeed())
+			Expect(l.Wait(ctx, testOperation, "cluster-1", "template-1")).To(MatchError(ratelimit.ErrDropped))
+			Expect(l.Wait(ctx, testOperation, "cluster-2", "template-1")).To(Succeed())
+		})
+	})
+
+	Context("When throttling with the wait policy", func() {
+		It("defers the writes which would wait longer than the maximum delay", func() {
+			l, err := ratelimit.NewLimiter(ratelimit.Config{PerCluster: 1, Policy: ratelimit.PolicyWait, MaxDelay: time.Second})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(l.Wait(ctx, testOperation, "cluster-1", "template-1")).To(Succeed())
+			Expect(l.Wait(ctx, testOperation, "cluster-1", "template-1")).To(MatchError(ratelimit.ErrDeferred))
+		})
+		It("delays the writes within the maximum delay", func() {
+			l, err := ratelimit.NewLimiter(ratelimit.Config{PerCluster: 600, Policy: ratelimit.PolicyWait})
+			Expect(err).ShouldNot(HaveOccurred())
+			for i := 0; i < 600; i++ {
+				Expect(l.Wait(ctx, testOperation, "cluster-1", "template-1")).To(Succeed())
+			}
+			start := time.Now()
+			Expect(l.Wait(ctx, testOperation, "cluster-1", "template-1")).To(Succeed())
+			Expect(time.Since(start)).To(BeNumerically(">=", 50*time.Millisecond))
+		})
+		It("stops waiting when the context is cancelled", func() {
+			l, err := ratelimit.NewLimiter(ratelimit.Config{PerCluster: 1, Policy: ratelimit.PolicyWait, MaxDelay: 2 * time.Minute})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(l.Wait(ctx, testOperation, "cluster-1", "template-1")).To(Succeed())
+			cancelled, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+			defer cancel()
+			Expect(l.Wait(cancelled, testOperation, "cluster-1", "template-1")).To(MatchError(context.DeadlineExceeded))
+		})
+	})
+})
@@ -312,32 +312,19 @@ var _ = ginkgo.Describe("ocm-agent", ginkgo.Ordered, func() {
 
 		// Step 1: Create a limited support reason
 		ginkgo.By("creating limited support reason via OCM client")
-		err = ocmClient.SendLimitedSupport(externalClusterID, lsReason)
+		createdReason, _, err := ocmClient.SendLimitedSupport(ctx, externalClusterID, lsReason)
 		if err != nil {
 			ginkgo.GinkgoWriter.Printf("Skipping test: Failed to create limited support reason. Error: %v\n", err)
 			ginkgo.Skip(fmt.Sprintf("Failed to create limited support reason: %v. This may be expected if cluster doesn't support limited support or lacks permissions.", err))
 		}
-
-		// Since SendLimitedSupport doesn't return the ID, we have to find it.
-		ginkgo.By("finding the created limited support reason to get its ID")
-		var limitedSupportReasonID string
-		reasons, err := ocmClient.GetLimitedSupportReasons(externalClusterID)
-		if err != nil {
-			ginkgo.Fail(fmt.Sprintf("Failed to get limited support reasons after creating one: %v", err))
-		}
-		for _, r := range reasons {
-			if r.Summary() == limitedSupportSummary && r.Details() == limitedSupportDetails {
-				limitedSupportReasonID = r.ID()
-				break
-			}
-		}
-		Expect(limitedSupportReasonID).ToNot(BeEmpty(), "Could not find the created limited support reason")
+		limitedSupportReasonID := createdReason.ID()
+		Expect(limitedSupportReasonID).ToNot(BeEmpty(), "OCM didn't return the ID of the created limited support reason")
 
 		// Ensure cleanup happens even if tests fail
 		defer func() {
 			if limitedSupportReasonID != "" {
 				ginkgo.By("cleaning up - deleting limited support reason")
-				err := ocmClient.RemoveLimitedSupport(internalClusterID, limitedSupportReasonID)
+				_, err := ocmClient.RemoveLimitedSupport(ctx, externalClusterID, limitedSupportReasonID)
 				if err != nil {
 					fmt.Printf("Failed to cleanup limited support reason %s: %v\n", limitedSupportReasonID, err)
 				}